@@ -0,0 +1,82 @@
+package broker
+
+import (
+	"errors"
+)
+
+var (
+	DefaultBroker = NewBroker()
+	// ErrNotConnected 消息代理未连接
+	ErrNotConnected = errors.New("broker not connected")
+	// ErrInvalidTopic 主题不合法
+	ErrInvalidTopic = errors.New("invalid topic")
+)
+
+// Broker 消息发布订阅接口
+type Broker interface {
+	Init(...Option) error
+	Options() Options
+	Address() string
+	Connect() error
+	Disconnect() error
+	Publish(topic string, m *Message, opts ...PublishOption) error
+	Subscribe(topic string, h Handler, opts ...SubscribeOption) (Subscriber, error)
+	String() string
+}
+
+// Handler 订阅者处理消息的方法
+type Handler func(Event) error
+
+// Message 消息
+type Message struct {
+	Header map[string]string `json:"header"`
+	Body   []byte            `json:"body"`
+}
+
+// Event 订阅者收到的事件
+type Event interface {
+	// Topic 消息所属主题
+	Topic() string
+	// Message 消息内容
+	Message() *Message
+}
+
+// Subscriber 订阅者
+type Subscriber interface {
+	// Options 订阅参数
+	Options() SubscribeOptions
+	// Topic 订阅的主题
+	Topic() string
+	// Unsubscribe 取消订阅
+	Unsubscribe() error
+}
+
+type Option func(*Options)
+
+type PublishOption func(*PublishOptions)
+
+type SubscribeOption func(*SubscribeOptions)
+
+// Connect 连接默认的消息代理
+func Connect() error {
+	return DefaultBroker.Connect()
+}
+
+// Disconnect 断开默认的消息代理
+func Disconnect() error {
+	return DefaultBroker.Disconnect()
+}
+
+// Publish 发布消息到指定主题
+func Publish(topic string, msg *Message, opts ...PublishOption) error {
+	return DefaultBroker.Publish(topic, msg, opts...)
+}
+
+// Subscribe 订阅指定主题
+func Subscribe(topic string, handler Handler, opts ...SubscribeOption) (Subscriber, error) {
+	return DefaultBroker.Subscribe(topic, handler, opts...)
+}
+
+func String() string {
+	return DefaultBroker.String()
+}
@@ -0,0 +1,181 @@
+package broker
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/util/grand"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/osgochina/dmicro/logger"
+	"sync"
+)
+
+// 基于内存的消息代理，只能在当前进程内发布订阅消息
+type memoryBroker struct {
+	sync.RWMutex
+	opts      Options
+	connected bool
+	// 订阅者 map[topic][]*memorySubscriber
+	subscribers map[string][]*memorySubscriber
+}
+
+type memoryEvent struct {
+	topic   string
+	message *Message
+}
+
+type memorySubscriber struct {
+	id      string
+	topic   string
+	handler Handler
+	opts    SubscribeOptions
+	broker  *memoryBroker
+}
+
+// NewBroker 创建基于内存的消息代理
+func NewBroker(opts ...Option) Broker {
+	options := Options{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &memoryBroker{
+		opts:        options,
+		subscribers: make(map[string][]*memorySubscriber),
+	}
+}
+
+// Init 初始化配置
+func (that *memoryBroker) Init(opts ...Option) error {
+	that.Lock()
+	defer that.Unlock()
+	for _, o := range opts {
+		o(&that.opts)
+	}
+	return nil
+}
+
+// Options 获取配置
+func (that *memoryBroker) Options() Options {
+	return that.opts
+}
+
+// Address 内存代理没有地址
+func (that *memoryBroker) Address() string {
+	return ""
+}
+
+// Connect 连接
+func (that *memoryBroker) Connect() error {
+	that.Lock()
+	defer that.Unlock()
+	that.connected = true
+	return nil
+}
+
+// Disconnect 断开连接
+func (that *memoryBroker) Disconnect() error {
+	that.Lock()
+	defer that.Unlock()
+	that.connected = false
+	return nil
+}
+
+// Publish 发布消息，同步调用所有匹配的订阅者
+func (that *memoryBroker) Publish(topic string, m *Message, _ ...PublishOption) error {
+	if len(topic) == 0 {
+		return ErrInvalidTopic
+	}
+	that.RLock()
+	if !that.connected {
+		that.RUnlock()
+		return ErrNotConnected
+	}
+	var (
+		subs   []*memorySubscriber
+		queues = make(map[string][]*memorySubscriber)
+	)
+	for _, sub := range that.subscribers[topic] {
+		if len(sub.opts.Queue) == 0 {
+			subs = append(subs, sub)
+			continue
+		}
+		queues[sub.opts.Queue] = append(queues[sub.opts.Queue], sub)
+	}
+	that.RUnlock()
+
+	// 同一分组只随机选择一个订阅者
+	for _, q := range queues {
+		subs = append(subs, q[grand.Intn(len(q))])
+	}
+	e := &memoryEvent{topic: topic, message: m}
+	for _, sub := range subs {
+		if err := sub.handler(e); err != nil {
+			logger.Warningf(context.TODO(), "broker: subscriber %s handle topic %s error: %v", sub.id, topic, err)
+		}
+	}
+	return nil
+}
+
+// Subscribe 订阅主题
+func (that *memoryBroker) Subscribe(topic string, h Handler, opts ...SubscribeOption) (Subscriber, error) {
+	if len(topic) == 0 {
+		return nil, ErrInvalidTopic
+	}
+	that.Lock()
+	defer that.Unlock()
+	if !that.connected {
+		return nil, ErrNotConnected
+	}
+	sub := &memorySubscriber{
+		id:      guid.S(),
+		topic:   topic,
+		handler: h,
+		opts:    NewSubscribeOptions(opts...),
+		broker:  that,
+	}
+	that.subscribers[topic] = append(that.subscribers[topic], sub)
+	return sub, nil
+}
+
+// 移除订阅者
+func (that *memoryBroker) unsubscribe(sub *memorySubscriber) {
+	that.Lock()
+	defer that.Unlock()
+	subs := that.subscribers[sub.topic]
+	for i, s := range subs {
+		if s.id == sub.id {
+			subs = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(subs) == 0 {
+		delete(that.subscribers, sub.topic)
+		return
+	}
+	that.subscribers[sub.topic] = subs
+}
+
+func (that *memoryBroker) String() string {
+	return "memory"
+}
+
+func (that *memoryEvent) Topic() string {
+	return that.topic
+}
+
+func (that *memoryEvent) Message() *Message {
+	return that.message
+}
+
+func (that *memorySubscriber) Options() SubscribeOptions {
+	return that.opts
+}
+
+func (that *memorySubscriber) Topic() string {
+	return that.topic
+}
+
+func (that *memorySubscriber) Unsubscribe() error {
+	that.broker.unsubscribe(that)
+	return nil
+}
@@ -0,0 +1,49 @@
+package broker
+
+import (
+	"github.com/gogf/gf/v2/container/gtype"
+	"github.com/gogf/gf/v2/test/gtest"
+	"testing"
+)
+
+func TestMemoryBroker(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		b := NewBroker()
+		_, err := b.Subscribe("foo", func(Event) error { return nil })
+		t.Assert(err, ErrNotConnected)
+		t.Assert(b.Connect(), nil)
+
+		var (
+			all   = gtype.NewInt()
+			queue = gtype.NewInt()
+		)
+		sub, err := b.Subscribe("foo", func(e Event) error {
+			t.Assert(e.Topic(), "foo")
+			t.Assert(e.Message().Body, []byte("bar"))
+			all.Add(1)
+			return nil
+		})
+		t.AssertNil(err)
+		for i := 0; i < 3; i++ {
+			_, err = b.Subscribe("foo", func(Event) error {
+				queue.Add(1)
+				return nil
+			}, OptQueue("q"))
+			t.AssertNil(err)
+		}
+		for i := 0; i < 10; i++ {
+			t.AssertNil(b.Publish("foo", &Message{Body: []byte("bar")}))
+		}
+		t.Assert(all.Val(), 10)
+		t.Assert(queue.Val(), 10)
+
+		t.AssertNil(sub.Unsubscribe())
+		t.AssertNil(b.Publish("foo", &Message{Body: []byte("bar")}))
+		t.Assert(all.Val(), 10)
+		t.Assert(queue.Val(), 11)
+		t.Assert(b.Publish("", &Message{}), ErrInvalidTopic)
+
+		t.AssertNil(b.Disconnect())
+		t.Assert(b.Publish("foo", &Message{}), ErrNotConnected)
+	})
+}
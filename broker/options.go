@@ -0,0 +1,70 @@
+package broker
+
+import (
+	"context"
+)
+
+// Options 配置
+type Options struct {
+	AddrList []string        // 地址列表
+	Context  context.Context // 上下文信息
+}
+
+// PublishOptions 发布消息参数
+type PublishOptions struct {
+	Context context.Context
+}
+
+// SubscribeOptions 订阅参数
+type SubscribeOptions struct {
+	// Queue 订阅者分组，同一分组内的订阅者，每条消息只会有一个订阅者收到
+	// 为空则表示每个订阅者都会收到消息
+	Queue   string
+	Context context.Context
+}
+
+// NewSubscribeOptions 初始化订阅参数
+func NewSubscribeOptions(opts ...SubscribeOption) SubscribeOptions {
+	opt := SubscribeOptions{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+	return opt
+}
+
+// OptAddrList 设置地址列表
+func OptAddrList(addrList ...string) Option {
+	return func(o *Options) {
+		o.AddrList = addrList
+	}
+}
+
+// OptContext 设置上下文
+func OptContext(ctx context.Context) Option {
+	return func(o *Options) {
+		o.Context = ctx
+	}
+}
+
+// OptPublishContext 设置发布消息的上下文
+func OptPublishContext(ctx context.Context) PublishOption {
+	return func(o *PublishOptions) {
+		o.Context = ctx
+	}
+}
+
+// OptQueue 设置订阅者分组
+func OptQueue(name string) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.Queue = name
+	}
+}
+
+// OptSubscribeContext 设置订阅的上下文
+func OptSubscribeContext(ctx context.Context) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.Context = ctx
+	}
+}
@@ -0,0 +1,329 @@
+package rpc
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/osgochina/dmicro/broker"
+	"github.com/osgochina/dmicro/client"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/logger"
+	"github.com/osgochina/dmicro/registry"
+	"github.com/osgochina/dmicro/utils/backoff"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 基于drpc的消息代理客户端，通过PUSH消息与消息代理服务端通信
+type rpcBroker struct {
+	sync.RWMutex
+	opts        broker.Options
+	cli         *client.RpcClient
+	ownClient   bool // 客户端是否由消息代理自己创建
+	routed      bool
+	connected   bool
+	subscribers map[string]*rpcSubscriber
+	// 提交订阅和接收投递消息的session，不放入客户端的session池，服务端只会在该session上投递消息
+	sess    drpc.Session
+	closeCh chan struct{}
+}
+
+type rpcSubscriber struct {
+	id      string
+	topic   string
+	handler broker.Handler
+	opts    broker.SubscribeOptions
+	broker  *rpcBroker
+}
+
+type rpcEvent struct {
+	topic   string
+	message *broker.Message
+}
+
+// NewBroker 创建基于drpc的消息代理客户端
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.Options{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &rpcBroker{
+		opts:        options,
+		subscribers: make(map[string]*rpcSubscriber),
+	}
+}
+
+// Init 初始化配置
+func (that *rpcBroker) Init(opts ...broker.Option) error {
+	that.Lock()
+	defer that.Unlock()
+	for _, o := range opts {
+		o(&that.opts)
+	}
+	return nil
+}
+
+// Options 获取配置
+func (that *rpcBroker) Options() broker.Options {
+	return that.opts
+}
+
+// Address 消息代理服务端地址
+func (that *rpcBroker) Address() string {
+	return strings.Join(that.opts.AddrList, ",")
+}
+
+// Connect 连接消息代理服务端，并且注册接收消息的路由
+func (that *rpcBroker) Connect() error {
+	that.Lock()
+	defer that.Unlock()
+	if that.connected {
+		return nil
+	}
+	if that.cli == nil {
+		that.cli = getClient(that.opts.Context)
+		if that.cli == nil {
+			that.cli = that.newClient()
+			that.ownClient = true
+		}
+	}
+	if !that.routed {
+		that.cli.SubRoute(routePrefix, &swapPlugin{name: "broker-client", value: that}).RoutePushFunc((*clientPush).deliver)
+		// 接收消息的session断开后重新连接
+		that.cli.Endpoint().PluginContainer().AppendRight(&redialPlugin{broker: that})
+		that.routed = true
+	}
+	that.closeCh = make(chan struct{})
+	that.connected = true
+	return nil
+}
+
+// 创建RpcClient，设置了地址列表则直连这些地址，否则通过注册中心发现服务
+func (that *rpcBroker) newClient() *client.RpcClient {
+	serviceName := getServiceName(that.opts.Context)
+	if len(that.opts.AddrList) == 0 {
+		return client.NewRpcClient(serviceName)
+	}
+	service := &registry.Service{Name: serviceName}
+	for _, addr := range that.opts.AddrList {
+		service.Nodes = append(service.Nodes, &registry.Node{
+			Id:      serviceName + "-" + addr,
+			Address: addr,
+		})
+	}
+	return client.NewRpcClient(serviceName, client.OptCustomService(service))
+}
+
+// Disconnect 取消所有订阅，并断开连接
+func (that *rpcBroker) Disconnect() error {
+	that.Lock()
+	defer that.Unlock()
+	if !that.connected {
+		return nil
+	}
+	close(that.closeCh)
+	if that.sess != nil {
+		sess := that.sess
+		// 先清除session，主动关闭不需要重新连接
+		that.sess = nil
+		for id, sub := range that.subscribers {
+			_ = sess.Push(pathUnsubscribe, &Subscription{Id: id, Topic: sub.topic})
+		}
+		_ = sess.Close()
+	}
+	that.subscribers = make(map[string]*rpcSubscriber)
+	if that.ownClient {
+		that.cli.Close()
+		that.cli = nil
+		that.ownClient = false
+		that.routed = false
+	}
+	that.connected = false
+	return nil
+}
+
+// Publish 发布消息
+func (that *rpcBroker) Publish(topic string, m *broker.Message, _ ...broker.PublishOption) error {
+	if len(topic) == 0 {
+		return broker.ErrInvalidTopic
+	}
+	that.RLock()
+	defer that.RUnlock()
+	if !that.connected {
+		return broker.ErrNotConnected
+	}
+	return that.cli.Push(pathPublish, &Publication{Topic: topic, Message: m}).Cause()
+}
+
+// Subscribe 订阅主题
+func (that *rpcBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	if len(topic) == 0 {
+		return nil, broker.ErrInvalidTopic
+	}
+	that.Lock()
+	defer that.Unlock()
+	if !that.connected {
+		return nil, broker.ErrNotConnected
+	}
+	sub := &rpcSubscriber{
+		id:      guid.S(),
+		topic:   topic,
+		handler: h,
+		opts:    broker.NewSubscribeOptions(opts...),
+		broker:  that,
+	}
+	if that.sess == nil {
+		if stat := that.dialLocked(); !stat.OK() {
+			return nil, stat.Cause()
+		}
+	}
+	if err := that.sess.Push(pathSubscribe, sub.subscription()).Cause(); err != nil {
+		return nil, err
+	}
+	that.subscribers[sub.id] = sub
+	return sub, nil
+}
+
+// 建立接收消息的session，并在新session上重新提交全部订阅，调用方需要持有锁。
+// 服务端对相同订阅id的重复提交是幂等的，订阅会转移到新的session上
+func (that *rpcBroker) dialLocked() *drpc.Status {
+	sess, stat := that.cli.Dial()
+	if !stat.OK() {
+		return stat
+	}
+	for _, sub := range that.subscribers {
+		if stat = sess.Push(pathSubscribe, sub.subscription()); !stat.OK() {
+			_ = sess.Close()
+			return stat
+		}
+	}
+	that.sess = sess
+	return nil
+}
+
+// 接收消息的session断开后，不断重新连接直到成功或者断开消息代理
+func (that *rpcBroker) redial(closeCh chan struct{}) {
+	for attempt := 1; ; attempt++ {
+		that.Lock()
+		select {
+		case <-closeCh:
+			that.Unlock()
+			return
+		default:
+		}
+		// 订阅时已经重新建立了session
+		if that.sess != nil {
+			that.Unlock()
+			return
+		}
+		stat := that.dialLocked()
+		that.Unlock()
+		if stat.OK() {
+			return
+		}
+		logger.Warningf(context.TODO(), "broker: redial error: %v", stat)
+		timer := time.NewTimer(backoff.Exponential(attempt, 100*time.Millisecond, maxRedialBackoff))
+		select {
+		case <-timer.C:
+		case <-closeCh:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// session断开时，如果是接收消息的session，则重新连接
+func (that *rpcBroker) disconnected(sess drpc.BaseSession) {
+	that.Lock()
+	defer that.Unlock()
+	if that.sess == nil || that.sess.ID() != sess.ID() {
+		return
+	}
+	that.sess = nil
+	go that.redial(that.closeCh)
+}
+
+// 取消订阅
+func (that *rpcBroker) unsubscribe(sub *rpcSubscriber) error {
+	that.Lock()
+	defer that.Unlock()
+	if _, ok := that.subscribers[sub.id]; !ok {
+		return nil
+	}
+	delete(that.subscribers, sub.id)
+	if that.sess == nil {
+		return nil
+	}
+	return that.sess.Push(pathUnsubscribe, sub.subscription()).Cause()
+}
+
+// 把消息交给订阅者处理
+func (that *rpcBroker) deliver(arg *Delivery) {
+	that.RLock()
+	sub, ok := that.subscribers[arg.Id]
+	that.RUnlock()
+	if !ok {
+		return
+	}
+	if err := sub.handler(&rpcEvent{topic: arg.Topic, message: arg.Message}); err != nil {
+		logger.Warningf(context.TODO(), "broker: subscriber %s handle topic %s error: %v", sub.id, arg.Topic, err)
+	}
+}
+
+func (that *rpcBroker) String() string {
+	return "rpc"
+}
+
+func (that *rpcSubscriber) subscription() *Subscription {
+	return &Subscription{Id: that.id, Topic: that.topic, Queue: that.opts.Queue}
+}
+
+func (that *rpcSubscriber) Options() broker.SubscribeOptions {
+	return that.opts
+}
+
+func (that *rpcSubscriber) Topic() string {
+	return that.topic
+}
+
+func (that *rpcSubscriber) Unsubscribe() error {
+	return that.broker.unsubscribe(that)
+}
+
+func (that *rpcEvent) Topic() string {
+	return that.topic
+}
+
+func (that *rpcEvent) Message() *broker.Message {
+	return that.message
+}
+
+// 客户端的PUSH路由
+type clientPush struct {
+	drpc.PushCtx
+}
+
+// 接收服务端投递的消息
+func (that *clientPush) deliver(arg *Delivery) *drpc.Status {
+	that.Swap().GetVar(swapKey{}).Val().(*rpcBroker).deliver(arg)
+	return nil
+}
+
+// 监听接收消息的session断开的插件
+type redialPlugin struct {
+	broker *rpcBroker
+}
+
+var _ drpc.AfterDisconnectPlugin = new(redialPlugin)
+
+func (that *redialPlugin) Name() string {
+	return "broker-client-redial"
+}
+
+func (that *redialPlugin) AfterDisconnect(sess drpc.BaseSession) *drpc.Status {
+	// 消息代理持有锁时也可能关闭session，异步处理避免死锁
+	go that.broker.disconnected(sess)
+	return nil
+}
@@ -0,0 +1,50 @@
+package rpc
+
+import (
+	"context"
+	"github.com/osgochina/dmicro/broker"
+	"github.com/osgochina/dmicro/client"
+)
+
+type clientKey struct{}
+
+type serviceNameKey struct{}
+
+// OptClient 使用已经创建好的RpcClient连接消息代理服务端
+func OptClient(cli *client.RpcClient) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, clientKey{}, cli)
+	}
+}
+
+// OptServiceName 设置消息代理服务端的服务名称，未设置地址列表时通过注册中心发现该服务
+func OptServiceName(name string) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, serviceNameKey{}, name)
+	}
+}
+
+// 从配置中获取RpcClient
+func getClient(ctx context.Context) *client.RpcClient {
+	if ctx == nil {
+		return nil
+	}
+	cli, _ := ctx.Value(clientKey{}).(*client.RpcClient)
+	return cli
+}
+
+// 从配置中获取服务名称
+func getServiceName(ctx context.Context) string {
+	if ctx != nil {
+		if name, ok := ctx.Value(serviceNameKey{}).(string); ok && len(name) > 0 {
+			return name
+		}
+	}
+	return DefaultServiceName
+}
@@ -0,0 +1,65 @@
+package rpc
+
+import (
+	"github.com/osgochina/dmicro/broker"
+	"github.com/osgochina/dmicro/drpc"
+	"time"
+)
+
+const (
+	// DefaultServiceName 消息代理服务端默认的服务名称
+	DefaultServiceName = "broker"
+
+	// 路由前缀
+	routePrefix = "/broker"
+	// 发布消息
+	pathPublish = routePrefix + "/publish"
+	// 订阅主题
+	pathSubscribe = routePrefix + "/subscribe"
+	// 取消订阅
+	pathUnsubscribe = routePrefix + "/unsubscribe"
+	// 服务端投递消息给订阅者
+	pathDeliver = routePrefix + "/deliver"
+)
+
+// 接收消息的session断开后，重新连接的最长等待时间
+const maxRedialBackoff = 10 * time.Second
+
+// Publication 发布消息的参数
+type Publication struct {
+	Topic   string          `json:"topic"`
+	Message *broker.Message `json:"message"`
+}
+
+// Subscription 订阅参数
+type Subscription struct {
+	Id    string `json:"id"`
+	Topic string `json:"topic"`
+	Queue string `json:"queue"`
+}
+
+// Delivery 服务端投递给订阅者的消息
+type Delivery struct {
+	Id      string          `json:"id"`
+	Topic   string          `json:"topic"`
+	Message *broker.Message `json:"message"`
+}
+
+type swapKey struct{}
+
+// 把消息代理对象写入上下文交换区，供路由处理方法获取
+type swapPlugin struct {
+	name  string
+	value interface{}
+}
+
+var _ drpc.AfterReadPushBodyPlugin = new(swapPlugin)
+
+func (that *swapPlugin) Name() string {
+	return that.name
+}
+
+func (that *swapPlugin) AfterReadPushBody(ctx drpc.ReadCtx) *drpc.Status {
+	ctx.Swap().Set(swapKey{}, that.value)
+	return nil
+}
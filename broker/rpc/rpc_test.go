@@ -0,0 +1,115 @@
+package rpc_test
+
+import (
+	"github.com/gogf/gf/v2/container/gtype"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/broker"
+	"github.com/osgochina/dmicro/broker/rpc"
+	"github.com/osgochina/dmicro/server"
+	"testing"
+	"time"
+)
+
+func TestRpcBroker(t *testing.T) {
+	addr := "127.0.0.1:9195"
+	svr := server.NewRpcServer(rpc.DefaultServiceName, server.OptListenAddress(addr))
+	brokerServer := rpc.NewServer(svr)
+	go func() {
+		_ = svr.ListenAndServe()
+	}()
+	defer svr.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	gtest.C(t, func(t *gtest.T) {
+		var (
+			all   = gtype.NewInt()
+			queue = gtype.NewInt()
+			local = gtype.NewInt()
+		)
+		_, err := brokerServer.Broker().Subscribe("foo", func(broker.Event) error {
+			local.Add(1)
+			return nil
+		})
+		t.AssertNil(err)
+
+		b1 := rpc.NewBroker(broker.OptAddrList(addr))
+		t.AssertNil(b1.Connect())
+		defer b1.Disconnect()
+		sub, err := b1.Subscribe("foo", func(e broker.Event) error {
+			t.Assert(e.Topic(), "foo")
+			t.Assert(e.Message().Header["k"], "v")
+			all.Add(1)
+			return nil
+		})
+		t.AssertNil(err)
+
+		var brokers []broker.Broker
+		for i := 0; i < 2; i++ {
+			b := rpc.NewBroker(broker.OptAddrList(addr))
+			t.AssertNil(b.Connect())
+			_, err = b.Subscribe("foo", func(broker.Event) error {
+				queue.Add(1)
+				return nil
+			}, broker.OptQueue("q"))
+			t.AssertNil(err)
+			brokers = append(brokers, b)
+		}
+		time.Sleep(100 * time.Millisecond)
+		for i := 0; i < 10; i++ {
+			t.AssertNil(b1.Publish("foo", &broker.Message{Header: map[string]string{"k": "v"}, Body: []byte("bar")}))
+		}
+		time.Sleep(500 * time.Millisecond)
+		t.Assert(all.Val(), 10)
+		t.Assert(queue.Val(), 10)
+		t.Assert(local.Val(), 10)
+
+		// 订阅者断开后不再收到消息
+		for _, b := range brokers {
+			t.AssertNil(b.Disconnect())
+		}
+		t.AssertNil(sub.Unsubscribe())
+		time.Sleep(100 * time.Millisecond)
+		t.AssertNil(b1.Publish("foo", &broker.Message{Body: []byte("bar")}))
+		time.Sleep(300 * time.Millisecond)
+		t.Assert(all.Val(), 10)
+		t.Assert(queue.Val(), 10)
+		t.Assert(local.Val(), 11)
+	})
+}
+
+func TestRpcBrokerRedial(t *testing.T) {
+	addr := "127.0.0.1:9215"
+	svr := server.NewRpcServer(rpc.DefaultServiceName, server.OptListenAddress(addr))
+	rpc.NewServer(svr)
+	go func() {
+		_ = svr.ListenAndServe()
+	}()
+	time.Sleep(500 * time.Millisecond)
+
+	gtest.C(t, func(t *gtest.T) {
+		received := gtype.NewInt()
+		b := rpc.NewBroker(broker.OptAddrList(addr))
+		t.AssertNil(b.Connect())
+		defer b.Disconnect()
+		_, err := b.Subscribe("foo", func(broker.Event) error {
+			received.Add(1)
+			return nil
+		})
+		t.AssertNil(err)
+
+		// 服务端重启后，客户端重新连接并且恢复订阅
+		svr.Close()
+		svr = server.NewRpcServer(rpc.DefaultServiceName, server.OptListenAddress(addr))
+		brokerServer := rpc.NewServer(svr)
+		go func() {
+			_ = svr.ListenAndServe()
+		}()
+		defer svr.Close()
+		deadline := time.Now().Add(5 * time.Second)
+		for received.Val() == 0 && time.Now().Before(deadline) {
+			t.AssertNil(brokerServer.Broker().Publish("foo", &broker.Message{Body: []byte("bar")}))
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.AssertGT(received.Val(), 0)
+	})
+}
@@ -0,0 +1,164 @@
+package rpc
+
+import (
+	"context"
+	"github.com/osgochina/dmicro/broker"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/logger"
+	"github.com/osgochina/dmicro/server"
+	"sync"
+)
+
+// Server 把RpcServer作为消息代理服务端，客户端通过PUSH消息发布和订阅
+type Server struct {
+	mu sync.Mutex
+	// 服务端内部使用内存代理分发消息
+	broker broker.Broker
+	// 远程订阅者 map[subscriptionId]*remoteSubscriber
+	subscribers map[string]*remoteSubscriber
+	// 会话上的订阅 map[sessionId]map[subscriptionId]struct{}
+	sessions map[string]map[string]struct{}
+}
+
+// 远程订阅者
+type remoteSubscriber struct {
+	sess drpc.CtxSession
+	sub  broker.Subscriber
+}
+
+// NewServer 在rpcServer上注册消息代理的路由
+func NewServer(rpcServer *server.RpcServer) *Server {
+	s := &Server{
+		broker:      broker.NewBroker(),
+		subscribers: make(map[string]*remoteSubscriber),
+		sessions:    make(map[string]map[string]struct{}),
+	}
+	_ = s.broker.Connect()
+	// 会话断开后统一取消该会话上的订阅
+	rpcServer.Endpoint().PluginContainer().AppendRight(&disconnectPlugin{server: s})
+	router := rpcServer.SubRoute(routePrefix, &swapPlugin{name: "broker-server", value: s})
+	router.RoutePushFunc((*serverPush).publish)
+	router.RoutePushFunc((*serverPush).subscribe)
+	router.RoutePushFunc((*serverPush).unsubscribe)
+	return s
+}
+
+// Broker 获取服务端内部的消息代理，服务端进程内也可以直接发布和订阅消息
+func (that *Server) Broker() broker.Broker {
+	return that.broker
+}
+
+// 订阅主题，相同订阅id重复提交时，如果会话已经变化，则使用新会话重新订阅
+func (that *Server) subscribe(sess drpc.CtxSession, arg *Subscription) *drpc.Status {
+	if len(arg.Id) == 0 || len(arg.Topic) == 0 {
+		return drpc.NewStatus(drpc.CodeBadMessage, "invalid subscription")
+	}
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	if rs, ok := that.subscribers[arg.Id]; ok {
+		if rs.sess == sess {
+			return nil
+		}
+		that.unsubscribe(arg.Id, rs)
+	}
+	id := arg.Id
+	sub, err := that.broker.Subscribe(arg.Topic, func(e broker.Event) error {
+		return sess.Push(pathDeliver, &Delivery{
+			Id:      id,
+			Topic:   e.Topic(),
+			Message: e.Message(),
+		}).Cause()
+	}, broker.OptQueue(arg.Queue))
+	if err != nil {
+		return drpc.NewStatus(drpc.CodeInternalServerError, err.Error())
+	}
+	that.subscribers[id] = &remoteSubscriber{sess: sess, sub: sub}
+	ids, ok := that.sessions[sess.ID()]
+	if !ok {
+		ids = make(map[string]struct{})
+		that.sessions[sess.ID()] = ids
+	}
+	ids[id] = struct{}{}
+	return nil
+}
+
+// 移除指定会话上的订阅，sess为nil时不检查会话
+func (that *Server) remove(id string, sess drpc.CtxSession) {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	rs, ok := that.subscribers[id]
+	if !ok || (sess != nil && rs.sess != sess) {
+		return
+	}
+	that.unsubscribe(id, rs)
+}
+
+// 取消会话上的所有订阅
+func (that *Server) removeSession(sessionId string) {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	for id := range that.sessions[sessionId] {
+		if rs, ok := that.subscribers[id]; ok {
+			that.unsubscribe(id, rs)
+		}
+	}
+	delete(that.sessions, sessionId)
+}
+
+// 取消订阅并清理记录，调用方需要持有锁
+func (that *Server) unsubscribe(id string, rs *remoteSubscriber) {
+	_ = rs.sub.Unsubscribe()
+	delete(that.subscribers, id)
+	sessionId := rs.sess.ID()
+	if ids, ok := that.sessions[sessionId]; ok {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(that.sessions, sessionId)
+		}
+	}
+}
+
+// 会话断开时清理订阅的插件
+type disconnectPlugin struct {
+	server *Server
+}
+
+var _ drpc.AfterDisconnectPlugin = new(disconnectPlugin)
+
+func (that *disconnectPlugin) Name() string {
+	return "broker-server-disconnect"
+}
+
+func (that *disconnectPlugin) AfterDisconnect(sess drpc.BaseSession) *drpc.Status {
+	that.server.removeSession(sess.ID())
+	return nil
+}
+
+// 服务端的PUSH路由
+type serverPush struct {
+	drpc.PushCtx
+}
+
+func (that *serverPush) server() *Server {
+	return that.Swap().GetVar(swapKey{}).Val().(*Server)
+}
+
+// 发布消息
+func (that *serverPush) publish(arg *Publication) *drpc.Status {
+	if err := that.server().broker.Publish(arg.Topic, arg.Message); err != nil {
+		logger.Warningf(context.TODO(), "broker: publish topic %s error: %v", arg.Topic, err)
+		return drpc.NewStatus(drpc.CodeBadMessage, err.Error())
+	}
+	return nil
+}
+
+// 订阅主题
+func (that *serverPush) subscribe(arg *Subscription) *drpc.Status {
+	return that.server().subscribe(that.Session(), arg)
+}
+
+// 取消订阅
+func (that *serverPush) unsubscribe(arg *Subscription) *drpc.Status {
+	that.server().remove(arg.Id, that.Session())
+	return nil
+}
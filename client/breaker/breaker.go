@@ -0,0 +1,234 @@
+package breaker
+
+import (
+	"context"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/eventbus"
+	"github.com/osgochina/dmicro/logger"
+	"github.com/osgochina/dmicro/metrics"
+	"sync"
+	"time"
+)
+
+// State 熔断器状态
+type State int32
+
+const (
+	// StateClosed 关闭状态，请求正常通过
+	StateClosed State = iota
+	// StateOpen 打开状态，请求直接被拒绝
+	StateOpen
+	// StateHalfOpen 半开状态，只允许少量探测请求通过
+	StateHalfOpen
+)
+
+// String 状态名称
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// OnStateChangeEvent 熔断器状态变化的事件名称，事件参数的key见 metrics.BreakerKeyName 等常量
+const OnStateChangeEvent = metrics.OnBreakerStateChangeEvent
+
+// StateChange 熔断器状态变化事件
+type StateChange struct {
+	*eventbus.Event
+	// 熔断器名称
+	Breaker string
+	// 变化前的状态
+	From State
+	// 变化后的状态
+	To State
+}
+
+// Breaker 熔断器
+type Breaker struct {
+	name       string
+	opts       *Options
+	mu         sync.Mutex
+	state      State
+	generation uint64    // 每次状态变化加1，用于忽略上一个状态中请求的结果
+	expiry     time.Time // 关闭状态下统计窗口的结束时间，打开状态下进入半开状态的时间
+	// 当前统计周期的计数
+	requests    int
+	failures    int
+	consecutive int
+	successes   int
+}
+
+// 创建熔断器
+func newBreaker(name string, opts *Options) *Breaker {
+	b := &Breaker{
+		name: name,
+		opts: opts,
+	}
+	b.toState(StateClosed, time.Now())
+	return b
+}
+
+// Name 熔断器名称
+func (that *Breaker) Name() string {
+	return that.name
+}
+
+// State 获取熔断器当前状态
+func (that *Breaker) State() State {
+	that.mu.Lock()
+	state, change := that.currentState(time.Now())
+	that.mu.Unlock()
+	that.publish(change)
+	return state
+}
+
+// Allow 判断请求是否允许通过，允许时返回的方法用于上报请求结果，被拒绝时返回 CodeCircuitOpen 状态
+func (that *Breaker) Allow() (report func(stat *drpc.Status), stat *drpc.Status) {
+	that.mu.Lock()
+	state, change := that.currentState(time.Now())
+	switch {
+	case state == StateOpen,
+		state == StateHalfOpen && that.requests >= that.opts.HalfOpenRequests:
+		that.mu.Unlock()
+		that.publish(change)
+		return nil, drpc.NewStatusByCodeText(drpc.CodeCircuitOpen, "circuit breaker "+that.name+" is "+state.String(), false)
+	}
+	that.requests++
+	generation := that.generation
+	that.mu.Unlock()
+	that.publish(change)
+
+	var once sync.Once
+	return func(stat *drpc.Status) {
+		once.Do(func() {
+			that.onResult(generation, that.opts.IsFailure(stat))
+		})
+	}, nil
+}
+
+// 处理请求结果
+func (that *Breaker) onResult(generation uint64, failure bool) {
+	that.mu.Lock()
+	now := time.Now()
+	state, change := that.currentState(now)
+	// 请求开始之后状态已经发生变化，结果不再统计
+	if generation != that.generation {
+		that.mu.Unlock()
+		that.publish(change)
+		return
+	}
+	switch state {
+	case StateClosed:
+		if failure {
+			that.failures++
+			that.consecutive++
+			if that.readyToTrip() {
+				change = that.toState(StateOpen, now)
+			}
+		} else {
+			that.successes++
+			that.consecutive = 0
+		}
+	case StateHalfOpen:
+		if failure {
+			change = that.toState(StateOpen, now)
+		} else {
+			that.successes++
+			if that.successes >= that.opts.HalfOpenRequests {
+				change = that.toState(StateClosed, now)
+			}
+		}
+	}
+	that.mu.Unlock()
+	that.publish(change)
+}
+
+// 判断是否需要熔断
+func (that *Breaker) readyToTrip() bool {
+	if that.opts.ConsecutiveFailures > 0 && that.consecutive >= that.opts.ConsecutiveFailures {
+		return true
+	}
+	if that.opts.FailureRatio > 0 && that.requests >= that.opts.MinRequests {
+		return float64(that.failures)/float64(that.requests) >= that.opts.FailureRatio
+	}
+	return false
+}
+
+// 获取当前状态，处理统计窗口结束和打开状态超时
+func (that *Breaker) currentState(now time.Time) (State, *StateChange) {
+	var change *StateChange
+	switch that.state {
+	case StateClosed:
+		if !that.expiry.IsZero() && now.After(that.expiry) {
+			that.resetCounts(now)
+		}
+	case StateOpen:
+		if now.After(that.expiry) {
+			change = that.toState(StateHalfOpen, now)
+		}
+	}
+	return that.state, change
+}
+
+// 切换状态
+func (that *Breaker) toState(state State, now time.Time) *StateChange {
+	from := that.state
+	that.state = state
+	that.generation++
+	that.resetCounts(now)
+	if state == StateOpen {
+		that.expiry = now.Add(that.opts.OpenTimeout)
+	}
+	if from == state {
+		return nil
+	}
+	return &StateChange{
+		Event: eventbus.NewEvent(OnStateChangeEvent, map[interface{}]interface{}{
+			metrics.BreakerKeyName:  that.name,
+			metrics.BreakerKeyFrom:  from.String(),
+			metrics.BreakerKeyTo:    state.String(),
+			metrics.BreakerKeyState: int(state),
+		}),
+		Breaker: that.name,
+		From:    from,
+		To:      state,
+	}
+}
+
+// 清空统计计数
+func (that *Breaker) resetCounts(now time.Time) {
+	that.requests = 0
+	that.failures = 0
+	that.consecutive = 0
+	that.successes = 0
+	that.expiry = time.Time{}
+	if that.state == StateClosed && that.opts.Window > 0 {
+		that.expiry = now.Add(that.opts.Window)
+	}
+}
+
+// 发布状态变化事件
+func (that *Breaker) publish(change *StateChange) {
+	if change == nil {
+		return
+	}
+	logger.Infof(context.TODO(), "circuit breaker %s state changed from %s to %s", change.Breaker, change.From, change.To)
+	var err error
+	if that.opts.EventBus != nil {
+		if that.opts.EventBus.HasListeners(OnStateChangeEvent) {
+			err = that.opts.EventBus.Publish(change)
+		}
+	} else if eventbus.HasListeners(OnStateChangeEvent) {
+		err = eventbus.Publish(change)
+	}
+	if err != nil {
+		logger.Warning(context.TODO(), err)
+	}
+}
@@ -0,0 +1,124 @@
+package breaker
+
+import (
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/eventbus"
+	"testing"
+	"time"
+)
+
+var (
+	statOK   *drpc.Status
+	statFail = drpc.NewStatusByCodeText(drpc.CodeInternalServerError, nil, false)
+	statBiz  = drpc.NewStatus(1001, "biz error", nil)
+)
+
+func TestBreakerStateMachine(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		bus := eventbus.New("breaker_test")
+		var changes []string
+		_ = bus.Listen(OnStateChangeEvent, eventbus.ListenerFunc(func(e eventbus.IEvent) error {
+			change := e.(*StateChange)
+			changes = append(changes, change.From.String()+"->"+change.To.String())
+			return nil
+		}))
+		g := NewGroup(
+			OptConsecutiveFailures(3),
+			OptOpenTimeout(100*time.Millisecond),
+			OptEventBus(bus),
+		)
+		b := g.Get(g.Name("foo", "127.0.0.1:1", "/a/b"))
+		t.Assert(b.Name(), "foo")
+
+		// 业务错误不算作失败
+		for i := 0; i < 5; i++ {
+			report, stat := b.Allow()
+			t.AssertNil(stat)
+			report(statBiz)
+		}
+		t.Assert(b.State(), StateClosed)
+
+		for i := 0; i < 3; i++ {
+			report, stat := b.Allow()
+			t.AssertNil(stat)
+			report(statFail)
+		}
+		t.Assert(b.State(), StateOpen)
+		t.Assert(g.IsOpen("foo"), true)
+		_, stat := b.Allow()
+		t.Assert(stat.Code(), drpc.CodeCircuitOpen)
+
+		// 超时后进入半开状态，只允许一个探测请求
+		time.Sleep(150 * time.Millisecond)
+		report, stat := b.Allow()
+		t.AssertNil(stat)
+		t.Assert(b.State(), StateHalfOpen)
+		_, stat = b.Allow()
+		t.Assert(stat.Code(), drpc.CodeCircuitOpen)
+		// 探测失败重新打开
+		report(statFail)
+		t.Assert(b.State(), StateOpen)
+
+		time.Sleep(150 * time.Millisecond)
+		report, stat = b.Allow()
+		t.AssertNil(stat)
+		report(statOK)
+		t.Assert(b.State(), StateClosed)
+
+		t.Assert(changes, []string{
+			"closed->open", "open->half-open", "half-open->open",
+			"open->half-open", "half-open->closed",
+		})
+	})
+}
+
+func TestBreakerFailureRatio(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		g := NewGroup(
+			OptScope(ScopeMethod),
+			OptConsecutiveFailures(0),
+			OptFailureRatio(0.5, 10),
+		)
+		name := g.Name("foo", "127.0.0.1:1", "/a/b")
+		t.Assert(name, "foo/a/b")
+		b := g.Get(name)
+		for i := 0; i < 10; i++ {
+			report, stat := b.Allow()
+			t.AssertNil(stat)
+			if i%2 == 1 {
+				report(statFail)
+			} else {
+				report(statOK)
+			}
+			// 重复上报无效
+			report(statFail)
+		}
+		t.Assert(b.State(), StateOpen)
+		// 其他方法不受影响
+		t.Assert(g.Get(g.Name("foo", "127.0.0.1:1", "/a/c")).State(), StateClosed)
+	})
+}
+
+func TestBreakerWindow(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		g := NewGroup(
+			OptScope(ScopeNode),
+			OptConsecutiveFailures(2),
+			OptWindow(100*time.Millisecond),
+		)
+		name := g.Name("foo", "127.0.0.1:1", "/a/b")
+		t.Assert(name, "foo@127.0.0.1:1")
+		b := g.Get(name)
+		report, _ := b.Allow()
+		report(statFail)
+		// 统计窗口结束后计数被清空
+		time.Sleep(150 * time.Millisecond)
+		report, _ = b.Allow()
+		report(statFail)
+		t.Assert(b.State(), StateClosed)
+		report, _ = b.Allow()
+		report(statFail)
+		t.Assert(b.State(), StateOpen)
+	})
+}
@@ -0,0 +1,63 @@
+package breaker
+
+import (
+	"sync"
+)
+
+// Group 熔断器组，按粒度管理多个熔断器
+type Group struct {
+	opts     Options
+	mu       sync.RWMutex
+	breakers map[string]*Breaker
+}
+
+// NewGroup 创建熔断器组
+func NewGroup(opts ...Option) *Group {
+	return &Group{
+		opts:     NewOptions(opts...),
+		breakers: make(map[string]*Breaker),
+	}
+}
+
+// Options 获取配置
+func (that *Group) Options() Options {
+	return that.opts
+}
+
+// Name 根据熔断器粒度生成熔断器名称
+func (that *Group) Name(service string, address string, serviceMethod string) string {
+	switch that.opts.Scope {
+	case ScopeNode:
+		return service + "@" + address
+	case ScopeMethod:
+		return service + serviceMethod
+	default:
+		return service
+	}
+}
+
+// Get 获取熔断器，不存在则创建
+func (that *Group) Get(name string) *Breaker {
+	that.mu.RLock()
+	b, ok := that.breakers[name]
+	that.mu.RUnlock()
+	if ok {
+		return b
+	}
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	if b, ok = that.breakers[name]; ok {
+		return b
+	}
+	b = newBreaker(name, &that.opts)
+	that.breakers[name] = b
+	return b
+}
+
+// IsOpen 判断熔断器是否处于打开状态，熔断器不存在时返回false
+func (that *Group) IsOpen(name string) bool {
+	that.mu.RLock()
+	b, ok := that.breakers[name]
+	that.mu.RUnlock()
+	return ok && b.State() == StateOpen
+}
@@ -0,0 +1,149 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/eventbus"
+	"time"
+)
+
+// Scope 熔断器的粒度
+type Scope int
+
+const (
+	// ScopeService 每个服务一个熔断器
+	ScopeService Scope = iota
+	// ScopeNode 每个服务节点一个熔断器
+	ScopeNode
+	// ScopeMethod 每个服务方法一个熔断器
+	ScopeMethod
+)
+
+// 默认配置
+const (
+	defaultConsecutiveFailures = 5
+	defaultFailureRatio        = 0.5
+	defaultMinRequests         = 20
+	defaultWindow              = 10 * time.Second
+	defaultOpenTimeout         = 5 * time.Second
+	defaultHalfOpenRequests    = 1
+)
+
+// Options 熔断器配置
+type Options struct {
+	// 熔断器的粒度
+	Scope Scope
+	// 连续失败多少次后熔断
+	ConsecutiveFailures int
+	// 统计窗口内失败率达到多少后熔断
+	FailureRatio float64
+	// 统计窗口内请求数达到多少后才计算失败率
+	MinRequests int
+	// 关闭状态下的统计窗口，窗口结束后清空计数
+	Window time.Duration
+	// 熔断后多久进入半开状态
+	OpenTimeout time.Duration
+	// 半开状态允许通过的探测请求数，全部成功后关闭熔断器
+	HalfOpenRequests int
+	// 判断请求结果是否算作失败
+	IsFailure func(stat *drpc.Status) bool
+	// 状态变化事件发布到的事件总线，为空则使用默认事件总线
+	EventBus *eventbus.EventBus
+	// 扩展配置，可以添加自定义选项
+	Context context.Context
+}
+
+// Option 熔断器配置项
+type Option func(*Options)
+
+// NewOptions 初始化配置
+func NewOptions(opts ...Option) Options {
+	o := Options{
+		Scope:               ScopeService,
+		ConsecutiveFailures: defaultConsecutiveFailures,
+		FailureRatio:        defaultFailureRatio,
+		MinRequests:         defaultMinRequests,
+		Window:              defaultWindow,
+		OpenTimeout:         defaultOpenTimeout,
+		HalfOpenRequests:    defaultHalfOpenRequests,
+		IsFailure:           IsFailure,
+		Context:             context.Background(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// IsFailure 默认的失败判断，链接错误、超时和服务端内部错误算作失败，业务错误不算
+func IsFailure(stat *drpc.Status) bool {
+	if stat.OK() {
+		return false
+	}
+	switch code := stat.Code(); code {
+	case drpc.CodeDialFailed, drpc.CodeConnClosed, drpc.CodeWriteFailed, drpc.CodeHandleTimeout:
+		return true
+	case drpc.CodeCallCanceled:
+		return errors.Is(stat.Cause(), context.DeadlineExceeded)
+	default:
+		return code >= 500 && code < 600
+	}
+}
+
+// OptScope 设置熔断器的粒度
+func OptScope(scope Scope) Option {
+	return func(o *Options) {
+		o.Scope = scope
+	}
+}
+
+// OptConsecutiveFailures 设置连续失败多少次后熔断
+func OptConsecutiveFailures(n int) Option {
+	return func(o *Options) {
+		o.ConsecutiveFailures = n
+	}
+}
+
+// OptFailureRatio 设置统计窗口内请求数达到minRequests后，失败率达到ratio则熔断
+func OptFailureRatio(ratio float64, minRequests int) Option {
+	return func(o *Options) {
+		o.FailureRatio = ratio
+		o.MinRequests = minRequests
+	}
+}
+
+// OptWindow 设置关闭状态下的统计窗口
+func OptWindow(d time.Duration) Option {
+	return func(o *Options) {
+		o.Window = d
+	}
+}
+
+// OptOpenTimeout 设置熔断后多久进入半开状态
+func OptOpenTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.OpenTimeout = d
+	}
+}
+
+// OptHalfOpenRequests 设置半开状态允许通过的探测请求数
+func OptHalfOpenRequests(n int) Option {
+	return func(o *Options) {
+		o.HalfOpenRequests = n
+	}
+}
+
+// OptIsFailure 设置判断请求结果是否算作失败的方法
+func OptIsFailure(fn func(stat *drpc.Status) bool) Option {
+	return func(o *Options) {
+		o.IsFailure = fn
+	}
+}
+
+// OptEventBus 设置状态变化事件发布到的事件总线
+func OptEventBus(bus *eventbus.EventBus) Option {
+	return func(o *Options) {
+		o.EventBus = bus
+	}
+}
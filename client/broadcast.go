@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/message"
+	"github.com/osgochina/dmicro/registry"
+	"github.com/osgochina/dmicro/selector"
+	"sync"
+)
+
+// BroadcastResult 广播请求单个节点的结果
+type BroadcastResult struct {
+	// 请求的节点
+	Node *registry.Node
+	// 节点返回的结果，由 newResult 创建
+	Result interface{}
+	// 请求状态，成功时为nil
+	Status *drpc.Status
+	// 节点是否被选择器剔除，被剔除的节点不发送请求，状态为 drpc.CodeCircuitOpen
+	Ejected bool
+}
+
+// Broadcast 向服务的所有节点发送相同的请求，返回每个节点的结果，顺序和节点列表一致。
+// newResult 为每个节点创建接收结果的对象，可以为nil。
+// 通过 WithConcurrency 限制同时请求的节点数，通过 WithQuorum 设置成功多少个节点后提前返回，
+// 提前返回时未完成的请求会被取消，状态为 drpc.CodeCallCanceled。
+// 广播请求不会重试，也不使用对冲策略。被选择器剔除的节点同样出现在结果中，但不会发送请求。
+// 节点列表为空或者成功的节点数未达到 Quorum 时返回错误状态
+func (that *RpcClient) Broadcast(serviceMethod string, args interface{}, newResult func() interface{}, setting ...message.MsgSetting) ([]*BroadcastResult, *drpc.Status) {
+	select {
+	case <-that.closeCh:
+		return nil, rerClientClosed
+	default:
+	}
+	cc := that.newCallConfig(serviceMethod, setting)
+	defer cc.cancel()
+	nodes, stat := that.allNodes(cc)
+	if stat != nil {
+		return nil, stat
+	}
+	// 达到quorum后取消其他请求
+	ctx, cancel := context.WithCancel(cc.ctx)
+	defer cancel()
+	cc.withContext(ctx)
+
+	concurrency := cc.Concurrency
+	if concurrency <= 0 || concurrency > len(nodes) {
+		concurrency = len(nodes)
+	}
+	var (
+		results   = make([]*BroadcastResult, len(nodes))
+		sem       = make(chan struct{}, concurrency)
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i, node := range nodes {
+		r := &BroadcastResult{Node: node}
+		if newResult != nil {
+			r.Result = newResult()
+		}
+		results[i] = r
+		if that.ejected(node) {
+			r.Ejected = true
+			r.Status = rerNodeEjected
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			r.Status = drpc.NewStatusByCodeText(drpc.CodeCallCanceled, ctx.Err(), false)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			sess, report, stat := that.nodeSession(serviceMethod, r.Node)
+			if stat != nil {
+				if drpc.IsConnError(stat) {
+					that.mark(r.Node, stat, 0)
+				}
+				r.Status = stat
+				return
+			}
+			r.Status = that.callNode(sess, r.Node, report, serviceMethod, args, r.Result, cc.setting).Status()
+			if !r.Status.OK() {
+				return
+			}
+			mu.Lock()
+			succeeded++
+			if cc.Quorum > 0 && succeeded >= cc.Quorum {
+				cancel()
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if cc.Quorum > 0 && succeeded < cc.Quorum {
+		return results, drpc.NewStatus(drpc.CodeInternalServerError,
+			fmt.Sprintf("dmicro.client broadcast %s quorum not reached: %d/%d", serviceMethod, succeeded, cc.Quorum))
+	}
+	return results, nil
+}
+
+// 从选择器的缓存中获取服务的所有节点，只使用单次请求的节点过滤器，被剔除的节点也会返回。
+// 选择器不支持获取全部节点时直接查询注册表
+func (that *RpcClient) allNodes(cc *callConfig) ([]*registry.Node, *drpc.Status) {
+	if len(cc.Address) > 0 {
+		return []*registry.Node{{Id: cc.Address, Address: cc.Address}}, nil
+	}
+	serviceName := that.Options().ServiceName
+	if that.opts.Selector == nil {
+		that.defaultSelector(serviceName)
+	}
+	services, err := that.getService(serviceName)
+	if err != nil {
+		if err == selector.ErrNotFound || err == registry.ErrNotFound {
+			return nil, drpc.NewStatus(drpc.CodeInternalServerError, fmt.Sprintf("dmicro.client service %s: %s", serviceName, err.Error()))
+		}
+		return nil, drpc.NewStatus(drpc.CodeInternalServerError, fmt.Sprintf("dmicro.client error selecting %s node: %s", serviceName, err.Error()))
+	}
+	for _, filter := range cc.Filters {
+		services = filter(services)
+	}
+	var nodes []*registry.Node
+	for _, service := range services {
+		for _, node := range service.Nodes {
+			if !containsNode(nodes, node) {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, drpc.NewStatus(drpc.CodeInternalServerError, fmt.Sprintf("dmicro.client error selecting %s node: %s", serviceName, selector.ErrNoneAvailable.Error()))
+	}
+	return nodes, nil
+}
+
+// 获取服务的全部节点
+func (that *RpcClient) getService(serviceName string) ([]*registry.Service, error) {
+	if s, ok := that.opts.Selector.(selector.OutlierSelector); ok {
+		return s.GetService(serviceName)
+	}
+	reg := that.registry()
+	if reg == nil {
+		return nil, selector.ErrNotFound
+	}
+	return reg.GetService(serviceName)
+}
+
+// 节点是否被选择器剔除，选择器不支持剔除节点时视为未剔除
+func (that *RpcClient) ejected(node *registry.Node) bool {
+	s, ok := that.opts.Selector.(selector.OutlierSelector)
+	if !ok {
+		return false
+	}
+	return s.Ejected(that.opts.ServiceName, node)
+}
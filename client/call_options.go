@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"github.com/osgochina/dmicro/client/breaker"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/message"
+	"github.com/osgochina/dmicro/selector"
+	"time"
+)
+
+// CallOption 单次请求的配置项，和 message.MsgSetting 一样作为 Call、AsyncCall、Push 的可变参数传入
+type CallOption = message.MsgSetting
+
+// CallOptions 单次请求的配置
+type CallOptions struct {
+	// 请求超时时间，超时后请求被取消
+	Timeout time.Duration
+	// 节点过滤器
+	Filters []selector.Filter
+	// 指定请求的节点地址，不再通过selector选择节点
+	Address string
+	// 覆盖客户端配置的重试策略
+	RetryPolicy *RetryPolicy
+	// 覆盖客户端配置的对冲请求策略
+	HedgePolicy *HedgePolicy
+	// 广播请求同时请求的最大节点数，为0则不限制
+	Concurrency int
+	// 广播请求成功的节点数达到该值后立即返回并取消其他请求，为0则等待所有节点返回
+	Quorum int
+}
+
+type callOptionsKey struct{}
+
+// 解析请求设置时使用的上下文，CallOption 通过它把配置写入 CallOptions
+type optionsCtx struct {
+	context.Context
+	opts *CallOptions
+}
+
+func (that *optionsCtx) Value(key interface{}) interface{} {
+	if key == (callOptionsKey{}) {
+		return that.opts
+	}
+	return that.Context.Value(key)
+}
+
+// 创建请求配置项，作用在真正发送的消息上时什么也不做
+func newCallOption(fn func(*CallOptions)) CallOption {
+	return func(m message.Message) {
+		if opts, ok := m.Context().Value(callOptionsKey{}).(*CallOptions); ok {
+			fn(opts)
+		}
+	}
+}
+
+// WithTimeout 设置本次请求的超时时间
+func WithTimeout(d time.Duration) CallOption {
+	return newCallOption(func(o *CallOptions) {
+		o.Timeout = d
+	})
+}
+
+// WithFilter 设置本次请求的节点过滤器
+func WithFilter(fn ...selector.Filter) CallOption {
+	return newCallOption(func(o *CallOptions) {
+		o.Filters = append(o.Filters, fn...)
+	})
+}
+
+// WithAddress 指定本次请求的节点地址
+func WithAddress(addr string) CallOption {
+	return newCallOption(func(o *CallOptions) {
+		o.Address = addr
+	})
+}
+
+// WithRetryPolicy 设置本次请求的重试策略
+func WithRetryPolicy(policy RetryPolicy) CallOption {
+	return newCallOption(func(o *CallOptions) {
+		o.RetryPolicy = &policy
+	})
+}
+
+// WithHedge 为本次请求开启对冲请求，覆盖客户端为该方法配置的策略，MaxRequests为1时关闭对冲
+func WithHedge(policy HedgePolicy) CallOption {
+	return newCallOption(func(o *CallOptions) {
+		o.HedgePolicy = &policy
+	})
+}
+
+// WithConcurrency 设置广播请求同时请求的最大节点数
+func WithConcurrency(n int) CallOption {
+	return newCallOption(func(o *CallOptions) {
+		o.Concurrency = n
+	})
+}
+
+// WithQuorum 设置广播请求成功多少个节点后返回
+func WithQuorum(n int) CallOption {
+	return newCallOption(func(o *CallOptions) {
+		o.Quorum = n
+	})
+}
+
+// WithBodyCodec 设置本次请求的消息编码格式
+func WithBodyCodec(bodyCodecName string) CallOption {
+	return drpc.WithBodyCodec(bodyCodecName)
+}
+
+// 单次请求的配置，由请求设置解析得到
+type callConfig struct {
+	CallOptions
+	// 请求的上下文
+	ctx    context.Context
+	cancel context.CancelFunc
+	// 实际发送消息时使用的设置
+	setting []message.MsgSetting
+	// 选择节点的参数
+	selectOpts []selector.SelectOption
+	// 重试策略
+	policy *RetryPolicy
+	// 对冲请求策略
+	hedge *HedgePolicy
+}
+
+// 解析请求设置，请求结束后需要调用 callConfig.cancel
+func (that *RpcClient) newCallConfig(serviceMethod string, setting []message.MsgSetting) *callConfig {
+	cc := &callConfig{
+		ctx:     context.Background(),
+		cancel:  func() {},
+		setting: setting,
+	}
+	var hashKey string
+	if len(setting) > 0 {
+		msg := message.GetMessage()
+		for _, s := range setting {
+			// 设置可能会替换消息的上下文，每次都确保 CallOption 能拿到配置
+			if _, ok := msg.Context().(*optionsCtx); !ok {
+				message.WithContext(&optionsCtx{Context: msg.Context(), opts: &cc.CallOptions})(msg)
+			}
+			s(msg)
+		}
+		cc.ctx = msg.Context()
+		if oc, ok := cc.ctx.(*optionsCtx); ok {
+			cc.ctx = oc.Context
+		}
+		if len(that.opts.HashKeyMeta) > 0 {
+			hashKey = msg.Meta().GetVar(that.opts.HashKeyMeta).String()
+		}
+		message.PutMessage(msg)
+	}
+	if cc.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(cc.ctx, cc.Timeout)
+		cc.cancel = cancel
+		cc.withContext(ctx)
+	}
+	// 节点粒度的熔断器，过滤掉已经熔断的节点
+	if that.opts.CircuitBreaker != nil && that.opts.CircuitBreaker.Options().Scope == breaker.ScopeNode {
+		cc.selectOpts = append(cc.selectOpts, selector.OptWithFilter(that.breakerFilter))
+	}
+	if len(cc.Filters) > 0 {
+		cc.selectOpts = append(cc.selectOpts, selector.OptWithFilter(cc.Filters...))
+	}
+	if len(hashKey) > 0 {
+		cc.selectOpts = append(cc.selectOpts, selector.OptWithHashKey(hashKey))
+	}
+	cc.policy = cc.RetryPolicy
+	if cc.policy == nil {
+		cc.policy = that.retryPolicy(serviceMethod)
+	}
+	cc.hedge = cc.HedgePolicy
+	if cc.hedge == nil {
+		cc.hedge = that.hedgePolicy(serviceMethod)
+	}
+	return cc
+}
+
+// 使用新的上下文发送消息
+func (that *callConfig) withContext(ctx context.Context) {
+	that.ctx = ctx
+	that.setting = append(that.setting[:len(that.setting):len(that.setting)], message.WithContext(ctx))
+}
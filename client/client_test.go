@@ -2,13 +2,17 @@ package client_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/gogf/gf/v2/test/gtest"
 	"github.com/osgochina/dmicro/client"
+	"github.com/osgochina/dmicro/client/breaker"
 	"github.com/osgochina/dmicro/drpc"
 	"github.com/osgochina/dmicro/drpc/message"
 	"github.com/osgochina/dmicro/drpc/plugin/ignorecase"
 	"github.com/osgochina/dmicro/logger"
 	"github.com/osgochina/dmicro/registry"
+	"github.com/osgochina/dmicro/registry/memory"
 	"github.com/osgochina/dmicro/server"
 	"sync"
 	"testing"
@@ -118,3 +122,458 @@ func TestNewRpcClientMemory(t *testing.T) {
 		t.Assert(result, 15)
 	})
 }
+
+func TestCircuitBreaker(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		cli := client.NewRpcClient("breaker",
+			client.OptCustomService(&registry.Service{
+				Name:  "breaker",
+				Nodes: []*registry.Node{{Id: "breaker-1", Address: "127.0.0.1:9193"}},
+			}),
+			client.OptRetryTimes(1),
+			client.OptCircuitBreaker(breaker.OptConsecutiveFailures(2)),
+		)
+		defer cli.Close()
+		var result int
+		// 节点不可达，连续失败后熔断
+		for i := 0; i < 2; i++ {
+			stat := cli.Call("/math/add", []int{1, 2}, &result).Status()
+			t.Assert(stat.Code(), drpc.CodeDialFailed)
+		}
+		stat := cli.Call("/math/add", []int{1, 2}, &result).Status()
+		t.Assert(stat.Code(), drpc.CodeCircuitOpen)
+		stat = cli.Push("/math/add", []int{1, 2})
+		t.Assert(stat.Code(), drpc.CodeCircuitOpen)
+	})
+}
+
+type Flaky struct {
+	drpc.CallCtx
+}
+
+var flakyCalls = make(map[string]int)
+var flakyMu sync.Mutex
+
+// Do 每个key的前两次请求失败
+func (that *Flaky) Do(key *string) (int, *drpc.Status) {
+	flakyMu.Lock()
+	defer flakyMu.Unlock()
+	flakyCalls[*key]++
+	if flakyCalls[*key] <= 2 {
+		return 0, drpc.NewStatus(drpc.CodeInternalServerError, "flaky", nil)
+	}
+	return flakyCalls[*key], nil
+}
+
+func TestRetryPolicy(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		svr := server.NewRpcServer("flaky", server.OptListenAddress("127.0.0.1:9196"))
+		svr.RouteCall(new(Flaky), drpc.Idempotent)
+		go func() {
+			_ = svr.ListenAndServe()
+		}()
+		defer svr.Close()
+		time.Sleep(500 * time.Millisecond)
+
+		newClient := func(idempotent bool, opts ...client.Option) *client.RpcClient {
+			node := &registry.Node{Id: "flaky-1", Address: "127.0.0.1:9196", Metadata: map[string]string{}}
+			if idempotent {
+				node.Metadata[registry.MetadataIdempotent] = "/flaky/do"
+			}
+			return client.NewRpcClient("flaky", append([]client.Option{
+				client.OptCustomService(&registry.Service{Name: "flaky", Nodes: []*registry.Node{node}}),
+				client.OptRetryPolicy(client.RetryPolicy{
+					MaxAttempts:    3,
+					Backoff:        client.ExponentialBackoff(10*time.Millisecond, 100*time.Millisecond),
+					RetryableCodes: []int32{drpc.CodeInternalServerError},
+				}),
+			}, opts...)...)
+		}
+		var result int
+
+		// 路由没有标记为幂等，不重试
+		cli := newClient(false)
+		stat := cli.Call("/flaky/do", "a", &result).Status()
+		t.Assert(stat.Code(), drpc.CodeInternalServerError)
+		cli.Close()
+
+		// 幂等的路由，失败后重试直到成功
+		cli = newClient(true)
+		stat = cli.Call("/flaky/do", "b", &result).Status()
+		t.Assert(stat.OK(), true)
+		t.Assert(result, 3)
+		cli.Close()
+
+		// 重试预算不足，不重试
+		cli = newClient(true, client.OptRetryBudget(0, 0))
+		stat = cli.Call("/flaky/do", "c", &result).Status()
+		t.Assert(stat.Code(), drpc.CodeInternalServerError)
+		cli.Close()
+
+		// 等待重试期间请求的上下文结束，立即返回上下文的状态
+		cli = newClient(true, client.OptRetryPolicy(client.RetryPolicy{
+			MaxAttempts:    3,
+			Backoff:        client.ExponentialBackoff(time.Second, time.Second),
+			RetryableCodes: []int32{drpc.CodeInternalServerError},
+		}))
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		start := time.Now()
+		stat = cli.Call("/flaky/do", "d", &result, message.WithContext(ctx)).Status()
+		cancel()
+		t.Assert(stat.Code(), drpc.CodeCallCanceled)
+		t.Assert(errors.Is(stat.Cause(), context.DeadlineExceeded), true)
+		t.Assert(time.Since(start) < time.Second, true)
+		cli.Close()
+	})
+}
+
+type Hedge struct {
+	drpc.CallCtx
+}
+
+// Get 9197端口的服务响应很慢
+func (that *Hedge) Get(_ *string) (string, *drpc.Status) {
+	addr := that.Session().LocalAddr().String()
+	if addr == "127.0.0.1:9197" {
+		select {
+		case <-time.After(time.Second):
+		case <-that.Context().Done():
+		}
+	}
+	return addr, nil
+}
+
+func TestHedgePolicy(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		for _, addr := range []string{"127.0.0.1:9197", "127.0.0.1:9198"} {
+			svr := server.NewRpcServer("hedge", server.OptListenAddress(addr))
+			svr.RouteCall(new(Hedge))
+			go func() {
+				_ = svr.ListenAndServe()
+			}()
+			defer svr.Close()
+		}
+		time.Sleep(500 * time.Millisecond)
+
+		cli := client.NewRpcClient("hedge",
+			client.OptCustomService(&registry.Service{Name: "hedge", Nodes: []*registry.Node{
+				{Id: "hedge-slow", Address: "127.0.0.1:9197"},
+				{Id: "hedge-fast", Address: "127.0.0.1:9198"},
+			}}),
+			client.OptHedgePolicy(client.HedgePolicy{Delay: 50 * time.Millisecond}, "/hedge/get"),
+		)
+		defer cli.Close()
+		// 无论先选中哪个节点，都能在慢节点响应之前拿到结果
+		for i := 0; i < 4; i++ {
+			var result string
+			start := time.Now()
+			stat := cli.Call("/hedge/get", "x", &result).Status()
+			t.Assert(stat.OK(), true)
+			t.Assert(result, "127.0.0.1:9198")
+			t.AssertLT(time.Since(start).Milliseconds(), 500)
+		}
+
+		// 单次请求开启对冲
+		cli2 := client.NewRpcClient("hedge",
+			client.OptCustomService(&registry.Service{Name: "hedge", Nodes: []*registry.Node{
+				{Id: "hedge-slow", Address: "127.0.0.1:9197"},
+				{Id: "hedge-fast", Address: "127.0.0.1:9198"},
+			}}),
+		)
+		defer cli2.Close()
+		for i := 0; i < 4; i++ {
+			var result string
+			start := time.Now()
+			stat := cli2.Call("/hedge/get", "x", &result, client.WithHedge(client.HedgePolicy{Delay: 50 * time.Millisecond})).Status()
+			t.Assert(stat.OK(), true)
+			t.Assert(result, "127.0.0.1:9198")
+			t.AssertLT(time.Since(start).Milliseconds(), 500)
+		}
+
+		// 按百分位计算延迟时没有设置固定延迟，样本不足时无法确定延迟，拒绝请求
+		var result string
+		stat := cli2.Call("/hedge/get", "x", &result, client.WithHedge(client.HedgePolicy{Percentile: 0.95})).Status()
+		t.Assert(stat.Code(), drpc.CodeInvalidOp)
+		stat = cli2.Call("/hedge/get", "x", &result, client.WithHedge(client.HedgePolicy{Percentile: 95, Delay: time.Millisecond})).Status()
+		t.Assert(stat.Code(), drpc.CodeInvalidOp)
+	})
+}
+
+type Slow struct {
+	drpc.CallCtx
+}
+
+// Sleep 等待指定的毫秒数后返回服务端地址
+func (that *Slow) Sleep(ms *int) (string, *drpc.Status) {
+	select {
+	case <-time.After(time.Duration(*ms) * time.Millisecond):
+	case <-that.Context().Done():
+	}
+	return that.Session().LocalAddr().String(), nil
+}
+
+func TestCallOptions(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		svr := server.NewRpcServer("slow", server.OptListenAddress("127.0.0.1:9199"))
+		svr.RouteCall(new(Slow))
+		go func() {
+			_ = svr.ListenAndServe()
+		}()
+		defer svr.Close()
+		time.Sleep(500 * time.Millisecond)
+
+		// 注册中心里只有一个不可达的节点
+		cli := client.NewRpcClient("slow",
+			client.OptCustomService(&registry.Service{Name: "slow", Nodes: []*registry.Node{
+				{Id: "slow-1", Address: "127.0.0.1:9193"},
+			}}),
+		)
+		defer cli.Close()
+		var result string
+
+		// 指定节点地址
+		stat := cli.Call("/slow/sleep", 0, &result, client.WithAddress("127.0.0.1:9199")).Status()
+		t.Assert(stat.OK(), true)
+		t.Assert(result, "127.0.0.1:9199")
+
+		// 单次请求超时
+		start := time.Now()
+		stat = cli.Call("/slow/sleep", 1000, &result,
+			client.WithTimeout(50*time.Millisecond),
+			client.WithAddress("127.0.0.1:9199"),
+		).Status()
+		t.Assert(stat.Code(), drpc.CodeCallCanceled)
+		t.AssertLT(time.Since(start).Milliseconds(), 500)
+
+		callCmd := cli.AsyncCall("/slow/sleep", 1000, &result, nil,
+			client.WithAddress("127.0.0.1:9199"),
+			client.WithTimeout(50*time.Millisecond),
+			drpc.WithSetMeta("author", "clownfish"),
+		)
+		<-callCmd.Done()
+		t.Assert(callCmd.Status().Code(), drpc.CodeCallCanceled)
+
+		// 过滤掉所有节点
+		stat = cli.Call("/slow/sleep", 0, &result, client.WithFilter(func(services []*registry.Service) []*registry.Service {
+			return nil
+		})).Status()
+		t.Assert(stat.Code(), drpc.CodeInternalServerError)
+
+		// 覆盖重试策略，只请求一次
+		stat = cli.Push("/slow/sleep", 0, client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 1}))
+		t.Assert(stat.Code(), drpc.CodeDialFailed)
+	})
+}
+
+type Broadcast struct {
+	drpc.CallCtx
+}
+
+// Echo 9200端口的服务响应很慢
+func (that *Broadcast) Echo(_ *string) (string, *drpc.Status) {
+	addr := that.Session().LocalAddr().String()
+	if addr == "127.0.0.1:9200" {
+		select {
+		case <-time.After(time.Second):
+		case <-that.Context().Done():
+		}
+	}
+	return addr, nil
+}
+
+func TestBroadcast(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		for _, addr := range []string{"127.0.0.1:9200", "127.0.0.1:9201", "127.0.0.1:9202"} {
+			svr := server.NewRpcServer("broadcast", server.OptListenAddress(addr))
+			svr.RouteCall(new(Broadcast))
+			go func() {
+				_ = svr.ListenAndServe()
+			}()
+			defer svr.Close()
+		}
+		time.Sleep(500 * time.Millisecond)
+
+		cli := client.NewRpcClient("broadcast",
+			client.OptCustomService(&registry.Service{Name: "broadcast", Nodes: []*registry.Node{
+				{Id: "broadcast-1", Address: "127.0.0.1:9200"},
+				{Id: "broadcast-2", Address: "127.0.0.1:9201"},
+				{Id: "broadcast-3", Address: "127.0.0.1:9202"},
+				{Id: "broadcast-4", Address: "127.0.0.1:9193"},
+			}}),
+			client.OptRetryPolicy(client.RetryPolicy{MaxAttempts: 1}),
+		)
+		defer cli.Close()
+		newResult := func() interface{} {
+			return new(string)
+		}
+
+		// 等待所有节点返回
+		results, stat := cli.Broadcast("/broadcast/echo", "x", newResult, client.WithConcurrency(2))
+		t.AssertNil(stat)
+		t.Assert(len(results), 4)
+		for _, r := range results {
+			if r.Node.Address == "127.0.0.1:9193" {
+				t.Assert(r.Status.Code(), drpc.CodeDialFailed)
+				continue
+			}
+			t.Assert(r.Status.OK(), true)
+			t.Assert(*r.Result.(*string), r.Node.Address)
+		}
+
+		// 两个节点成功后返回，慢节点的请求被取消
+		start := time.Now()
+		results, stat = cli.Broadcast("/broadcast/echo", "x", newResult, client.WithQuorum(2))
+		t.AssertNil(stat)
+		t.AssertLT(time.Since(start).Milliseconds(), 500)
+		for _, r := range results {
+			if r.Node.Address == "127.0.0.1:9200" {
+				t.Assert(r.Status.Code(), drpc.CodeCallCanceled)
+			}
+		}
+
+		// 成功的节点数达不到quorum
+		_, stat = cli.Broadcast("/broadcast/echo", "x", newResult,
+			client.WithQuorum(4),
+			client.WithTimeout(100*time.Millisecond),
+		)
+		t.Assert(stat.Code(), drpc.CodeInternalServerError)
+
+		// 过滤掉所有节点
+		_, stat = cli.Broadcast("/broadcast/echo", "x", newResult, client.WithFilter(func(services []*registry.Service) []*registry.Service {
+			return nil
+		}))
+		t.Assert(stat.Code(), drpc.CodeInternalServerError)
+
+		// 被剔除的节点出现在结果中，但是不发送请求
+		unreachable := &registry.Node{Id: "broadcast-4", Address: "127.0.0.1:9193"}
+		for i := 0; i < 5; i++ {
+			cli.Options().Selector.Mark("broadcast", unreachable, errors.New("dial failed"))
+		}
+		results, stat = cli.Broadcast("/broadcast/echo", "x", newResult)
+		t.AssertNil(stat)
+		t.Assert(len(results), 4)
+		for _, r := range results {
+			if r.Node.Address == "127.0.0.1:9193" {
+				t.Assert(r.Ejected, true)
+				t.Assert(r.Status.Code(), drpc.CodeCircuitOpen)
+				continue
+			}
+			t.Assert(r.Ejected, false)
+			t.Assert(r.Status.OK(), true)
+		}
+	})
+}
+
+func TestWatchRegistry(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		addrs := []string{"127.0.0.1:9203", "127.0.0.1:9204", "127.0.0.1:9205"}
+		for _, addr := range addrs {
+			// 服务端不使用全局的mdns注册中心，避免和客户端的注册中心互相影响
+			svr := server.NewRpcServer("watch", server.OptListenAddress(addr),
+				server.OptRegistry(memory.NewRegistry(registry.OptServiceName("watch"), registry.OptServiceVersion("1.0.0"))),
+			)
+			svr.RouteCall(new(Slow))
+			go func() {
+				_ = svr.ListenAndServe()
+			}()
+			defer svr.Close()
+		}
+		time.Sleep(500 * time.Millisecond)
+
+		reg := memory.NewRegistry()
+		_ = reg.Register(&registry.Service{Name: "watch", Version: "1.0.0", Nodes: []*registry.Node{
+			{Id: "watch-1", Address: addrs[0]},
+			{Id: "watch-2", Address: addrs[1]},
+		}})
+		cli := client.NewRpcClient("watch", client.OptRegistry(reg), client.OptPreDial(true))
+		defer cli.Close()
+		hasSession := func(addr string) bool {
+			_, found := cli.Endpoint().GetSession(addr)
+			return found
+		}
+
+		// 在截止时间之前轮询，直到条件满足
+		waitFor := func(cond func() bool) bool {
+			deadline := time.Now().Add(5 * time.Second)
+			for !cond() {
+				if time.Now().After(deadline) {
+					return false
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			return true
+		}
+
+		// 预热后所有节点都已经建立连接
+		t.AssertNil(cli.Warmup())
+		t.Assert(hasSession(addrs[0]), true)
+		t.Assert(hasSession(addrs[1]), true)
+
+		// 新增节点提前建立连接，监听启动之前注册的节点收不到事件，所以每次轮询都注册一个新节点
+		probe := 0
+		t.Assert(waitFor(func() bool {
+			probe++
+			_ = reg.Register(&registry.Service{Name: "watch", Version: "1.0.0", Nodes: []*registry.Node{
+				{Id: fmt.Sprintf("watch-3-%d", probe), Address: addrs[2]},
+			}})
+			return hasSession(addrs[2])
+		}), true)
+
+		// 节点删除后session被关闭
+		_ = reg.Deregister(&registry.Service{Name: "watch", Version: "1.0.0", Nodes: []*registry.Node{
+			{Id: "watch-2", Address: addrs[1]},
+		}})
+		t.Assert(waitFor(func() bool {
+			return !hasSession(addrs[1])
+		}), true)
+		t.Assert(hasSession(addrs[0]), true)
+
+		// 不可达的节点预热失败
+		cli2 := client.NewRpcClient("watch2", client.OptCustomService(&registry.Service{Name: "watch2", Nodes: []*registry.Node{
+			{Id: "watch2-1", Address: "127.0.0.1:9193"},
+		}}))
+		defer cli2.Close()
+		t.Assert(cli2.Warmup().Code(), drpc.CodeDialFailed)
+	})
+}
+
+func TestSessionPool(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		svr := server.NewRpcServer("pool", server.OptListenAddress("127.0.0.1:9206"))
+		svr.RouteCall(new(Slow))
+		go func() {
+			_ = svr.ListenAndServe()
+		}()
+		defer svr.Close()
+		time.Sleep(500 * time.Millisecond)
+
+		cli := client.NewRpcClient("pool",
+			client.OptCustomService(&registry.Service{Name: "pool", Nodes: []*registry.Node{
+				{Id: "pool-1", Address: "127.0.0.1:9206"},
+			}}),
+			client.OptSessionPool(1, 3, 200*time.Millisecond),
+		)
+		defer cli.Close()
+		t.AssertNil(cli.Warmup())
+		t.Assert(cli.Endpoint().CountSession(), 1)
+
+		// 并发请求时新建session，但不超过上限
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var result string
+				stat := cli.Call("/slow/sleep", 200, &result).Status()
+				t.Assert(stat.OK(), true)
+			}()
+		}
+		time.Sleep(100 * time.Millisecond)
+		t.Assert(cli.Endpoint().CountSession(), 3)
+		wg.Wait()
+
+		// 空闲的session被关闭，保留最小数量
+		time.Sleep(500 * time.Millisecond)
+		t.Assert(cli.Endpoint().CountSession(), 1)
+	})
+}
@@ -0,0 +1,233 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/registry"
+	"github.com/osgochina/dmicro/selector"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// 计算延迟百分位时保留的最近请求耗时数量
+	hedgeLatencyWindow = 100
+	// 至少有多少个样本才使用百分位延迟
+	hedgeLatencyMinSamples = 10
+)
+
+// HedgePolicy 对冲请求策略，第一个请求在延迟时间内没有返回时，向另一个节点发送相同的请求，
+// 使用最先成功的结果并取消其他请求。只适合幂等的读请求
+type HedgePolicy struct {
+	// 使用最近请求耗时的百分位作为对冲延迟，例如0.95，为0则使用固定延迟
+	Percentile float64
+	// 固定的对冲延迟，样本不足时也使用该延迟，设置了百分位时必须大于0
+	Delay time.Duration
+	// 最多同时发出的请求数，包含第一个请求，默认为2
+	MaxRequests int
+}
+
+// 检查对冲策略，按百分位计算延迟时必须设置样本不足时使用的固定延迟，否则会立即发出对冲请求
+func (that *HedgePolicy) check() error {
+	if that.Percentile < 0 || that.Percentile > 1 {
+		return fmt.Errorf("dmicro.client hedge policy: percentile %v out of range [0, 1]", that.Percentile)
+	}
+	if that.Percentile > 0 && that.Delay <= 0 {
+		return errors.New("dmicro.client hedge policy: delay is required as the fallback before enough samples for the percentile")
+	}
+	return nil
+}
+
+// 对冲请求的结果
+type hedgeResult struct {
+	callCmd drpc.CallCmd
+	result  interface{}
+}
+
+// 判断方法是否开启了对冲请求
+func (that *RpcClient) hedgePolicy(serviceMethod string) *HedgePolicy {
+	if that.opts.HedgePolicies == nil {
+		return nil
+	}
+	return that.opts.HedgePolicies[serviceMethod]
+}
+
+// 获取对冲延迟
+func (that *RpcClient) hedgeDelay(policy *HedgePolicy, serviceMethod string) time.Duration {
+	if policy.Percentile > 0 {
+		if v, ok := that.hedgeLatency.Load(serviceMethod); ok {
+			if d, ok := v.(*latencyWindow).percentile(policy.Percentile); ok {
+				return d
+			}
+		}
+	}
+	return policy.Delay
+}
+
+// 记录请求耗时
+func (that *RpcClient) observeHedgeLatency(serviceMethod string, d time.Duration) {
+	v, _ := that.hedgeLatency.LoadOrStore(serviceMethod, &latencyWindow{})
+	v.(*latencyWindow).add(d)
+}
+
+// 发送对冲请求
+func (that *RpcClient) hedgeCall(policy *HedgePolicy, cc *callConfig, serviceMethod string, args interface{}, result interface{}) drpc.CallCmd {
+	if err := policy.check(); err != nil {
+		return drpc.NewFakeCallCmd(serviceMethod, args, result, drpc.NewStatus(drpc.CodeInvalidOp, err.Error(), ""))
+	}
+	maxRequests := policy.MaxRequests
+	if maxRequests <= 0 {
+		maxRequests = 2
+	}
+	// 所有请求共享同一个可取消的上下文，得到结果后取消其他请求
+	ctx, cancel := context.WithCancel(cc.ctx)
+	defer cancel()
+	cc.withContext(ctx)
+
+	var (
+		resultCh = make(chan hedgeResult, maxRequests)
+		nodes    []*registry.Node
+		pending  int
+		last     drpc.CallCmd
+		delay    = that.hedgeDelay(policy, serviceMethod)
+	)
+	// 向一个新节点发送请求，返回是否发送成功
+	send := func() bool {
+		// 排除已经请求过的节点
+		sess, node, report, stat := that.selectSession(serviceMethod, cc, selector.OptWithFilter(excludeNodes(nodes)))
+		if stat != nil {
+			if node != nil && drpc.IsConnError(stat) {
+				that.mark(node, stat, 0)
+			}
+			last = drpc.NewFakeCallCmd(serviceMethod, args, result, stat)
+			return false
+		}
+		nodes = append(nodes, node)
+		pending++
+		r := newResult(result)
+		go func() {
+			start := time.Now()
+			callCmd := that.callNode(sess, node, report, serviceMethod, args, r, cc.setting)
+			if callCmd.Status().OK() {
+				that.observeHedgeLatency(serviceMethod, time.Since(start))
+			}
+			resultCh <- hedgeResult{callCmd: callCmd, result: r}
+		}()
+		return true
+	}
+	if !send() {
+		return last
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for pending > 0 {
+		select {
+		case r := <-resultCh:
+			pending--
+			last = r.callCmd
+			if r.callCmd.Status().OK() {
+				copyResult(result, r.result)
+				return r.callCmd
+			}
+		case <-timer.C:
+			if len(nodes) < maxRequests && send() {
+				timer.Reset(delay)
+			}
+		}
+	}
+	return last
+}
+
+// 过滤掉已经请求过的节点，没有其他节点时返回空列表
+func excludeNodes(nodes []*registry.Node) selector.Filter {
+	return func(services []*registry.Service) []*registry.Service {
+		filtered := make([]*registry.Service, 0, len(services))
+		for _, service := range services {
+			s := *service
+			s.Nodes = make([]*registry.Node, 0, len(service.Nodes))
+			for _, node := range service.Nodes {
+				if !containsNode(nodes, node) {
+					s.Nodes = append(s.Nodes, node)
+				}
+			}
+			filtered = append(filtered, &s)
+		}
+		return filtered
+	}
+}
+
+func containsNode(nodes []*registry.Node, node *registry.Node) bool {
+	for _, n := range nodes {
+		if n.Id == node.Id && n.Address == node.Address {
+			return true
+		}
+	}
+	return false
+}
+
+// 为每个请求创建独立的结果对象，避免并发写入调用方的结果
+func newResult(result interface{}) interface{} {
+	if result == nil {
+		return nil
+	}
+	t := reflect.TypeOf(result)
+	if t.Kind() != reflect.Ptr {
+		return result
+	}
+	return reflect.New(t.Elem()).Interface()
+}
+
+// 把胜出的请求结果复制给调用方
+func copyResult(dst interface{}, src interface{}) {
+	if dst == nil || src == nil || dst == src {
+		return
+	}
+	dv := reflect.ValueOf(dst)
+	sv := reflect.ValueOf(src)
+	if dv.Kind() != reflect.Ptr || sv.Type() != dv.Type() {
+		return
+	}
+	dv.Elem().Set(sv.Elem())
+}
+
+// 最近请求的耗时
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func (that *latencyWindow) add(d time.Duration) {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	if len(that.samples) < hedgeLatencyWindow {
+		that.samples = append(that.samples, d)
+		return
+	}
+	that.samples[that.next] = d
+	that.next = (that.next + 1) % hedgeLatencyWindow
+}
+
+// 计算耗时的百分位，样本不足返回false
+func (that *latencyWindow) percentile(p float64) (time.Duration, bool) {
+	that.mu.Lock()
+	if len(that.samples) < hedgeLatencyMinSamples {
+		that.mu.Unlock()
+		return 0, false
+	}
+	sorted := make([]time.Duration, len(that.samples))
+	copy(sorted, that.samples)
+	that.mu.Unlock()
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	i := int(p * float64(len(sorted)))
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i], true
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"crypto/tls"
+	"github.com/osgochina/dmicro/client/breaker"
 	"github.com/osgochina/dmicro/drpc"
 	"github.com/osgochina/dmicro/drpc/proto"
 	"github.com/osgochina/dmicro/logger"
@@ -34,7 +35,18 @@ type Options struct {
 	GlobalPlugin      []drpc.Plugin
 	Registry          registry.Registry
 	Selector          selector.Selector
-	Metrics           metrics.Metrics // 统计信息
+	Metrics           metrics.Metrics         // 统计信息
+	HashKeyMeta       string                  // 一致性哈希的key所在的元数据名称
+	CircuitBreaker    *breaker.Group          // 熔断器
+	RetryPolicy       *RetryPolicy            // 默认的重试策略，为空则只在链接错误时重试 RetryTimes 次
+	RetryPolicies     map[string]*RetryPolicy // 按方法设置的重试策略
+	RetryBudget       *RetryBudget            // 重试预算
+	HedgePolicies     map[string]*HedgePolicy // 按方法设置的对冲请求策略
+	WatchRegistry     bool                    // 监听注册中心，节点被删除时关闭对应的session
+	PreDial           bool                    // 监听到新节点时提前建立连接
+	PoolMinSessions   int                     // 每个节点最少保持的session数量
+	PoolMaxSessions   int                     // 每个节点最多创建的session数量，大于1时开启session池
+	PoolIdleTimeout   time.Duration           // session池中空闲session的超时时间，为0不关闭空闲session
 }
 
 type Option func(*Options)
@@ -51,6 +63,8 @@ func NewOptions(options ...Option) Options {
 		DialTimeout:       defaultDialTimeout,
 		SlowCometDuration: defaultSlowCometDuration,
 		RetryTimes:        defaultRetryTimes,
+		WatchRegistry:     true,
+		HashKeyMeta:       MetaHashKey,
 		PrintDetail:       false,
 		HeartbeatTime:     time.Duration(0),
 		ProtoFunc:         drpc.DefaultProtoFunc(),
@@ -154,6 +168,41 @@ func OptRetryTimes(n int) Option {
 	}
 }
 
+// OptRetryPolicy 设置重试策略，不传入方法名则设置默认的重试策略
+func OptRetryPolicy(policy RetryPolicy, serviceMethods ...string) Option {
+	return func(o *Options) {
+		if len(serviceMethods) == 0 {
+			o.RetryPolicy = &policy
+			return
+		}
+		if o.RetryPolicies == nil {
+			o.RetryPolicies = make(map[string]*RetryPolicy)
+		}
+		for _, serviceMethod := range serviceMethods {
+			o.RetryPolicies[serviceMethod] = &policy
+		}
+	}
+}
+
+// OptRetryBudget 设置重试预算，每个请求增加ratio次重试的额度，另外每秒最少允许minPerSecond次重试
+func OptRetryBudget(ratio float64, minPerSecond int) Option {
+	return func(o *Options) {
+		o.RetryBudget = NewRetryBudget(ratio, minPerSecond)
+	}
+}
+
+// OptHedgePolicy 为方法开启对冲请求，作为这些方法的默认策略，单次请求可以通过 WithHedge 覆盖
+func OptHedgePolicy(policy HedgePolicy, serviceMethods ...string) Option {
+	return func(o *Options) {
+		if o.HedgePolicies == nil {
+			o.HedgePolicies = make(map[string]*HedgePolicy)
+		}
+		for _, serviceMethod := range serviceMethods {
+			o.HedgePolicies[serviceMethod] = &policy
+		}
+	}
+}
+
 // OptSessionAge 设置会话生命周期
 func OptSessionAge(n time.Duration) Option {
 	return func(o *Options) {
@@ -240,3 +289,41 @@ func OptMetrics(m metrics.Metrics) Option {
 		o.Metrics = m
 	}
 }
+
+// OptHashKeyMeta 设置一致性哈希的key所在的元数据名称，例如使用 "user_id" 元数据保证同一用户的请求落在同一个节点
+func OptHashKeyMeta(key string) Option {
+	return func(o *Options) {
+		o.HashKeyMeta = key
+	}
+}
+
+// OptCircuitBreaker 开启熔断器，熔断器打开时请求直接返回 drpc.CodeCircuitOpen 错误
+func OptCircuitBreaker(opts ...breaker.Option) Option {
+	return func(o *Options) {
+		o.CircuitBreaker = breaker.NewGroup(opts...)
+	}
+}
+
+// OptWatchRegistry 设置是否监听注册中心，开启后节点从注册中心删除时主动关闭对应的session，默认开启
+func OptWatchRegistry(watch bool) Option {
+	return func(o *Options) {
+		o.WatchRegistry = watch
+	}
+}
+
+// OptPreDial 设置监听到新节点时是否提前建立连接，需要开启 OptWatchRegistry
+func OptPreDial(preDial bool) Option {
+	return func(o *Options) {
+		o.PreDial = preDial
+	}
+}
+
+// OptSessionPool 为每个节点创建session池，请求时选择正在处理的请求最少的session，
+// 所有session都繁忙时在后台新建session，请求仍然使用现有的session，直到达到max。空闲超过idleTimeout的session会被关闭，但至少保留min个
+func OptSessionPool(min int, max int, idleTimeout time.Duration) Option {
+	return func(o *Options) {
+		o.PoolMinSessions = min
+		o.PoolMaxSessions = max
+		o.PoolIdleTimeout = idleTimeout
+	}
+}
@@ -0,0 +1,260 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/proto"
+	"github.com/osgochina/dmicro/logger"
+	"sync"
+	"time"
+)
+
+// 节点的session池，请求时选择正在处理的请求最少的session，
+// 所有session都在处理请求且未达到上限时在后台创建新的session，空闲超时的session会被关闭
+type sessionPool struct {
+	addr        string
+	endpoint    drpc.Endpoint
+	protoFunc   proto.ProtoFunc
+	min         int
+	max         int
+	idleTimeout time.Duration
+
+	mu       sync.Mutex
+	sessions []*pooledSession
+	dialing  int
+	seq      int
+	closed   bool
+}
+
+// 池中的session
+type pooledSession struct {
+	sess     drpc.Session
+	inflight int
+	lastUsed time.Time
+}
+
+func newSessionPool(addr string, endpoint drpc.Endpoint, opts *Options) *sessionPool {
+	p := &sessionPool{
+		addr:        addr,
+		endpoint:    endpoint,
+		protoFunc:   opts.ProtoFunc,
+		min:         opts.PoolMinSessions,
+		max:         opts.PoolMaxSessions,
+		idleTimeout: opts.PoolIdleTimeout,
+	}
+	if p.min > p.max {
+		p.min = p.max
+	}
+	return p
+}
+
+// 获取session，请求结束后必须调用返回的release方法
+func (that *sessionPool) get() (drpc.Session, func(), *drpc.Status) {
+	that.mu.Lock()
+	if that.closed {
+		that.mu.Unlock()
+		return nil, nil, drpc.NewStatus(drpc.CodeDialFailed, fmt.Sprintf("dmicro.client session pool %s closed", that.addr))
+	}
+	that.removeUnhealthy()
+	best := that.leastBusy()
+	if best == nil {
+		// 没有可用的session，只能等待拨号完成
+		that.dialing++
+		that.mu.Unlock()
+		sess, stat := that.dial()
+		that.mu.Lock()
+		that.dialing--
+		if stat != nil {
+			that.mu.Unlock()
+			return nil, nil, stat
+		}
+		best = that.add(sess)
+	} else if size := len(that.sessions) + that.dialing; size < that.min || (best.inflight > 0 && size < that.max) {
+		// 先使用现有的session，后台扩容，请求不等待拨号
+		that.dialing++
+		go that.grow()
+	}
+	ps := best
+	ps.inflight++
+	that.mu.Unlock()
+	var once sync.Once
+	return ps.sess, func() {
+		once.Do(func() {
+			that.mu.Lock()
+			ps.inflight--
+			ps.lastUsed = time.Now()
+			that.mu.Unlock()
+		})
+	}, nil
+}
+
+// 后台新建一个session加入池中，调用方需要先增加拨号中的数量
+func (that *sessionPool) grow() {
+	sess, stat := that.dial()
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	that.dialing--
+	if stat != nil {
+		logger.Debugf(context.TODO(), "dmicro.client session pool %s grow error: %v", that.addr, stat)
+		return
+	}
+	that.add(sess)
+}
+
+// 创建session直到达到最小数量，至少保留一个session
+func (that *sessionPool) fill() *drpc.Status {
+	min := that.min
+	if min < 1 {
+		min = 1
+	}
+	for {
+		that.mu.Lock()
+		that.removeUnhealthy()
+		if that.closed || len(that.sessions)+that.dialing >= min {
+			that.mu.Unlock()
+			return nil
+		}
+		that.dialing++
+		that.mu.Unlock()
+		sess, stat := that.dial()
+		that.mu.Lock()
+		that.dialing--
+		if stat == nil {
+			that.add(sess)
+		}
+		that.mu.Unlock()
+		if stat != nil {
+			return stat
+		}
+	}
+}
+
+// 关闭空闲超时的session，保留最小数量的session
+func (that *sessionPool) evict() {
+	if that.idleTimeout <= 0 {
+		return
+	}
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	that.removeUnhealthy()
+	now := time.Now()
+	kept := that.sessions[:0]
+	for i, ps := range that.sessions {
+		// 剩余的session数量不能少于最小数量
+		remain := len(kept) + len(that.sessions) - i
+		if remain > that.min && ps.inflight == 0 && now.Sub(ps.lastUsed) > that.idleTimeout {
+			_ = ps.sess.Close()
+			continue
+		}
+		kept = append(kept, ps)
+	}
+	that.sessions = kept
+}
+
+// 关闭所有session
+func (that *sessionPool) close() {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	that.closed = true
+	for _, ps := range that.sessions {
+		_ = ps.sess.Close()
+	}
+	that.sessions = nil
+}
+
+// 当前session数量
+func (that *sessionPool) size() int {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	return len(that.sessions)
+}
+
+func (that *sessionPool) dial() (drpc.Session, *drpc.Status) {
+	sess, stat := that.endpoint.Dial(that.addr, that.protoFunc)
+	if !stat.OK() {
+		return nil, drpc.NewStatus(drpc.CodeDialFailed, "", stat)
+	}
+	return sess, nil
+}
+
+// 把新建的session加入池中，池已经关闭时直接关闭session。调用方需持有锁
+func (that *sessionPool) add(sess drpc.Session) *pooledSession {
+	that.seq++
+	sess.SetID(fmt.Sprintf("%s#%d", that.addr, that.seq))
+	ps := &pooledSession{sess: sess, lastUsed: time.Now()}
+	if that.closed {
+		_ = sess.Close()
+		return ps
+	}
+	that.sessions = append(that.sessions, ps)
+	return ps
+}
+
+// 移除已经断开的session。调用方需持有锁
+func (that *sessionPool) removeUnhealthy() {
+	kept := that.sessions[:0]
+	for _, ps := range that.sessions {
+		if ps.sess.Health() {
+			kept = append(kept, ps)
+		} else {
+			_ = ps.sess.Close()
+		}
+	}
+	that.sessions = kept
+}
+
+// 正在处理的请求最少的session。调用方需持有锁
+func (that *sessionPool) leastBusy() *pooledSession {
+	var best *pooledSession
+	for _, ps := range that.sessions {
+		if best == nil || ps.inflight < best.inflight {
+			best = ps
+		}
+	}
+	return best
+}
+
+// 获取节点的session池，不存在则创建
+func (that *RpcClient) sessionPool(addr string) *sessionPool {
+	if v, ok := that.pools.Load(addr); ok {
+		return v.(*sessionPool)
+	}
+	v, loaded := that.pools.LoadOrStore(addr, newSessionPool(addr, that.endpoint, &that.opts))
+	if !loaded && that.opts.PoolIdleTimeout > 0 {
+		that.evictOnce.Do(func() {
+			go that.runEvict()
+		})
+	}
+	return v.(*sessionPool)
+}
+
+// 关闭节点的session池
+func (that *RpcClient) closeSessionPool(addr string) bool {
+	v, ok := that.pools.LoadAndDelete(addr)
+	if ok {
+		v.(*sessionPool).close()
+	}
+	return ok
+}
+
+// 定时关闭空闲超时的session，直到客户端关闭
+func (that *RpcClient) runEvict() {
+	interval := that.opts.PoolIdleTimeout / 2
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			that.pools.Range(func(_, v interface{}) bool {
+				v.(*sessionPool).evict()
+				return true
+			})
+		case <-that.closeCh:
+			return
+		}
+	}
+}
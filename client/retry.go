@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/registry"
+	"github.com/osgochina/dmicro/utils/backoff"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	// 最大尝试次数，包含第一次请求
+	MaxAttempts int
+	// 第attempt次重试之前的等待时间，为空则立即重试
+	Backoff func(attempt int) time.Duration
+	// 可以重试的状态码，链接错误总是可以重试
+	RetryableCodes []int32
+	// 强制认为方法是幂等的，否则以服务端注册路由时的幂等标记为准。
+	// 非幂等的方法只在链接错误时重试
+	Idempotent bool
+}
+
+// ExponentialBackoff 带随机抖动的指数退避
+func ExponentialBackoff(base time.Duration, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		return backoff.Jitter(backoff.Exponential(attempt, base, max), 0.5)
+	}
+}
+
+// 状态码是否可以重试
+func (that *RetryPolicy) retryable(code int32) bool {
+	for _, c := range that.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取方法的重试策略，未单独设置的方法使用默认策略
+func (that *RpcClient) retryPolicy(serviceMethod string) *RetryPolicy {
+	if p, ok := that.opts.RetryPolicies[serviceMethod]; ok {
+		return p
+	}
+	if that.opts.RetryPolicy != nil {
+		return that.opts.RetryPolicy
+	}
+	return &RetryPolicy{MaxAttempts: that.opts.RetryTimes}
+}
+
+// 判断第attempt次请求失败后是否需要重试
+func (that *RpcClient) shouldRetry(policy *RetryPolicy, attempt int, serviceMethod string, node *registry.Node, stat *drpc.Status) bool {
+	if stat.OK() || attempt >= policy.MaxAttempts {
+		return false
+	}
+	if !drpc.IsConnError(stat) {
+		if !policy.retryable(stat.Code()) {
+			return false
+		}
+		// 非幂等的方法可能已经被服务端执行过，不能重试
+		if !policy.Idempotent && !isIdempotent(node, serviceMethod) {
+			return false
+		}
+	}
+	if that.opts.RetryBudget != nil && !that.opts.RetryBudget.withdraw() {
+		return false
+	}
+	return true
+}
+
+// 重试之前等待，客户端关闭或者请求的上下文结束则返回false
+func (that *RpcClient) waitRetry(cc *callConfig, attempt int) bool {
+	if cc.ctx.Err() != nil {
+		return false
+	}
+	if cc.policy.Backoff == nil {
+		return true
+	}
+	d := cc.policy.Backoff(attempt)
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-that.closeCh:
+		return false
+	case <-cc.ctx.Done():
+		return false
+	}
+}
+
+// 请求的上下文已经结束时返回对应的状态
+func ctxStatus(ctx context.Context) *drpc.Status {
+	if err := ctx.Err(); err != nil {
+		return drpc.NewStatusByCodeText(drpc.CodeCallCanceled, err, false)
+	}
+	return nil
+}
+
+// 节点注册时是否把该方法标记为幂等
+func isIdempotent(node *registry.Node, serviceMethod string) bool {
+	if node == nil || node.Metadata == nil {
+		return false
+	}
+	for _, path := range strings.Split(node.Metadata[registry.MetadataIdempotent], ",") {
+		if strings.EqualFold(path, serviceMethod) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryBudget 重试预算，限制重试请求占正常请求的比例，避免服务故障时产生重试风暴
+type RetryBudget struct {
+	mu           sync.Mutex
+	ratio        float64
+	minPerSecond int
+	balance      float64
+	second       int64
+	minUsed      int
+}
+
+// 重试预算余额上限，相当于该数量请求存入的额度
+const retryBudgetDeposits = 1000
+
+// NewRetryBudget 创建重试预算，每个请求存入ratio次重试的额度，另外每秒最少允许minPerSecond次重试
+func NewRetryBudget(ratio float64, minPerSecond int) *RetryBudget {
+	return &RetryBudget{
+		ratio:        ratio,
+		minPerSecond: minPerSecond,
+	}
+}
+
+// 每个请求存入重试额度
+func (that *RetryBudget) deposit() {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	that.balance += that.ratio
+	if max := that.ratio * retryBudgetDeposits; that.balance > max {
+		that.balance = max
+	}
+}
+
+// 每次重试消耗一次额度，额度不足返回false
+func (that *RetryBudget) withdraw() bool {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	if now := time.Now().Unix(); now != that.second {
+		that.second = now
+		that.minUsed = 0
+	}
+	if that.minUsed < that.minPerSecond {
+		that.minUsed++
+		return true
+	}
+	if that.balance >= 1 {
+		that.balance--
+		return true
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/osgochina/dmicro/drpc"
 	"github.com/osgochina/dmicro/drpc/message"
@@ -30,14 +31,35 @@ var (
 
 	// RerClientClosed 客户端已关闭错误信息
 	rerClientClosed = drpc.NewStatus(100, "client is closed", "")
+	rerNodeEjected  = drpc.NewStatus(drpc.CodeCircuitOpen, "node is ejected by selector", "")
 )
 
+// MetaHashKey 默认的一致性哈希key的元数据名称，设置后使用selector的一致性哈希策略选择节点
+const MetaHashKey = "X-Hash-Key"
+
+// WithHashKey 设置本次请求的一致性哈希key，相同key的请求会落在同一个节点上
+func WithHashKey(key string) message.MsgSetting {
+	return drpc.WithSetMeta(MetaHashKey, key)
+}
+
+// Caller 发送请求的接口，*RpcClient 和 drpc.Session 都实现了该接口
+type Caller = drpc.Caller
+
 // RpcClient rpc客户端结构体
 type RpcClient struct {
 	endpoint drpc.Endpoint
 	opts     Options
 	closeCh  chan bool
 	closeMu  sync.Mutex
+	// 对冲请求的方法最近的请求耗时 map[serviceMethod]*latencyWindow
+	hedgeLatency sync.Map
+	// 保证只监听一次注册中心
+	watchOnce sync.Once
+	// 节点的session池 map[address]*sessionPool
+	pools sync.Map
+	// 同一个节点同时只拨号一次 map[address]*sync.Mutex
+	dialLocks sync.Map
+	evictOnce sync.Once
 }
 
 // NewRpcClient 创建rpc客户端
@@ -76,6 +98,7 @@ func NewRpcClient(serviceName string, opt ...Option) *RpcClient {
 		endpoint: endpoint,
 		closeCh:  make(chan bool),
 	}
+	rc.watch()
 	return rc
 }
 
@@ -91,28 +114,41 @@ func (that *RpcClient) Call(serviceMethod string, args interface{}, result inter
 		return drpc.NewFakeCallCmd(serviceMethod, args, result, rerClientClosed)
 	default:
 	}
-	var (
-		callCmd  drpc.CallCmd
-		connFail bool
-	)
-	for i := 0; i < that.opts.RetryTimes; i++ {
-		sess, stat := that.selectSession(serviceMethod)
+	cc := that.newCallConfig(serviceMethod, setting)
+	defer cc.cancel()
+	// 开启了对冲请求的方法，最多只发出一个请求时等同于关闭对冲
+	if cc.hedge != nil && cc.hedge.MaxRequests != 1 {
+		return that.hedgeCall(cc.hedge, cc, serviceMethod, args, result)
+	}
+	var callCmd drpc.CallCmd
+	if that.opts.RetryBudget != nil {
+		that.opts.RetryBudget.deposit()
+	}
+	for attempt := 1; ; attempt++ {
+		sess, node, report, stat := that.selectSession(serviceMethod, cc)
 		if stat != nil {
-			return drpc.NewFakeCallCmd(serviceMethod, args, result, stat)
+			callCmd = drpc.NewFakeCallCmd(serviceMethod, args, result, stat)
+			// 拨号失败，报告给选择器后重新选择节点
+			if node == nil || !drpc.IsConnError(stat) {
+				return callCmd
+			}
+			that.mark(node, stat, 0)
+		} else {
+			callCmd = that.callNode(sess, node, report, serviceMethod, args, result, cc.setting)
+			stat = callCmd.Status()
 		}
-		var callCmdChan = make(chan drpc.CallCmd, 1)
-		sess.AsyncCall(serviceMethod, args, result, callCmdChan, setting...)
-		callCmd = <-callCmdChan
-		// 判断错误类型是否是链接出错，如果不是链接出错，则直接返回错误信息，如果是链接出错，则删除该链接，重新执行
-		connFail = drpc.IsConnError(callCmd.Status())
-		if !connFail {
+		// 根据重试策略判断是否需要重试
+		if !that.shouldRetry(cc.policy, attempt, serviceMethod, node, stat) {
 			return callCmd
 		}
-		if i > 0 {
-			logger.Debugf(context.TODO(), "链接第[%d]出错，错误原因: %s", i, callCmd.Status().String())
+		logger.Debugf(context.TODO(), "请求第[%d]次出错，错误原因: %s", attempt, stat.String())
+		if !that.waitRetry(cc, attempt) {
+			if stat = ctxStatus(cc.ctx); stat != nil {
+				return drpc.NewFakeCallCmd(serviceMethod, args, result, stat)
+			}
+			return callCmd
 		}
 	}
-	return callCmd
 }
 
 // Push 发送push消息
@@ -123,25 +159,44 @@ func (that *RpcClient) Push(serviceMethod string, arg interface{}, setting ...me
 	default:
 	}
 	var (
-		stat     *drpc.Status
-		connFail bool
-		sess     drpc.Session
+		stat   *drpc.Status
+		sess   drpc.Session
+		node   *registry.Node
+		report func(*drpc.Status)
+		cc     = that.newCallConfig(serviceMethod, setting)
 	)
-	for i := 0; i < that.opts.RetryTimes; i++ {
-		sess, stat = that.selectSession(serviceMethod)
+	defer cc.cancel()
+	if that.opts.RetryBudget != nil {
+		that.opts.RetryBudget.deposit()
+	}
+	for attempt := 1; ; attempt++ {
+		sess, node, report, stat = that.selectSession(serviceMethod, cc)
 		if stat != nil {
-			return stat
+			// 拨号失败，报告给选择器后重新选择节点
+			if node == nil || !drpc.IsConnError(stat) {
+				return stat
+			}
+			that.mark(node, stat, 0)
+		} else {
+			start := time.Now()
+			end := that.begin(node)
+			stat = sess.Push(serviceMethod, arg, cc.setting...)
+			end()
+			report(stat)
+			that.mark(node, stat, time.Since(start))
 		}
-		stat = sess.Push(serviceMethod, arg, setting...)
-		connFail = !drpc.IsConnError(stat)
-		if connFail {
+		// 根据重试策略判断是否需要重试
+		if !that.shouldRetry(cc.policy, attempt, serviceMethod, node, stat) {
 			return stat
 		}
-		if i > 0 {
-			logger.Debugf(context.TODO(), "链接第[%d]出错，错误原因: %s", i, stat.String())
+		logger.Debugf(context.TODO(), "请求第[%d]次出错，错误原因: %s", attempt, stat.String())
+		if !that.waitRetry(cc, attempt) {
+			if ctxStat := ctxStatus(cc.ctx); ctxStat != nil {
+				return ctxStat
+			}
+			return stat
 		}
 	}
-	return stat
 }
 
 // AsyncCall 异步请求
@@ -160,13 +215,25 @@ func (that *RpcClient) AsyncCall(serviceMethod string, arg interface{}, result i
 		return callCmd
 	default:
 	}
-	sess, stat := that.selectSession(serviceMethod)
+	cc := that.newCallConfig(serviceMethod, setting)
+	sess, node, report, stat := that.selectSession(serviceMethod, cc)
 	if stat != nil {
+		cc.cancel()
 		callCmd := drpc.NewFakeCallCmd(serviceMethod, arg, result, stat)
 		callCmdChan <- callCmd
 		return callCmd
 	}
-	callCmd := sess.AsyncCall(serviceMethod, arg, result, callCmdChan, setting...)
+	start := time.Now()
+	end := that.begin(node)
+	callCmd := sess.AsyncCall(serviceMethod, arg, result, callCmdChan, cc.setting...)
+	// 请求完成后减少节点正在处理的请求数，并把结果报告给熔断器和选择器
+	go func() {
+		<-callCmd.Done()
+		cc.cancel()
+		end()
+		report(callCmd.Status())
+		that.mark(node, callCmd.Status(), time.Since(start))
+	}()
 	return callCmd
 }
 
@@ -185,6 +252,20 @@ func (that *RpcClient) RoutePushFunc(pushHandleFunc interface{}, plugin ...drpc.
 	return that.endpoint.RoutePushFunc(pushHandleFunc, plugin...)
 }
 
+// Dial 选择一个节点建立独立的session，session不放入session池，不会被其他请求使用，由调用方负责关闭。
+// 需要在固定的session上接收服务端推送时使用
+func (that *RpcClient) Dial() (drpc.Session, *drpc.Status) {
+	node, stat := that.selectNode(&callConfig{})
+	if stat != nil {
+		return nil, stat
+	}
+	s, stat := that.endpoint.Dial(node.Address, that.opts.ProtoFunc)
+	if !stat.OK() {
+		return nil, drpc.NewStatus(drpc.CodeDialFailed, "", stat)
+	}
+	return s, nil
+}
+
 // Endpoint 返回Endpoint对象
 func (that *RpcClient) Endpoint() drpc.Endpoint {
 	return that.endpoint
@@ -207,10 +288,66 @@ func (that *RpcClient) Close() {
 	}
 }
 
-// 选择session
-func (that *RpcClient) selectSession(serviceMethod string) (drpc.Session, *drpc.Status) {
+// 选择session，同时返回session所属的节点，以及向熔断器上报请求结果的方法
+func (that *RpcClient) selectSession(serviceMethod string, cc *callConfig, opts ...selector.SelectOption) (drpc.Session, *registry.Node, func(*drpc.Status), *drpc.Status) {
+	node, stat := that.selectNode(cc, opts...)
+	if stat != nil {
+		return nil, nil, nil, stat
+	}
+	sess, report, stat := that.nodeSession(serviceMethod, node)
+	return sess, node, report, stat
+}
+
+// 获取节点的session，同时返回向熔断器上报请求结果的方法，请求结束后必须调用该方法
+func (that *RpcClient) nodeSession(serviceMethod string, node *registry.Node) (drpc.Session, func(*drpc.Status), *drpc.Status) {
+	// 熔断器打开时不再请求节点
+	report, stat := that.allow(serviceMethod, node)
+	if stat != nil {
+		return nil, nil, stat
+	}
+	if !that.poolEnabled() {
+		s, stat := that.dial(node)
+		if stat != nil {
+			report(stat)
+			return nil, nil, stat
+		}
+		return s, report, nil
+	}
+	s, release, stat := that.sessionPool(node.Address).get()
+	if stat != nil {
+		report(stat)
+		return nil, nil, stat
+	}
+	// 上报结果的同时把session归还给session池
+	return s, func(stat *drpc.Status) {
+		release()
+		report(stat)
+	}, nil
+}
+
+// 是否为每个节点使用session池
+func (that *RpcClient) poolEnabled() bool {
+	return that.opts.PoolMaxSessions > 1
+}
+
+// 请求节点并等待结果，结果会报告给熔断器和选择器
+func (that *RpcClient) callNode(sess drpc.Session, node *registry.Node, report func(*drpc.Status), serviceMethod string, args interface{}, result interface{}, setting []message.MsgSetting) drpc.CallCmd {
+	start := time.Now()
+	end := that.begin(node)
+	callCmd := sess.AsyncCall(serviceMethod, args, result, make(chan drpc.CallCmd, 1), setting...)
+	<-callCmd.Done()
+	end()
+	report(callCmd.Status())
+	that.mark(node, callCmd.Status(), time.Since(start))
+	return callCmd
+}
 
-	next, err := that.next(that.Options().ServiceName)
+// 选择节点，指定了节点地址则直接使用该地址
+func (that *RpcClient) selectNode(cc *callConfig, opts ...selector.SelectOption) (*registry.Node, *drpc.Status) {
+	if len(cc.Address) > 0 {
+		return &registry.Node{Id: cc.Address, Address: cc.Address}, nil
+	}
+	next, err := that.next(that.Options().ServiceName, append(cc.selectOpts[:len(cc.selectOpts):len(cc.selectOpts)], opts...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -221,28 +358,85 @@ func (that *RpcClient) selectSession(serviceMethod string) (drpc.Session, *drpc.
 		}
 		return nil, drpc.NewStatus(drpc.CodeInternalServerError, fmt.Sprintf("dmicro.client error selecting %s node: %s", that.Options().ServiceName, e.Error()))
 	}
-	addr := node.Address
-	s, found := that.endpoint.GetSession(addr)
-	if found && s.Health() {
-		if s.Health() {
-			return s, nil
+	return node, nil
+}
+
+// 熔断器判断请求是否允许通过，允许时返回的方法用于上报请求结果。未开启熔断器时总是允许
+func (that *RpcClient) allow(serviceMethod string, node *registry.Node) (func(*drpc.Status), *drpc.Status) {
+	group := that.opts.CircuitBreaker
+	if group == nil {
+		return func(*drpc.Status) {}, nil
+	}
+	return group.Get(group.Name(that.opts.ServiceName, node.Address, serviceMethod)).Allow()
+}
+
+// 过滤熔断器处于打开状态的节点，全部节点都被熔断时返回原始列表
+func (that *RpcClient) breakerFilter(services []*registry.Service) []*registry.Service {
+	group := that.opts.CircuitBreaker
+	filtered := make([]*registry.Service, 0, len(services))
+	var available int
+	for _, service := range services {
+		s := *service
+		s.Nodes = make([]*registry.Node, 0, len(service.Nodes))
+		for _, node := range service.Nodes {
+			if !group.IsOpen(group.Name(that.opts.ServiceName, node.Address, "")) {
+				s.Nodes = append(s.Nodes, node)
+			}
 		}
-		_ = s.Close()
+		available += len(s.Nodes)
+		filtered = append(filtered, &s)
 	}
-	s, stat := that.endpoint.Dial(addr, that.opts.ProtoFunc)
-	if !stat.OK() {
-		return s, drpc.NewStatus(drpc.CodeDialFailed, "", stat)
+	if available == 0 {
+		return services
 	}
-	s.SetID(addr)
-	return s, nil
+	return filtered
+}
+
+// 记录节点开始处理一个请求，返回的方法在请求结束时调用，选择器不支持负载统计时不记录
+func (that *RpcClient) begin(node *registry.Node) (end func()) {
+	s, ok := that.opts.Selector.(selector.OutlierSelector)
+	if node == nil || !ok {
+		return func() {}
+	}
+	return s.Begin(that.opts.ServiceName, node)
+}
+
+// 把调用结果报告给选择器，只有节点故障才算作失败，业务错误不影响节点的健康状态
+func (that *RpcClient) mark(node *registry.Node, stat *drpc.Status, latency time.Duration) {
+	if node == nil || that.opts.Selector == nil {
+		return
+	}
+	var err error
+	if isNodeFailure(stat) {
+		err = stat.Cause()
+	}
+	if s, ok := that.opts.Selector.(selector.OutlierSelector); ok {
+		s.MarkLatency(that.opts.ServiceName, node, err, latency)
+		return
+	}
+	that.opts.Selector.Mark(that.opts.ServiceName, node, err)
+}
+
+// 判断调用失败是否是节点故障导致的
+func isNodeFailure(stat *drpc.Status) bool {
+	if stat.OK() {
+		return false
+	}
+	switch stat.Code() {
+	case drpc.CodeDialFailed, drpc.CodeConnClosed, drpc.CodeWriteFailed, drpc.CodeHandleTimeout:
+		return true
+	case drpc.CodeCallCanceled:
+		return errors.Is(stat.Cause(), context.DeadlineExceeded)
+	}
+	return false
 }
 
 // 获取服务可用的节点列表
-func (that *RpcClient) next(serviceName string) (selector.Next, *drpc.Status) {
+func (that *RpcClient) next(serviceName string, opts ...selector.SelectOption) (selector.Next, *drpc.Status) {
 	if that.opts.Selector == nil {
 		that.defaultSelector(serviceName)
 	}
-	next, err := that.opts.Selector.Select(serviceName)
+	next, err := that.opts.Selector.Select(serviceName, opts...)
 	if err != nil {
 		if err == selector.ErrNotFound {
 			return nil, drpc.NewStatus(drpc.CodeInternalServerError, fmt.Sprintf("dmicro.client service %s: %s", serviceName, err.Error()))
@@ -266,4 +460,5 @@ func (that *RpcClient) defaultSelector(serviceName string) {
 	} else {
 		_ = that.opts.Selector.Init(selector.OptRegistry(that.opts.Registry))
 	}
+	that.watch()
 }
@@ -0,0 +1,212 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/logger"
+	"github.com/osgochina/dmicro/registry"
+	"github.com/osgochina/dmicro/utils/backoff"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 监听注册中心出错后，重新监听的最长等待时间
+const maxWatchBackoff = 30 * time.Second
+
+// Warmup 连接服务的所有节点，在第一次请求之前建立好session。
+// 有节点连接失败时返回 drpc.CodeDialFailed，连接成功的session仍然保留
+func (that *RpcClient) Warmup() *drpc.Status {
+	select {
+	case <-that.closeCh:
+		return rerClientClosed
+	default:
+	}
+	nodes, stat := that.allNodes(&callConfig{})
+	if stat != nil {
+		return stat
+	}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []string
+	)
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(node *registry.Node) {
+			defer wg.Done()
+			if stat := that.connect(node); stat != nil {
+				that.mark(node, stat, 0)
+				mu.Lock()
+				failed = append(failed, node.Address)
+				mu.Unlock()
+			}
+		}(node)
+	}
+	wg.Wait()
+	if len(failed) > 0 {
+		return drpc.NewStatus(drpc.CodeDialFailed, fmt.Sprintf("dmicro.client warmup %d/%d nodes failed: %s",
+			len(failed), len(nodes), strings.Join(failed, ",")))
+	}
+	return nil
+}
+
+// 建立到节点的连接，使用session池时创建最小数量的session
+func (that *RpcClient) connect(node *registry.Node) *drpc.Status {
+	if that.poolEnabled() {
+		return that.sessionPool(node.Address).fill()
+	}
+	_, stat := that.dial(node)
+	return stat
+}
+
+// 连接节点，已经存在健康的session则直接使用。
+// 预热和提前连接可能同时拨号同一个节点，重复的session设置相同的id时会互相关闭，所以按地址串行拨号
+func (that *RpcClient) dial(node *registry.Node) (drpc.Session, *drpc.Status) {
+	addr := node.Address
+	if s, found := that.endpoint.GetSession(addr); found && s.Health() {
+		return s, nil
+	}
+	mu, _ := that.dialLocks.LoadOrStore(addr, new(sync.Mutex))
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+	s, found := that.endpoint.GetSession(addr)
+	if found {
+		if s.Health() {
+			return s, nil
+		}
+		_ = s.Close()
+	}
+	s, stat := that.endpoint.Dial(addr, that.opts.ProtoFunc)
+	if !stat.OK() {
+		return nil, drpc.NewStatus(drpc.CodeDialFailed, "", stat)
+	}
+	s.SetID(addr)
+	return s, nil
+}
+
+// 获取客户端使用的注册中心
+func (that *RpcClient) registry() registry.Registry {
+	if that.opts.Registry != nil {
+		return that.opts.Registry
+	}
+	if that.opts.Selector != nil {
+		return that.opts.Selector.Options().Registry
+	}
+	return nil
+}
+
+// 开始监听注册中心的服务变化，只会启动一次
+func (that *RpcClient) watch() {
+	if !that.opts.WatchRegistry {
+		return
+	}
+	reg := that.registry()
+	if reg == nil {
+		return
+	}
+	that.watchOnce.Do(func() {
+		go that.runWatch(reg)
+	})
+}
+
+// 监听注册中心，出错后退避重试，直到客户端关闭
+func (that *RpcClient) runWatch(reg registry.Registry) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-that.closeCh
+		cancel()
+	}()
+	for attempt := 0; ; attempt++ {
+		w, err := reg.Watch(registry.OptWatchService(that.opts.ServiceName), registry.OptWatchContext(ctx))
+		if err == nil {
+			attempt = 0
+			err = that.watchNext(w)
+		}
+		select {
+		case <-that.closeCh:
+			return
+		default:
+		}
+		logger.Debugf(context.TODO(), "dmicro.client watch %s error: %v", that.opts.ServiceName, err)
+		timer := time.NewTimer(backoff.Exponential(attempt+1, 100*time.Millisecond, maxWatchBackoff))
+		select {
+		case <-timer.C:
+		case <-that.closeCh:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// 处理监听到的服务变化，直到监听器出错或者客户端关闭
+func (that *RpcClient) watchNext(w registry.Watcher) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-that.closeCh:
+		case <-stop:
+		}
+		w.Stop()
+	}()
+	for {
+		res, err := w.Next()
+		if err != nil {
+			return err
+		}
+		if res == nil || res.Service == nil {
+			continue
+		}
+		switch res.Action {
+		case registry.Delete:
+			that.closeSessions(res.Service.Nodes)
+		case registry.Create, registry.Update:
+			if that.opts.PreDial {
+				for _, node := range res.Service.Nodes {
+					go that.preDial(node)
+				}
+			}
+		}
+	}
+}
+
+// 提前连接新增的节点
+func (that *RpcClient) preDial(node *registry.Node) {
+	if stat := that.connect(node); stat != nil {
+		that.mark(node, stat, 0)
+	}
+}
+
+// 关闭已经从注册中心删除的节点的session，其他版本中仍然存在的节点不关闭
+func (that *RpcClient) closeSessions(nodes []*registry.Node) {
+	var registered []*registry.Node
+	if services, err := that.registry().GetService(that.opts.ServiceName); err == nil {
+		for _, service := range services {
+			registered = append(registered, service.Nodes...)
+		}
+	}
+	for _, node := range nodes {
+		if containsAddress(registered, node.Address) {
+			continue
+		}
+		if that.closeSessionPool(node.Address) {
+			logger.Debugf(context.TODO(), "dmicro.client node %s removed from registry, close session pool", node.Address)
+			continue
+		}
+		if s, found := that.endpoint.GetSession(node.Address); found {
+			logger.Debugf(context.TODO(), "dmicro.client node %s removed from registry, close session", node.Address)
+			_ = s.Close()
+		}
+	}
+}
+
+func containsAddress(nodes []*registry.Node, addr string) bool {
+	for _, n := range nodes {
+		if n.Address == addr {
+			return true
+		}
+	}
+	return false
+}
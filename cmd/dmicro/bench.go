@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/modood/table"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/codec"
+	"github.com/osgochina/dmicro/drpc/message"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// 压测的参数
+type benchFlags struct {
+	sendFlags
+	concurrency int
+	connections int
+	qps         int
+	duration    time.Duration
+	requests    int
+	payload     int
+	output      string
+}
+
+func (that *benchFlags) addFlags(cmd *cobra.Command) {
+	that.sendFlags.addFlags(cmd)
+	cmd.Flags().IntVar(&that.concurrency, "concurrency", 10, "Number of concurrent workers")
+	cmd.Flags().IntVar(&that.connections, "connections", 1, "Number of connections shared by the workers")
+	cmd.Flags().IntVar(&that.qps, "qps", 0, "Rate limit of all workers, 0 means unlimited")
+	cmd.Flags().DurationVarP(&that.duration, "duration", "d", 10*time.Second, "Duration of the benchmark")
+	cmd.Flags().IntVarP(&that.requests, "requests", "n", 0, "Stop after sending the number of requests, 0 means only limited by duration")
+	cmd.Flags().IntVar(&that.payload, "payload", 0, "Size of a generated string body when no body is given")
+	cmd.Flags().StringVarP(&that.output, "output", "o", outputTable, "Output format, table or json")
+}
+
+func (that *benchFlags) check() error {
+	if that.concurrency <= 0 {
+		return fmt.Errorf("concurrency must be greater than 0")
+	}
+	if that.connections <= 0 {
+		return fmt.Errorf("connections must be greater than 0")
+	}
+	if that.duration <= 0 && that.requests <= 0 {
+		return fmt.Errorf("either duration or requests must be greater than 0")
+	}
+	if that.output != outputTable && that.output != outputJSON {
+		return fmt.Errorf("unsupported output: %s", that.output)
+	}
+	return nil
+}
+
+// 压测使用的消息体，没有指定时生成指定长度的字符串
+func (that *benchFlags) benchBody(raw string) (interface{}, error) {
+	if len(raw) == 0 && that.payload > 0 {
+		s := strings.Repeat("x", that.payload)
+		if that.codec == codec.JsonName {
+			b, _ := json.Marshal(s)
+			return json.RawMessage(b), nil
+		}
+		return s, nil
+	}
+	return that.body(raw)
+}
+
+// BenchReport 压测报告
+type BenchReport struct {
+	Requests   int            `json:"requests"`
+	Succeeded  int            `json:"succeeded"`
+	Failed     int            `json:"failed"`
+	Elapsed    time.Duration  `json:"elapsed"`
+	Throughput float64        `json:"throughput"`
+	Latency    BenchLatency   `json:"latency"`
+	Codes      []*BenchStatus `json:"codes"`
+}
+
+// BenchLatency 成功请求的耗时分布
+type BenchLatency struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+// BenchStatus 每种状态码的请求数
+type BenchStatus struct {
+	Code  int32  `json:"code"`
+	Count int    `json:"count"`
+	Msg   string `json:"msg"`
+}
+
+// 单个worker的统计，结束后合并
+type benchStats struct {
+	latencies []time.Duration
+	codes     map[int32]*BenchStatus
+}
+
+func (that *benchStats) add(d time.Duration, stat *drpc.Status) {
+	code := stat.Code()
+	s, ok := that.codes[code]
+	if !ok {
+		s = &BenchStatus{Code: code, Msg: stat.Msg()}
+		that.codes[code] = s
+	}
+	s.Count++
+	if stat.OK() {
+		that.latencies = append(that.latencies, d)
+	}
+}
+
+// 合并所有worker的统计生成报告
+func newBenchReport(stats []*benchStats, elapsed time.Duration) *BenchReport {
+	report := &BenchReport{Elapsed: elapsed}
+	codes := make(map[int32]*BenchStatus)
+	var latencies []time.Duration
+	for _, s := range stats {
+		latencies = append(latencies, s.latencies...)
+		for code, c := range s.codes {
+			if total, ok := codes[code]; ok {
+				total.Count += c.Count
+			} else {
+				codes[code] = &BenchStatus{Code: code, Count: c.Count, Msg: c.Msg}
+			}
+		}
+	}
+	for _, c := range codes {
+		report.Requests += c.Count
+		if c.Code == drpc.CodeOK {
+			report.Succeeded += c.Count
+		} else {
+			report.Failed += c.Count
+		}
+		report.Codes = append(report.Codes, c)
+	}
+	sort.Slice(report.Codes, func(i, j int) bool {
+		return report.Codes[i].Code < report.Codes[j].Code
+	})
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool {
+			return latencies[i] < latencies[j]
+		})
+		var sum time.Duration
+		for _, d := range latencies {
+			sum += d
+		}
+		report.Latency = BenchLatency{
+			Min:  latencies[0],
+			Mean: sum / time.Duration(len(latencies)),
+			P50:  percentile(latencies, 0.5),
+			P90:  percentile(latencies, 0.9),
+			P99:  percentile(latencies, 0.99),
+			Max:  latencies[len(latencies)-1],
+		}
+	}
+	return report
+}
+
+// 已经排序的耗时的百分位
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+func (that *BenchReport) print(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "Requests:    %d total, %d succeeded, %d failed\n", that.Requests, that.Succeeded, that.Failed)
+	_, _ = fmt.Fprintf(w, "Elapsed:     %s\n", that.Elapsed.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(w, "Throughput:  %.2f req/s\n", that.Throughput)
+	l := that.Latency
+	_, _ = fmt.Fprintf(w, "Latency:     min=%s mean=%s p50=%s p90=%s p99=%s max=%s\n", l.Min, l.Mean, l.P50, l.P90, l.P99, l.Max)
+	if len(that.Codes) > 0 {
+		_, _ = fmt.Fprintln(w, "Status codes:")
+		_, _ = fmt.Fprintln(w, table.Table(that.Codes))
+	}
+}
+
+// 执行压测，ctx取消或者达到时间、请求数后停止发送新的请求，等待已经发出的请求返回
+func (that *benchFlags) run(ctx context.Context, sessions []drpc.Session, serviceMethod string, body interface{}, setting []message.MsgSetting) *BenchReport {
+	if that.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, that.duration)
+		defer cancel()
+	}
+	var (
+		// 已经领取的请求序号
+		seq      int64
+		interval time.Duration
+		wg       sync.WaitGroup
+		stats    = make([]*benchStats, that.concurrency)
+		start    = time.Now()
+	)
+	if that.qps > 0 {
+		interval = time.Second / time.Duration(that.qps)
+	}
+	// 每个请求都会追加上下文，避免并发写入同一个底层数组
+	setting = setting[:len(setting):len(setting)]
+	// 领取下一个请求，限速时等待到该请求的发送时间，返回false表示停止
+	next := func() bool {
+		n := atomic.AddInt64(&seq, 1)
+		if that.requests > 0 && n > int64(that.requests) {
+			return false
+		}
+		if interval > 0 {
+			if wait := time.Until(start.Add(time.Duration(n-1) * interval)); wait > 0 {
+				timer := time.NewTimer(wait)
+				defer timer.Stop()
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					return false
+				}
+			}
+		}
+		return ctx.Err() == nil
+	}
+	for i := 0; i < that.concurrency; i++ {
+		s := &benchStats{codes: make(map[int32]*BenchStatus)}
+		stats[i] = s
+		sess := sessions[i%len(sessions)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for next() {
+				callCtx, cancel := context.WithTimeout(context.Background(), that.timeout)
+				begin := time.Now()
+				stat := sess.Call(serviceMethod, body, that.result(), append(setting, message.WithContext(callCtx))...).Status()
+				s.add(time.Since(begin), stat)
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	return newBenchReport(stats, time.Since(start))
+}
+
+func newBenchCmd() *cobra.Command {
+	flags := &benchFlags{}
+	cmd := &cobra.Command{
+		Use:   "bench <service_method> [json_body]",
+		Short: "Benchmark a CALL method.",
+		Long:  "Benchmark a CALL method and report throughput, latency percentiles and status codes. The body is read from stdin when it is \"-\".",
+		Example: `
+  dmicro bench /math/add '{"a":1,"b":2}' --address=127.0.0.1:9090 --concurrency=50 -d 30s
+  dmicro bench /echo/echo --address=127.0.0.1:9090 --proto=json --payload=1024 --qps=1000 -n 10000
+`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := flags.check(); err != nil {
+				return err
+			}
+			var raw string
+			if len(args) > 1 {
+				var err error
+				if raw, err = readBody(cmd, args); err != nil {
+					return err
+				}
+			}
+			body, err := flags.benchBody(raw)
+			if err != nil {
+				return err
+			}
+			setting, err := flags.setting()
+			if err != nil {
+				return err
+			}
+			endpoint, sessions, err := flags.dialN(flags.connections)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = endpoint.Close() }()
+			// Ctrl+C提前结束压测并输出报告
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			report := flags.run(ctx, sessions, args[0], body, setting)
+			if flags.output == outputJSON {
+				return printJSON(cmd.OutOrStdout(), report)
+			}
+			report.print(cmd.OutOrStdout())
+			return nil
+		},
+	}
+	flags.addFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/proto/jsonproto"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var echoCount int64
+
+type Echo struct {
+	drpc.CallCtx
+}
+
+// 每5个请求失败一个
+func (that *Echo) Echo(arg *string) (string, *drpc.Status) {
+	if atomic.AddInt64(&echoCount, 1)%5 == 0 {
+		return "", drpc.NewStatus(drpc.CodeInternalServerError, "fail")
+	}
+	return *arg, nil
+}
+
+func TestBench(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		srv := drpc.NewEndpoint(drpc.EndpointConfig{ListenPort: 9210})
+		defer srv.Close()
+		srv.RouteCall(new(Echo))
+		go srv.ListenAndServe(jsonproto.NewJSONProtoFunc())
+		time.Sleep(500 * time.Millisecond)
+
+		out, err := execute("", "bench", "/echo/echo", "-a", "127.0.0.1:9210", "-p", "json",
+			"--concurrency", "5", "--connections", "2", "-n", "50", "--payload", "128", "-o", "json")
+		t.AssertNil(err)
+		var report BenchReport
+		t.AssertNil(json.Unmarshal([]byte(out), &report))
+		t.Assert(report.Requests, 50)
+		t.Assert(report.Succeeded, 40)
+		t.Assert(report.Failed, 10)
+		t.Assert(len(report.Codes), 2)
+		t.Assert(report.Codes[0].Code, drpc.CodeOK)
+		t.Assert(report.Codes[1].Code, drpc.CodeInternalServerError)
+		t.Assert(report.Codes[1].Count, 10)
+		t.Assert(report.Latency.Max >= report.Latency.P99, true)
+		t.Assert(report.Latency.P99 >= report.Latency.P50, true)
+		t.Assert(report.Latency.P50 >= report.Latency.Min, true)
+
+		// 限速时20个请求至少需要190ms
+		out, err = execute("", "bench", "/echo/echo", `"hi"`, "-a", "127.0.0.1:9210", "-p", "json",
+			"--qps", "100", "-n", "20", "-o", "json")
+		t.AssertNil(err)
+		t.AssertNil(json.Unmarshal([]byte(out), &report))
+		t.Assert(report.Requests, 20)
+		t.Assert(report.Elapsed >= 190*time.Millisecond, true)
+
+		// 按时间压测，输出表格
+		out, err = execute("", "bench", "/echo/echo", `"hi"`, "-a", "127.0.0.1:9210", "-p", "json", "-d", "200ms")
+		t.AssertNil(err)
+		t.Assert(strings.Contains(out, "Throughput:"), true)
+		t.Assert(strings.Contains(out, "p99="), true)
+
+		_, err = execute("", "bench", "/echo/echo", "-a", "127.0.0.1:9210", "--concurrency", "0")
+		t.AssertNE(err, nil)
+	})
+}
+
+func TestPercentile(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		var sorted []time.Duration
+		for i := 1; i <= 100; i++ {
+			sorted = append(sorted, time.Duration(i))
+		}
+		t.Assert(percentile(sorted, 0.5) == 50, true)
+		t.Assert(percentile(sorted, 0.9) == 90, true)
+		t.Assert(percentile(sorted, 0.99) == 99, true)
+		t.Assert(percentile(sorted[:1], 0.99) == 1, true)
+	})
+}
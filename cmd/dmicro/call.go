@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/codec"
+	"github.com/osgochina/dmicro/drpc/message"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 发送消息的参数
+type sendFlags struct {
+	dialFlags
+	codec string
+	meta  []string
+}
+
+// 调用的参数
+type callFlags struct {
+	sendFlags
+	output string
+}
+
+func (that *callFlags) addFlags(cmd *cobra.Command) {
+	that.sendFlags.addFlags(cmd)
+	cmd.Flags().StringVarP(&that.output, "output", "o", outputTable, "Output format, table or json")
+}
+
+func (that *callFlags) check() error {
+	if that.output != outputTable && that.output != outputJSON {
+		return fmt.Errorf("unsupported output: %s", that.output)
+	}
+	return nil
+}
+
+// 调用返回的状态
+type replyStatus struct {
+	Code int32  `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// 调用返回的结果，包含状态，元数据和消息体
+type callReply struct {
+	Status replyStatus            `json:"status"`
+	Meta   map[string]interface{} `json:"meta"`
+	Body   interface{}            `json:"body"`
+}
+
+func newCallReply(callCmd drpc.CallCmd, result interface{}) *callReply {
+	stat := callCmd.Status()
+	reply := &callReply{
+		Status: replyStatus{Code: stat.Code(), Msg: stat.Msg()},
+		Meta:   map[string]interface{}{},
+	}
+	if stat.OK() {
+		reply.Status.Msg = "OK"
+	} else if len(reply.Status.Msg) == 0 {
+		reply.Status.Msg = drpc.CodeText(reply.Status.Code)
+	}
+	if meta := callCmd.InputMeta(); meta != nil {
+		reply.Meta = meta.MapStrAny()
+	}
+	if stat.OK() {
+		reply.Body = result
+	}
+	if r, ok := reply.Body.(*json.RawMessage); ok && len(*r) == 0 {
+		reply.Body = nil
+	}
+	return reply
+}
+
+func (that *sendFlags) addFlags(cmd *cobra.Command) {
+	that.dialFlags.addFlags(cmd)
+	cmd.Flags().StringVarP(&that.codec, "codec", "c", codec.JsonName, "Body codec: json, plain, form, xml")
+	cmd.Flags().StringArrayVarP(&that.meta, "meta", "m", nil, "Meta header in key=value form, can be repeated")
+}
+
+// 消息的设置，不包含上下文
+func (that *sendFlags) setting() ([]message.MsgSetting, error) {
+	if _, err := codec.GetByName(that.codec); err != nil {
+		return nil, err
+	}
+	// 消息体从JSON解析为通用对象，没有具体的消息类型，无法使用protobuf编码
+	if that.codec == codec.ProtobufName {
+		return nil, fmt.Errorf("codec %s is not supported, the body is decoded from json without a message type", that.codec)
+	}
+	setting := []message.MsgSetting{
+		drpc.WithBodyCodec(that.codec),
+	}
+	for _, kv := range that.meta {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || len(k) == 0 {
+			return nil, fmt.Errorf("invalid meta %q, want key=value", kv)
+		}
+		setting = append(setting, message.WithSetMeta(k, v))
+	}
+	return setting, nil
+}
+
+// 把命令行输入的JSON转换为消息体，json编码直接发送原始内容，plain编码发送原始字符串
+func (that *sendFlags) body(raw string) (interface{}, error) {
+	switch that.codec {
+	case codec.JsonName:
+		if len(raw) == 0 {
+			return json.RawMessage("null"), nil
+		}
+		if !json.Valid([]byte(raw)) {
+			return nil, fmt.Errorf("body is not valid json: %s", raw)
+		}
+		return json.RawMessage(raw), nil
+	case codec.PlainName:
+		return raw, nil
+	}
+	var v interface{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return nil, fmt.Errorf("body is not valid json: %v", err)
+		}
+	}
+	return v, nil
+}
+
+// 接收结果的对象
+func (that *sendFlags) result() interface{} {
+	switch that.codec {
+	case codec.JsonName:
+		return new(json.RawMessage)
+	case codec.PlainName:
+		return new(string)
+	}
+	return new(interface{})
+}
+
+// 读取消息体，没有通过参数传入时从标准输入读取，"-"表示从标准输入读取
+func readBody(cmd *cobra.Command, args []string) (string, error) {
+	if len(args) > 1 && args[1] != "-" {
+		return args[1], nil
+	}
+	if len(args) > 1 || !isTerminal(cmd.InOrStdin()) {
+		b, err := io.ReadAll(cmd.InOrStdin())
+		return strings.TrimSpace(string(b)), err
+	}
+	return "", nil
+}
+
+// 判断输入是否是终端
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// 打印结果，json格式化输出
+func printResult(w io.Writer, result interface{}) {
+	switch r := result.(type) {
+	case *string:
+		_, _ = fmt.Fprintln(w, *r)
+		return
+	case *json.RawMessage:
+		if len(*r) == 0 {
+			_, _ = fmt.Fprintln(w, "null")
+			return
+		}
+	}
+	b, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "%v\n", result)
+		return
+	}
+	_, _ = fmt.Fprintln(w, string(b))
+}
+
+// 打印调用返回的结果，table格式依次输出状态，元数据和消息体
+func printReply(w io.Writer, reply *callReply, output string) {
+	if output == outputJSON {
+		printResult(w, reply)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "status: %d %s\n", reply.Status.Code, reply.Status.Msg)
+	_, _ = fmt.Fprintln(w, "meta:")
+	keys := make([]string, 0, len(reply.Meta))
+	for k := range reply.Meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(w, "  %s: %v\n", k, reply.Meta[k])
+	}
+	_, _ = fmt.Fprintln(w, "body:")
+	if reply.Body == nil {
+		_, _ = fmt.Fprintln(w, "null")
+		return
+	}
+	printResult(w, reply.Body)
+}
+
+// 把失败的状态转换为错误，输出状态的json
+func statusError(stat *drpc.Status) error {
+	return fmt.Errorf("status: %s", stat.String())
+}
+
+func newCallCmd() *cobra.Command {
+	flags := &callFlags{}
+	cmd := &cobra.Command{
+		Use:   "call <service_method> [json_body]",
+		Short: "Send a CALL message and print the reply.",
+		Long:  "Send a CALL message and print the reply status, meta and body. The body is read from stdin when it is omitted or is \"-\".",
+		Example: `
+  dmicro call /math/add '{"a":1,"b":2}' --address=127.0.0.1:9090
+  dmicro call /math/add '{"a":1,"b":2}' --service=test_one --registry=etcd --registry_address=127.0.0.1:2379
+  echo '[1,2,3]' | dmicro call /math/sum --address=127.0.0.1:9090 --proto=json --meta=token=abc -o json
+`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := flags.check(); err != nil {
+				return err
+			}
+			raw, err := readBody(cmd, args)
+			if err != nil {
+				return err
+			}
+			body, err := flags.body(raw)
+			if err != nil {
+				return err
+			}
+			setting, err := flags.setting()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), flags.timeout)
+			defer cancel()
+			setting = append(setting, message.WithContext(ctx))
+			endpoint, sess, err := flags.dial()
+			if err != nil {
+				return err
+			}
+			defer func() { _ = endpoint.Close() }()
+			result := flags.result()
+			callCmd := sess.Call(args[0], body, result, setting...)
+			printReply(cmd.OutOrStdout(), newCallReply(callCmd, result), flags.output)
+			if stat := callCmd.Status(); !stat.OK() {
+				return statusError(stat)
+			}
+			return nil
+		},
+	}
+	flags.addFlags(cmd)
+	return cmd
+}
+
+func newPushCmd() *cobra.Command {
+	flags := &sendFlags{}
+	cmd := &cobra.Command{
+		Use:   "push <service_method> [json_body]",
+		Short: "Send a PUSH message.",
+		Long:  "Send a PUSH message. The body is read from stdin when it is omitted or is \"-\".",
+		Example: `
+  dmicro push /notify/ping '"hello"' --address=127.0.0.1:9090
+`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raw, err := readBody(cmd, args)
+			if err != nil {
+				return err
+			}
+			body, err := flags.body(raw)
+			if err != nil {
+				return err
+			}
+			setting, err := flags.setting()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), flags.timeout)
+			defer cancel()
+			setting = append(setting, message.WithContext(ctx))
+			endpoint, sess, err := flags.dial()
+			if err != nil {
+				return err
+			}
+			defer func() { _ = endpoint.Close() }()
+			if stat := sess.Push(args[0], body, setting...); !stat.OK() {
+				return statusError(stat)
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "OK")
+			return nil
+		},
+	}
+	flags.addFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/proto/jsonproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type AddArgs struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type AddReply struct {
+	Sum   int    `json:"sum"`
+	Token string `json:"token"`
+}
+
+type Math struct {
+	drpc.CallCtx
+}
+
+func (that *Math) Add(arg *AddArgs) (*AddReply, *drpc.Status) {
+	that.SetMeta("x-sum", strconv.Itoa(arg.A+arg.B))
+	return &AddReply{Sum: arg.A + arg.B, Token: that.PeekMeta("token").(string)}, nil
+}
+
+var pushed = make(chan string, 1)
+
+type Notify struct {
+	drpc.PushCtx
+}
+
+func (that *Notify) Ping(arg *string) *drpc.Status {
+	pushed <- *arg
+	return nil
+}
+
+// 执行命令，返回输出和错误
+func execute(stdin string, args ...string) (string, error) {
+	cmd := newRootCmd()
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetIn(strings.NewReader(stdin))
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestCallAndPush(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		srv := drpc.NewEndpoint(drpc.EndpointConfig{ListenPort: 9209})
+		defer srv.Close()
+		srv.RouteCall(new(Math))
+		srv.RoutePush(new(Notify))
+		go srv.ListenAndServe(jsonproto.NewJSONProtoFunc())
+		time.Sleep(500 * time.Millisecond)
+
+		out, err := execute("", "call", "/math/add", `{"a":1,"b":2}`, "-a", "127.0.0.1:9209", "-p", "json", "-m", "token=abc")
+		t.AssertNil(err)
+		t.Assert(strings.Contains(out, "status: 0 OK"), true)
+		t.Assert(strings.Contains(out, "x-sum: 3"), true)
+		t.Assert(strings.Contains(out, `"sum": 3`), true)
+		t.Assert(strings.Contains(out, `"token": "abc"`), true)
+
+		// json格式输出状态，元数据和消息体
+		out, err = execute("", "call", "/math/add", `{"a":1,"b":2}`, "-a", "127.0.0.1:9209", "-p", "json", "-m", "token=abc", "-o", "json")
+		t.AssertNil(err)
+		var reply struct {
+			Status struct {
+				Code int32  `json:"code"`
+				Msg  string `json:"msg"`
+			} `json:"status"`
+			Meta map[string]string `json:"meta"`
+			Body AddReply          `json:"body"`
+		}
+		t.AssertNil(json.Unmarshal([]byte(out), &reply))
+		t.Assert(reply.Status.Code, drpc.CodeOK)
+		t.Assert(reply.Status.Msg, "OK")
+		t.Assert(reply.Meta["x-sum"], "3")
+		t.Assert(reply.Body, AddReply{Sum: 3, Token: "abc"})
+
+		// 从标准输入读取消息体
+		out, err = execute(`{"a":2,"b":2}`, "call", "/math/add", "-", "-a", "127.0.0.1:9209", "-p", "json", "-m", "token=x")
+		t.AssertNil(err)
+		t.Assert(strings.Contains(out, `"sum": 4`), true)
+
+		out, err = execute("", "call", "/math/sub", "{}", "-a", "127.0.0.1:9209", "-p", "json")
+		t.AssertNE(err, nil)
+		t.Assert(strings.Contains(err.Error(), "404"), true)
+		t.Assert(strings.Contains(out, "status: 404"), true)
+
+		out, err = execute("", "call", "/math/sub", "{}", "-a", "127.0.0.1:9209", "-p", "json", "-o", "json")
+		t.AssertNE(err, nil)
+		t.Assert(strings.Contains(out, `"code": 404`), true)
+		t.Assert(strings.Contains(out, `"body": null`), true)
+
+		_, err = execute("", "call", "/math/add", "{}", "-a", "127.0.0.1:9209", "-p", "json", "-o", "yaml")
+		t.AssertNE(err, nil)
+
+		_, err = execute("", "call", "/math/add", "{", "-a", "127.0.0.1:9209", "-p", "json")
+		t.AssertNE(err, nil)
+
+		_, err = execute("", "call", "/math/add", "{}", "-p", "json")
+		t.AssertNE(err, nil)
+
+		_, err = execute("", "call", "/math/add", "{}", "-a", "127.0.0.1:9209", "-p", "unknown")
+		t.AssertNE(err, nil)
+
+		// 通用对象无法使用protobuf编码
+		_, err = execute("", "call", "/math/add", "{}", "-a", "127.0.0.1:9209", "-p", "json", "-c", "protobuf")
+		t.AssertNE(err, nil)
+		t.Assert(strings.Contains(err.Error(), "protobuf"), true)
+
+		out, err = execute("", "push", "/notify/ping", `"hello"`, "-a", "127.0.0.1:9209", "-p", "json")
+		t.AssertNil(err)
+		t.Assert(strings.TrimSpace(out), "OK")
+		select {
+		case v := <-pushed:
+			t.Assert(v, "hello")
+		case <-time.After(3 * time.Second):
+			t.Fatal("push not received")
+		}
+	})
+}
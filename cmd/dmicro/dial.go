@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/proto"
+	"github.com/osgochina/dmicro/drpc/proto/httpproto"
+	"github.com/osgochina/dmicro/drpc/proto/jsonproto"
+	"github.com/osgochina/dmicro/drpc/proto/jsonrpcproto"
+	"github.com/osgochina/dmicro/drpc/proto/pbproto"
+	"github.com/osgochina/dmicro/drpc/proto/rawproto"
+	"github.com/osgochina/dmicro/registry"
+	"github.com/osgochina/dmicro/registry/etcd"
+	"github.com/osgochina/dmicro/registry/memory"
+	"github.com/osgochina/dmicro/selector"
+	"github.com/spf13/cobra"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 支持的协议
+var protoFuncs = map[string]func() proto.ProtoFunc{
+	"raw":     rawproto.NewRawProtoFunc,
+	"json":    jsonproto.NewJSONProtoFunc,
+	"http":    func() proto.ProtoFunc { return httpproto.NewHTTProtoFunc() },
+	"jsonrpc": jsonrpcproto.NewJSONRPCProtoFunc,
+	"pb":      pbproto.NewPbProtoFunc,
+}
+
+// 支持的注册中心，memory只在当前进程内有效，用于和其他代码共享同一个进程时检查注册信息
+var registries = map[string]func(opts ...registry.Option) registry.Registry{
+	"mdns":   registry.NewRegistry,
+	"etcd":   etcd.NewRegistry,
+	"memory": memory.NewRegistry,
+}
+
+// 注册中心的参数
+type registryFlags struct {
+	registry        string
+	registryAddress []string
+}
+
+func (that *registryFlags) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&that.registry, "registry", "mdns", "Registry: mdns, etcd, memory")
+	cmd.Flags().StringSliceVar(&that.registryAddress, "registry_address", nil, "Comma-separated list of registry addresses")
+}
+
+// 创建注册中心
+func (that *registryFlags) newRegistry() (registry.Registry, error) {
+	var opts []registry.Option
+	if len(that.registryAddress) > 0 {
+		opts = append(opts, registry.OptAddrList(that.registryAddress...))
+	}
+	newRegistry, ok := registries[that.registry]
+	if !ok {
+		return nil, fmt.Errorf("unsupported registry: %s", that.registry)
+	}
+	return newRegistry(opts...), nil
+}
+
+// 连接服务端的参数
+type dialFlags struct {
+	registryFlags
+	address     string
+	service     string
+	proto       string
+	timeout     time.Duration
+	tls         bool
+	tlsCert     string
+	tlsKey      string
+	tlsCA       string
+	tlsInsecure bool
+}
+
+func (that *dialFlags) addFlags(cmd *cobra.Command) {
+	that.registryFlags.addFlags(cmd)
+	cmd.Flags().StringVarP(&that.address, "address", "a", "", "Address of the server, e.g. 127.0.0.1:9090")
+	cmd.Flags().StringVarP(&that.service, "service", "s", "", "Service name resolved through the registry when address is empty")
+	cmd.Flags().StringVarP(&that.proto, "proto", "p", "raw", "Socket protocol: "+strings.Join(protoNames(), ", "))
+	cmd.Flags().DurationVarP(&that.timeout, "timeout", "t", 10*time.Second, "Timeout of dialing and calling")
+	cmd.Flags().BoolVar(&that.tls, "tls", false, "Dial with TLS")
+	cmd.Flags().StringVar(&that.tlsCert, "tls_cert", "", "TLS client certificate file")
+	cmd.Flags().StringVar(&that.tlsKey, "tls_key", "", "TLS client key file")
+	cmd.Flags().StringVar(&that.tlsCA, "tls_ca", "", "CA certificate file used to verify the server")
+	cmd.Flags().BoolVar(&that.tlsInsecure, "tls_insecure", false, "Skip verification of the server certificate")
+}
+
+// 解析服务端地址，没有指定地址时通过注册中心选择服务的一个节点
+func (that *dialFlags) resolve() (string, error) {
+	if len(that.address) > 0 {
+		return that.address, nil
+	}
+	if len(that.service) == 0 {
+		return "", fmt.Errorf("either --address or --service is required")
+	}
+	reg, err := that.newRegistry()
+	if err != nil {
+		return "", err
+	}
+	s := selector.NewSelector(selector.OptRegistry(reg))
+	defer func() { _ = s.Close() }()
+	next, err := s.Select(that.service)
+	if err != nil {
+		return "", fmt.Errorf("resolve service %s: %v", that.service, err)
+	}
+	node, err := next()
+	if err != nil {
+		return "", fmt.Errorf("resolve service %s: %v", that.service, err)
+	}
+	return node.Address, nil
+}
+
+// 连接服务端，调用方负责关闭返回的endpoint
+func (that *dialFlags) dial() (drpc.Endpoint, drpc.Session, error) {
+	endpoint, sessions, err := that.dialN(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return endpoint, sessions[0], nil
+}
+
+// 和服务端建立n个连接，调用方负责关闭返回的endpoint
+func (that *dialFlags) dialN(n int) (drpc.Endpoint, []drpc.Session, error) {
+	protoFunc, ok := protoFuncs[that.proto]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported proto: %s", that.proto)
+	}
+	addr, err := that.resolve()
+	if err != nil {
+		return nil, nil, err
+	}
+	endpoint := drpc.NewEndpoint(drpc.EndpointConfig{DialTimeout: that.timeout})
+	if that.tls || len(that.tlsCert) > 0 || len(that.tlsCA) > 0 || that.tlsInsecure {
+		tlsConfig, err := that.tlsConfig(addr)
+		if err != nil {
+			_ = endpoint.Close()
+			return nil, nil, err
+		}
+		endpoint.SetTLSConfig(tlsConfig)
+	}
+	sessions := make([]drpc.Session, 0, n)
+	for i := 0; i < n; i++ {
+		sess, stat := endpoint.Dial(addr, protoFunc())
+		if !stat.OK() {
+			_ = endpoint.Close()
+			return nil, nil, fmt.Errorf("dial %s: %s", addr, stat.String())
+		}
+		sessions = append(sessions, sess)
+	}
+	return endpoint, sessions, nil
+}
+
+func (that *dialFlags) tlsConfig(addr string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: that.tlsInsecure}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		tlsConfig.ServerName = host
+	}
+	if len(that.tlsCert) > 0 || len(that.tlsKey) > 0 {
+		cert, err := tls.LoadX509KeyPair(that.tlsCert, that.tlsKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if len(that.tlsCA) > 0 {
+		b, err := os.ReadFile(that.tlsCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificate found in %s", that.tlsCA)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+func protoNames() []string {
+	names := make([]string, 0, len(protoFuncs))
+	for name := range protoFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
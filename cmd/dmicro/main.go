@@ -0,0 +1,30 @@
+// dmicro 命令行工具，用于调试和管理dmicro服务
+//
+//	dmicro call /math/add '{"a":1,"b":2}' --address=127.0.0.1:9090
+//	dmicro push /notify/ping '"hello"' --service=test_one --registry=etcd --registry_address=127.0.0.1:2379
+package main
+
+import (
+	"github.com/osgochina/dmicro"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// 创建根命令
+func newRootCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:          "dmicro <command> [flags]",
+		Short:        "dmicro CLI",
+		Long:         "dmicro is a command line tool for debugging and managing services built on the DMicro framework",
+		Version:      dmicro.Version,
+		SilenceUsage: true,
+	}
+	rootCmd.AddCommand(newNewCmd(), newCallCmd(), newPushCmd(), newBenchCmd(), newRegistryCmd())
+	return rootCmd
+}
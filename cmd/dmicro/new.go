@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"github.com/osgochina/dmicro"
+	"github.com/spf13/cobra"
+	"go/format"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+//go:embed template/*.tmpl
+var templateFS embed.FS
+
+// 模板和生成的文件路径
+var projectFiles = []struct {
+	tmpl string
+	file string
+}{
+	{tmpl: "go.mod.tmpl", file: "go.mod"},
+	{tmpl: "main.go.tmpl", file: "main.go"},
+	{tmpl: "sandbox.go.tmpl", file: "sandbox/{{.SandboxFile}}.go"},
+	{tmpl: "controller.go.tmpl", file: "controller/greeter.go"},
+	{tmpl: "client.go.tmpl", file: "client/main.go"},
+	{tmpl: "config.toml.tmpl", file: "config/config.toml"},
+	{tmpl: "README.md.tmpl", file: "README.md"},
+}
+
+// 服务名称只能包含字母、数字、下划线和中划线，并以字母开头
+var projectNameRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// 生成项目使用的参数
+type project struct {
+	// 服务名称
+	Name string
+	// go module路径
+	Module string
+	// 依赖的dmicro版本
+	Version string
+	// sandbox结构体名称
+	Sandbox string
+	// sandbox文件名
+	SandboxFile string
+	// rpc服务端口
+	Port int
+	// prometheus指标端口
+	MetricsPort int
+}
+
+// 生成项目到指定目录
+func (that *project) generate(dir string, force bool) ([]string, error) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 && !force {
+		return nil, fmt.Errorf("directory %s is not empty, use --force to overwrite", dir)
+	}
+	var files []string
+	for _, f := range projectFiles {
+		name, err := that.execute(f.file, f.file)
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := templateFS.ReadFile(path.Join("template", f.tmpl))
+		if err != nil {
+			return nil, err
+		}
+		content, err := that.execute(f.tmpl, string(tmpl))
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(name, ".go") {
+			b, err := format.Source([]byte(content))
+			if err != nil {
+				return nil, fmt.Errorf("format %s: %v", name, err)
+			}
+			content = string(b)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if err = os.WriteFile(target, []byte(content), 0644); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+func (that *project) execute(name string, text string) (string, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, that); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// 转换为驼峰格式，例如 user-service 转换为 UserService
+func camelCase(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '-' || r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			b.WriteString(strings.ToUpper(string(r)))
+			upper = false
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func newNewCmd() *cobra.Command {
+	var (
+		module      string
+		dir         string
+		port        int
+		metricsPort int
+		force       bool
+	)
+	cmd := &cobra.Command{
+		Use:   "new <name>",
+		Short: "Create a service skeleton.",
+		Long:  "Create a service skeleton with a dserver main, an rpc sandbox, a sample controller, a config file, registry, prometheus metrics and a client.",
+		Example: `
+  dmicro new greeter
+  dmicro new user-service --module=github.com/foo/user-service --port=9090
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if !projectNameRegexp.MatchString(name) {
+				return fmt.Errorf("invalid name %q, only letters, digits, '_' and '-' are allowed and it must start with a letter", name)
+			}
+			if len(module) == 0 {
+				module = name
+			}
+			if len(dir) == 0 {
+				dir = name
+			}
+			p := &project{
+				Name:        name,
+				Module:      module,
+				Version:     dmicro.Version,
+				Sandbox:     camelCase(name) + "Sandbox",
+				SandboxFile: strings.ReplaceAll(strings.ToLower(name), "-", "_"),
+				Port:        port,
+				MetricsPort: metricsPort,
+			}
+			files, err := p.generate(dir, force)
+			if err != nil {
+				return err
+			}
+			w := cmd.OutOrStdout()
+			for _, f := range files {
+				_, _ = fmt.Fprintf(w, "create %s\n", filepath.Join(dir, filepath.FromSlash(f)))
+			}
+			_, _ = fmt.Fprintf(w, "\nget started:\n  cd %s\n  go mod tidy\n  go run . start\n  go run ./client\n", dir)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&module, "module", "", "Go module path, default is the name")
+	cmd.Flags().StringVar(&dir, "dir", "", "Output directory, default is the name")
+	cmd.Flags().IntVar(&port, "port", 8199, "Listen port of the rpc server")
+	cmd.Flags().IntVar(&metricsPort, "metrics_port", 9101, "Listen port of the prometheus metrics")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite files in a non-empty directory")
+	return cmd
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 把dmicro替换为本仓库的代码后编译生成的项目，依赖只从本地的模块缓存中读取，不访问网络
+func buildProject(dir string) (string, error) {
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "go.mod"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString("\nreplace github.com/osgochina/dmicro => " + root + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command("go", "build", "-o", os.DevNull, "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOSUMDB=off", "GOTOOLCHAIN=local")
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func TestNew(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		dir, err := os.MkdirTemp("", "dmicro-new")
+		t.AssertNil(err)
+		defer os.RemoveAll(dir)
+		target := filepath.Join(dir, "user-service")
+
+		_, err = execute("", "new", "user-service", "--dir", target, "--module", "example.com/user-service", "--port", "9300")
+		t.AssertNil(err)
+
+		for _, f := range []string{"main.go", "sandbox/user_service.go", "controller/greeter.go", "client/main.go"} {
+			b, err := os.ReadFile(filepath.Join(target, f))
+			t.AssertNil(err)
+			_, err = parser.ParseFile(token.NewFileSet(), f, b, parser.AllErrors)
+			t.AssertNil(err)
+		}
+		b, err := os.ReadFile(filepath.Join(target, "go.mod"))
+		t.AssertNil(err)
+		t.Assert(strings.HasPrefix(string(b), "module example.com/user-service\n"), true)
+		t.Assert(strings.Contains(string(b), "require github.com/osgochina/dmicro "+dmicro.Version+"\n"), true)
+
+		// 生成的项目使用本仓库的代码可以编译通过
+		out, err := buildProject(target)
+		t.AssertNil(err)
+		t.Assert(out, "")
+
+		b, err = os.ReadFile(filepath.Join(target, "sandbox/user_service.go"))
+		t.AssertNil(err)
+		t.Assert(strings.Contains(string(b), "type UserServiceSandbox struct"), true)
+		t.Assert(strings.Contains(string(b), `"example.com/user-service/controller"`), true)
+
+		b, err = os.ReadFile(filepath.Join(target, "config/config.toml"))
+		t.AssertNil(err)
+		t.Assert(strings.Contains(string(b), "[sandbox.UserServiceSandbox]"), true)
+		t.Assert(strings.Contains(string(b), "ListenPort = 9300"), true)
+
+		// 目录不为空时需要指定--force
+		_, err = execute("", "new", "user-service", "--dir", target)
+		t.AssertNE(err, nil)
+		_, err = execute("", "new", "user-service", "--dir", target, "--force")
+		t.AssertNil(err)
+
+		_, err = execute("", "new", "1user", "--dir", filepath.Join(dir, "x"))
+		t.AssertNE(err, nil)
+	})
+}
+
+func TestCamelCase(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		t.Assert(camelCase("user-service"), "UserService")
+		t.Assert(camelCase("order_api"), "OrderApi")
+		t.Assert(camelCase("greeter"), "Greeter")
+	})
+}
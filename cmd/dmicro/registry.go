@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/modood/table"
+	"github.com/osgochina/dmicro/registry"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 输出格式
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// 查看注册中心的参数
+type inspectFlags struct {
+	registryFlags
+	output string
+}
+
+func (that *inspectFlags) addFlags(cmd *cobra.Command) {
+	that.registryFlags.addFlags(cmd)
+	cmd.Flags().StringVarP(&that.output, "output", "o", outputTable, "Output format, table or json")
+}
+
+func (that *inspectFlags) check() error {
+	if that.output != outputTable && that.output != outputJSON {
+		return fmt.Errorf("unsupported output: %s", that.output)
+	}
+	return nil
+}
+
+// 表格中的服务
+type serviceRow struct {
+	Name     string
+	Version  string
+	Nodes    int
+	Metadata string
+}
+
+// 表格中的节点
+type nodeRow struct {
+	Service  string
+	Version  string
+	ID       string
+	Address  string
+	Paths    string
+	Metadata string
+}
+
+func newRegistryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "registry <command>",
+		Short: "Inspect services in the registry.",
+		Long:  "Inspect services in the registry.",
+	}
+	cmd.AddCommand(newRegistryListCmd(), newRegistryGetCmd(), newRegistryWatchCmd())
+	return cmd
+}
+
+func newRegistryListCmd() *cobra.Command {
+	flags := &inspectFlags{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all services.",
+		Example: `
+  dmicro registry list
+  dmicro registry list --registry=etcd --registry_address=127.0.0.1:2379 -o json
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := flags.check(); err != nil {
+				return err
+			}
+			reg, err := flags.newRegistry()
+			if err != nil {
+				return err
+			}
+			services, err := reg.ListServices()
+			if err != nil {
+				return err
+			}
+			sortServices(services)
+			w := cmd.OutOrStdout()
+			if flags.output == outputJSON {
+				return printJSON(w, services)
+			}
+			rows := make([]serviceRow, 0, len(services))
+			for _, s := range services {
+				rows = append(rows, serviceRow{
+					Name:     s.Name,
+					Version:  s.Version,
+					Nodes:    len(s.Nodes),
+					Metadata: formatMetadata(s.Metadata),
+				})
+			}
+			printTable(w, rows, len(rows))
+			return nil
+		},
+	}
+	flags.addFlags(cmd)
+	return cmd
+}
+
+func newRegistryGetCmd() *cobra.Command {
+	flags := &inspectFlags{}
+	cmd := &cobra.Command{
+		Use:   "get <service>",
+		Short: "Get the versions and nodes of a service.",
+		Example: `
+  dmicro registry get test_one
+  dmicro registry get test_one --registry=etcd --registry_address=127.0.0.1:2379 -o json
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := flags.check(); err != nil {
+				return err
+			}
+			reg, err := flags.newRegistry()
+			if err != nil {
+				return err
+			}
+			services, err := reg.GetService(args[0])
+			if err != nil {
+				return err
+			}
+			sortServices(services)
+			w := cmd.OutOrStdout()
+			if flags.output == outputJSON {
+				return printJSON(w, services)
+			}
+			var rows []nodeRow
+			for _, s := range services {
+				for _, n := range s.Nodes {
+					rows = append(rows, nodeRow{
+						Service:  s.Name,
+						Version:  s.Version,
+						ID:       n.Id,
+						Address:  n.Address,
+						Paths:    strings.Join(n.Paths, ","),
+						Metadata: formatMetadata(n.Metadata),
+					})
+				}
+			}
+			printTable(w, rows, len(rows))
+			return nil
+		},
+	}
+	flags.addFlags(cmd)
+	return cmd
+}
+
+func newRegistryWatchCmd() *cobra.Command {
+	flags := &inspectFlags{}
+	var count int
+	cmd := &cobra.Command{
+		Use:   "watch [service]",
+		Short: "Watch changes of services, stop with Ctrl+C.",
+		Example: `
+  dmicro registry watch
+  dmicro registry watch test_one --registry=etcd --registry_address=127.0.0.1:2379 -o json
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := flags.check(); err != nil {
+				return err
+			}
+			reg, err := flags.newRegistry()
+			if err != nil {
+				return err
+			}
+			var opts []registry.WatchOption
+			if len(args) > 0 {
+				opts = append(opts, registry.OptWatchService(args[0]))
+			}
+			watcher, err := reg.Watch(opts...)
+			if err != nil {
+				return err
+			}
+			// 收到退出信号时停止监听，Next会立即返回错误
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+			defer signal.Stop(sigCh)
+			done := make(chan struct{})
+			interrupted := make(chan struct{})
+			defer close(done)
+			defer watcher.Stop()
+			go func() {
+				select {
+				case <-sigCh:
+					close(interrupted)
+					watcher.Stop()
+				case <-done:
+				}
+			}()
+			w := cmd.OutOrStdout()
+			for i := 0; count <= 0 || i < count; i++ {
+				res, err := watcher.Next()
+				if err != nil {
+					select {
+					case <-interrupted:
+						return nil
+					default:
+						return err
+					}
+				}
+				if err = printEvent(w, flags.output, res); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	flags.addFlags(cmd)
+	cmd.Flags().IntVarP(&count, "count", "n", 0, "Exit after receiving the number of events, 0 means never")
+	return cmd
+}
+
+// 每个事件输出一行：时间 事件 服务 版本 节点地址，json格式每行一个事件
+func printEvent(w io.Writer, output string, res *registry.Result) error {
+	if output == outputJSON {
+		b, err := json.Marshal(struct {
+			Action  string            `json:"action"`
+			Service *registry.Service `json:"service"`
+		}{Action: res.Action.String(), Service: res.Service})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+	var name, version string
+	var addrs []string
+	if res.Service != nil {
+		name, version = res.Service.Name, res.Service.Version
+		for _, n := range res.Service.Nodes {
+			addrs = append(addrs, n.Address)
+		}
+	}
+	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+		time.Now().Format("2006-01-02 15:04:05"), res.Action.String(), name, version, strings.Join(addrs, ","))
+	return err
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// 输出表格，没有数据时输出提示
+func printTable(w io.Writer, rows interface{}, n int) {
+	if n == 0 {
+		_, _ = fmt.Fprintln(w, "no services found")
+		return
+	}
+	_, _ = fmt.Fprintln(w, table.Table(rows))
+}
+
+// 按照名称和版本排序
+func sortServices(services []*registry.Service) {
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Name != services[j].Name {
+			return services[i].Name < services[j].Name
+		}
+		return services[i].Version < services[j].Version
+	})
+}
+
+// 元数据格式化为 k1=v1,k2=v2
+func formatMetadata(md map[string]string) string {
+	kvs := make([]string, 0, len(md))
+	for k, v := range md {
+		kvs = append(kvs, k+"="+v)
+	}
+	sort.Strings(kvs)
+	return strings.Join(kvs, ",")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/registry"
+	"github.com/osgochina/dmicro/registry/memory"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		reg := memory.NewRegistry()
+		registries["memory"] = func(opts ...registry.Option) registry.Registry {
+			return reg
+		}
+		defer func() {
+			registries["memory"] = memory.NewRegistry
+		}()
+
+		out, err := execute("", "registry", "list", "--registry=memory")
+		t.AssertNil(err)
+		t.Assert(strings.TrimSpace(out), "no services found")
+
+		t.AssertNil(reg.Register(&registry.Service{
+			Name:    "user",
+			Version: "v1",
+			Nodes: []*registry.Node{
+				{Id: "user-1", Address: "127.0.0.1:9001", Paths: []string{"/user/get"}, Metadata: map[string]string{"zone": "a"}},
+			},
+		}))
+		t.AssertNil(reg.Register(&registry.Service{
+			Name:    "order",
+			Version: "v2",
+			Nodes:   []*registry.Node{{Id: "order-1", Address: "127.0.0.1:9002"}},
+		}))
+
+		out, err = execute("", "registry", "list", "--registry=memory")
+		t.AssertNil(err)
+		t.Assert(strings.Contains(out, "user"), true)
+		t.Assert(strings.Index(out, "order") < strings.Index(out, "user"), true)
+
+		out, err = execute("", "registry", "list", "--registry=memory", "-o", "json")
+		t.AssertNil(err)
+		var services []*registry.Service
+		t.AssertNil(json.Unmarshal([]byte(out), &services))
+		t.Assert(len(services), 2)
+		t.Assert(services[0].Name, "order")
+
+		out, err = execute("", "registry", "get", "user", "--registry=memory")
+		t.AssertNil(err)
+		t.Assert(strings.Contains(out, "127.0.0.1:9001"), true)
+		t.Assert(strings.Contains(out, "/user/get"), true)
+		t.Assert(strings.Contains(out, "zone=a"), true)
+
+		_, err = execute("", "registry", "get", "unknown", "--registry=memory")
+		t.AssertNE(err, nil)
+
+		_, err = execute("", "registry", "list", "--registry=memory", "-o", "xml")
+		t.AssertNE(err, nil)
+
+		// 监听到一个事件后退出
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			_ = reg.Register(&registry.Service{
+				Name:    "pay",
+				Version: "v1",
+				Nodes:   []*registry.Node{{Id: "pay-1", Address: "127.0.0.1:9003"}},
+			})
+		}()
+		out, err = execute("", "registry", "watch", "pay", "--registry=memory", "-n", "1", "-o", "json")
+		t.AssertNil(err)
+		var event struct {
+			Action  string            `json:"action"`
+			Service *registry.Service `json:"service"`
+		}
+		t.AssertNil(json.Unmarshal([]byte(out), &event))
+		t.Assert(event.Action, "update")
+		t.Assert(event.Service.Name, "pay")
+	})
+}
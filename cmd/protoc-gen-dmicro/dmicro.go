@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"github.com/osgochina/dmicro"
+	"github.com/osgochina/dmicro/drpc"
+	"google.golang.org/protobuf/compiler/protogen"
+	"strings"
+)
+
+const (
+	fmtPackage     = protogen.GoImportPath("fmt")
+	drpcPackage    = protogen.GoImportPath("github.com/osgochina/dmicro/drpc")
+	codecPackage   = protogen.GoImportPath("github.com/osgochina/dmicro/drpc/codec")
+	messagePackage = protogen.GoImportPath("github.com/osgochina/dmicro/drpc/message")
+)
+
+// 名称以该后缀结尾的service生成push控制器
+const pushSuffix = "Push"
+
+// 生成的路由常量使用的映射规则，和 drpc 默认的全局映射规则一致。
+// 调用 drpc.SetServiceMethodMapper 修改了映射规则时，注册生成的控制器会panic
+var serviceMethodMapper = drpc.HTTPServiceMethodMapper
+
+// 生成代码时使用的上下文
+type generator struct {
+	g    *protogen.GeneratedFile
+	file *protogen.File
+	// 为请求追加protobuf编码设置的方法名称
+	settingFunc string
+	// 检查注册的路由和生成的路由是否一致的方法名称
+	checkRoutesFunc string
+}
+
+// 生成单个.proto文件对应的代码，文件中没有service时不生成
+func generateFile(gen *protogen.Plugin, file *protogen.File) (*protogen.GeneratedFile, error) {
+	if len(file.Services) == 0 {
+		return nil, nil
+	}
+	for _, service := range file.Services {
+		for _, method := range service.Methods {
+			if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
+				return nil, fmt.Errorf("protoc-gen-dmicro: %s.%s: streaming method is not supported",
+					service.Desc.FullName(), method.Desc.Name())
+			}
+		}
+	}
+	g := gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_dmicro.pb.go", file.GoImportPath)
+	// 同一个包里可能有多个.proto文件，方法名称带上文件标识避免冲突
+	filePrefix := "f" + strings.TrimPrefix(file.GoDescriptorIdent.GoName, "F")
+	that := &generator{
+		g:               g,
+		file:            file,
+		settingFunc:     filePrefix + "_callSetting",
+		checkRoutesFunc: filePrefix + "_checkRoutes",
+	}
+	g.P("// Code generated by protoc-gen-dmicro. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P("// \tprotoc-gen-dmicro ", dmicro.Version)
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+	for _, service := range file.Services {
+		if isPush(service) {
+			that.generatePush(service)
+		} else {
+			that.generateCall(service)
+		}
+	}
+	that.generateSetting()
+	that.generateCheckRoutes()
+	return g, nil
+}
+
+// 是否生成push控制器
+func isPush(service *protogen.Service) bool {
+	return strings.HasSuffix(service.GoName, pushSuffix)
+}
+
+// 使用默认的路由映射规则生成方法的路由，和 Router.RouteCall 注册的路由一致
+func serviceMethod(service *protogen.Service, method *protogen.Method) string {
+	return serviceMethodMapper(serviceMethodMapper("", service.GoName), method.GoName)
+}
+
+// 控制器接口名称，call服务为XxxCall，push服务的名称已经以Push结尾，直接使用服务名称
+func ctrlTypeName(service *protogen.Service) string {
+	if isPush(service) {
+		return service.GoName
+	}
+	return service.GoName + "Call"
+}
+
+// 路由常量名称
+func serviceMethodConst(service *protogen.Service, method *protogen.Method) string {
+	return service.GoName + method.GoName + "ServiceMethod"
+}
+
+// 路由常量列表，用于检查注册的路由
+func serviceMethodConsts(service *protogen.Service) string {
+	consts := make([]string, 0, len(service.Methods))
+	for _, method := range service.Methods {
+		consts = append(consts, serviceMethodConst(service, method))
+	}
+	return strings.Join(consts, ", ")
+}
+
+// 生成路由注册方法，注册后检查路由和生成的路由常量一致
+func (that *generator) generateRoute(service *protogen.Service, funcName, ctrlName, routeFunc string) {
+	g := that.g
+	g.P("func ", funcName, "(router *", drpcPackage.Ident("Router"), ", ctrl ", ctrlName,
+		", plugin ...", drpcPackage.Ident("Plugin"), ") []string {")
+	g.P("names := router.", routeFunc, "(ctrl, plugin...)")
+	if len(service.Methods) > 0 {
+		g.P(that.checkRoutesFunc, "(", fmt.Sprintf("%q", ctrlName), ", names, ", serviceMethodConsts(service), ")")
+	}
+	g.P("return names")
+	g.P("}")
+	g.P()
+}
+
+// 生成路由常量
+func (that *generator) generateServiceMethods(service *protogen.Service) {
+	if len(service.Methods) == 0 {
+		return
+	}
+	g := that.g
+	g.P("// ", service.GoName, "服务的路由，使用 drpc.HTTPServiceMethodMapper 映射规则")
+	g.P("const (")
+	for _, method := range service.Methods {
+		g.P(serviceMethodConst(service, method), " = ", fmt.Sprintf("%q", serviceMethod(service, method)))
+	}
+	g.P(")")
+	g.P()
+}
+
+// 生成call服务的控制器接口和客户端
+func (that *generator) generateCall(service *protogen.Service) {
+	var (
+		g        = that.g
+		status   = g.QualifiedGoIdent(drpcPackage.Ident("Status"))
+		ctrlName = ctrlTypeName(service)
+	)
+	that.generateServiceMethods(service)
+
+	g.P("// ", ctrlName, " ", service.GoName, "服务的控制器接口。")
+	g.P("// 实现该接口的结构体需要匿名嵌入 drpc.CallCtx，并且结构体名称为", service.GoName, "，注册的路由才和客户端一致，否则注册时panic")
+	g.Annotate(ctrlName, service.Location)
+	g.P("type ", ctrlName, " interface {")
+	g.P(drpcPackage.Ident("CallCtx"))
+	for _, method := range service.Methods {
+		g.Annotate(ctrlName+"."+method.GoName, method.Location)
+		g.P(method.Comments.Leading,
+			method.GoName, "(arg *", method.Input.GoIdent, ") (*", method.Output.GoIdent, ", *", status, ")")
+	}
+	g.P("}")
+	g.P()
+
+	g.P("// Route", ctrlName, " 把", service.GoName, "服务的控制器注册到路由")
+	that.generateRoute(service, "Route"+ctrlName, ctrlName, "RouteCall")
+
+	clientName := that.generateClient(service)
+	for _, method := range service.Methods {
+		g.P(methodComment(method, " %s 调用%s方法"),
+			"func (that *", clientName, ") ", method.GoName, "(arg *", method.Input.GoIdent,
+			", setting ...", messagePackage.Ident("MsgSetting"), ") (*", method.Output.GoIdent, ", *", status, ") {")
+		g.P("result := new(", method.Output.GoIdent, ")")
+		g.P("stat := that.caller.Call(", serviceMethodConst(service, method), ", arg, result, ", that.settingFunc, "(setting)...).Status()")
+		g.P("return result, stat")
+		g.P("}")
+		g.P()
+	}
+}
+
+// 生成push服务的控制器接口和客户端
+func (that *generator) generatePush(service *protogen.Service) {
+	var (
+		g        = that.g
+		status   = g.QualifiedGoIdent(drpcPackage.Ident("Status"))
+		ctrlName = ctrlTypeName(service)
+	)
+	that.generateServiceMethods(service)
+
+	g.P("// ", ctrlName, " ", service.GoName, "服务的push控制器接口。")
+	g.P("// 实现该接口的结构体需要匿名嵌入 drpc.PushCtx，并且结构体名称为", service.GoName, "，注册的路由才和客户端一致，否则注册时panic")
+	g.Annotate(ctrlName, service.Location)
+	g.P("type ", ctrlName, " interface {")
+	g.P(drpcPackage.Ident("PushCtx"))
+	for _, method := range service.Methods {
+		g.Annotate(ctrlName+"."+method.GoName, method.Location)
+		g.P(method.Comments.Leading,
+			method.GoName, "(arg *", method.Input.GoIdent, ") *", status)
+	}
+	g.P("}")
+	g.P()
+
+	g.P("// Route", ctrlName, " 把", service.GoName, "服务的push控制器注册到路由")
+	that.generateRoute(service, "Route"+ctrlName, ctrlName, "RoutePush")
+
+	clientName := that.generateClient(service)
+	for _, method := range service.Methods {
+		g.P(methodComment(method, " %s 推送%s消息"),
+			"func (that *", clientName, ") ", method.GoName, "(arg *", method.Input.GoIdent,
+			", setting ...", messagePackage.Ident("MsgSetting"), ") *", status, " {")
+		g.P("return that.caller.Push(", serviceMethodConst(service, method), ", arg, ", that.settingFunc, "(setting)...)")
+		g.P("}")
+		g.P()
+	}
+}
+
+// 生成客户端结构体，返回结构体名称
+func (that *generator) generateClient(service *protogen.Service) string {
+	g := that.g
+	clientName := service.GoName + "Client"
+	g.P("// ", clientName, " ", service.GoName, "服务的客户端，消息使用protobuf编码")
+	g.P("type ", clientName, " struct {")
+	g.P("caller ", drpcPackage.Ident("Caller"))
+	g.P("}")
+	g.P()
+	g.P("// New", clientName, " 创建", service.GoName, "服务的客户端，caller可以是 *client.RpcClient 或者 drpc.Session")
+	g.P("func New", clientName, "(caller ", drpcPackage.Ident("Caller"), ") *", clientName, " {")
+	g.P("return &", clientName, "{caller: caller}")
+	g.P("}")
+	g.P()
+	return clientName
+}
+
+// 生成为请求追加protobuf编码设置的方法
+func (that *generator) generateSetting() {
+	g := that.g
+	msgSetting := g.QualifiedGoIdent(messagePackage.Ident("MsgSetting"))
+	g.P("// ", that.settingFunc, " 使用protobuf编码消息，调用方传入的设置优先")
+	g.P("func ", that.settingFunc, "(setting []", msgSetting, ") []", msgSetting, " {")
+	g.P("return append([]", msgSetting, "{", drpcPackage.Ident("WithBodyCodec"),
+		"(", codecPackage.Ident("ProtobufName"), ")}, setting...)")
+	g.P("}")
+}
+
+// 生成检查注册路由的方法，路由由控制器结构体名称决定，和生成的路由常量不一致时客户端无法调用
+func (that *generator) generateCheckRoutes() {
+	g := that.g
+	g.P()
+	g.P("// ", that.checkRoutesFunc, " 检查控制器注册的路由包含所有生成的路由，结构体名称不一致或者修改了路由映射规则时panic")
+	g.P("func ", that.checkRoutesFunc, "(ctrlName string, names []string, serviceMethods ...string) {")
+	g.P("for _, serviceMethod := range serviceMethods {")
+	g.P("found := false")
+	g.P("for _, name := range names {")
+	g.P("if name == serviceMethod {")
+	g.P("found = true")
+	g.P("break")
+	g.P("}")
+	g.P("}")
+	g.P("if !found {")
+	g.P("panic(", fmtPackage.Ident("Sprintf"), "(\"%s: route %s is not registered, got %v, check the name of the controller struct and use drpc.HTTPServiceMethodMapper\", ctrlName, serviceMethod, names))")
+	g.P("}")
+	g.P("}")
+	g.P("}")
+}
+
+// 客户端方法的注释，proto中没有注释时生成默认注释
+func methodComment(method *protogen.Method, format string) protogen.Comments {
+	if len(method.Comments.Leading) > 0 {
+		return method.Comments.Leading
+	}
+	return protogen.Comments(fmt.Sprintf(format, method.GoName, method.Desc.Name()))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/proto/pbproto"
+	"github.com/osgochina/dmicro/examples/protobuf/pb"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+	"os"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "更新examples/protobuf/pb中生成的代码")
+
+const goldenFile = "../../examples/protobuf/pb/math_dmicro.pb.go"
+
+// 使用examples/protobuf/pb中的proto定义生成代码
+func generateExample(t *gtest.T) []byte {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{pb.File_math_proto.Path()},
+		Parameter:      proto.String("paths=source_relative"),
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(pb.File_math_proto)},
+	}
+	gen, err := protogen.Options{}.New(req)
+	t.AssertNil(err)
+	for _, f := range gen.Files {
+		if f.Generate {
+			_, err = generateFile(gen, f)
+			t.AssertNil(err)
+		}
+	}
+	resp := gen.Response()
+	t.AssertNil(resp.Error)
+	t.Assert(len(resp.File), 1)
+	t.Assert(resp.File[0].GetName(), "math_dmicro.pb.go")
+	return []byte(resp.File[0].GetContent())
+}
+
+func TestGenerateExample(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		content := generateExample(t)
+		if *update {
+			t.AssertNil(os.WriteFile(goldenFile, content, 0644))
+		}
+		golden, err := os.ReadFile(goldenFile)
+		t.AssertNil(err)
+		t.Assert(string(content), string(golden))
+	})
+}
+
+func TestGenerateStreaming(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		fd := protodesc.ToFileDescriptorProto(pb.File_math_proto)
+		fd.Service[0].Method[0].ServerStreaming = proto.Bool(true)
+		gen, err := protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{fd.GetName()},
+			ProtoFile:      []*descriptorpb.FileDescriptorProto{fd},
+		})
+		t.AssertNil(err)
+		_, err = generateFile(gen, gen.Files[0])
+		t.AssertNE(err, nil)
+	})
+}
+
+type Math struct {
+	drpc.CallCtx
+}
+
+func (that *Math) Add(arg *pb.AddArgs) (*pb.AddReply, *drpc.Status) {
+	var sum int32
+	for _, n := range arg.Nums {
+		sum += n
+	}
+	return &pb.AddReply{Sum: sum}, nil
+}
+
+type Push struct {
+	drpc.PushCtx
+}
+
+var pushed = make(chan string, 1)
+
+func (that *Push) Status(arg *pb.StatusArgs) *drpc.Status {
+	pushed <- arg.Msg
+	return nil
+}
+
+// 生成的控制器接口和客户端可以正常通信
+func TestGeneratedRoundTrip(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		svr := drpc.NewEndpoint(drpc.EndpointConfig{LocalIP: "127.0.0.1", ListenPort: 9207})
+		t.Assert(pb.RouteMathCall(svr.Router(), new(Math)), []string{pb.MathAddServiceMethod})
+		go func() {
+			_ = svr.ListenAndServe(pbproto.NewPbProtoFunc())
+		}()
+		defer svr.Close()
+		time.Sleep(500 * time.Millisecond)
+
+		cli := drpc.NewEndpoint(drpc.EndpointConfig{})
+		defer cli.Close()
+		t.Assert(pb.RoutePush(cli.Router(), new(Push)), []string{pb.PushStatusServiceMethod})
+		sess, stat := cli.Dial("127.0.0.1:9207", pbproto.NewPbProtoFunc())
+		t.Assert(stat.OK(), true)
+
+		reply, stat := pb.NewMathClient(sess).Add(&pb.AddArgs{Nums: []int32{1, 2, 3, 4, 5}})
+		t.Assert(stat.OK(), true)
+		t.Assert(reply.Sum, 15)
+
+		svr.RangeSession(func(s drpc.Session) bool {
+			t.Assert(pb.NewPushClient(s).Status(&pb.StatusArgs{Msg: "hello"}).OK(), true)
+			return false
+		})
+		select {
+		case msg := <-pushed:
+			t.Assert(msg, "hello")
+		case <-time.After(time.Second):
+			t.Fatal("push timeout")
+		}
+	})
+}
+
+// Calc 结构体名称和服务名称不一致，注册的路由和客户端不一致
+type Calc struct {
+	drpc.CallCtx
+}
+
+func (that *Calc) Add(_ *pb.AddArgs) (*pb.AddReply, *drpc.Status) {
+	return &pb.AddReply{}, nil
+}
+
+func TestRouteNameMismatch(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		svr := drpc.NewEndpoint(drpc.EndpointConfig{})
+		defer svr.Close()
+		defer func() {
+			t.AssertNE(recover(), nil)
+		}()
+		pb.RouteMathCall(svr.Router(), new(Calc))
+		t.Fatal("route name mismatch should panic")
+	})
+}
+
+// 修改了路由映射规则，注册的路由和生成的路由常量不一致
+func TestRouteMapperMismatch(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		drpc.SetServiceMethodMapper(drpc.RPCServiceMethodMapper)
+		defer drpc.SetServiceMethodMapper(drpc.HTTPServiceMethodMapper)
+		svr := drpc.NewEndpoint(drpc.EndpointConfig{})
+		defer svr.Close()
+		defer func() {
+			t.AssertNE(recover(), nil)
+		}()
+		pb.RouteMathCall(svr.Router(), new(Math))
+		t.Fatal("service method mapper mismatch should panic")
+	})
+}
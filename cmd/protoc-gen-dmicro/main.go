@@ -0,0 +1,44 @@
+// protoc-gen-dmicro 是protoc的插件，根据.proto文件中的service生成dmicro的服务端控制器接口和客户端代码。
+//
+// 安装:
+//
+//	go install github.com/osgochina/dmicro/cmd/protoc-gen-dmicro@latest
+//
+// 使用:
+//
+//	protoc --go_out=. --dmicro_out=. math.proto
+//
+// 每个.proto文件生成一个 xxx_dmicro.pb.go 文件，名称以Push结尾的service生成push控制器，其他service生成call控制器
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/osgochina/dmicro"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	if len(os.Args) == 2 && os.Args[1] == "--version" {
+		fmt.Fprintf(os.Stdout, "%v %v\n", filepath.Base(os.Args[0]), dmicro.Version)
+		os.Exit(0)
+	}
+	var flags flag.FlagSet
+	protogen.Options{
+		ParamFunc: flags.Set,
+	}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		for _, f := range gen.Files {
+			if !f.Generate {
+				continue
+			}
+			if _, err := generateFile(gen, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
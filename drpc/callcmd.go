@@ -49,6 +49,7 @@ type callCmd struct {
 	mu             sync.Mutex
 	callCmdChan    chan<- CallCmd
 	doneChan       chan struct{}
+	cancelCtx      context.CancelFunc // 释放请求的上下文
 	inputBodyCodec byte
 }
 
@@ -143,10 +144,50 @@ func (that *callCmd) done() {
 	that.sess.callCmdMap.Remove(that.output.Seq())
 	that.callCmdChan <- that
 	close(that.doneChan)
+	that.releaseContext()
 	// free count call-launch
 	that.sess.graceCallCmdWaitGroup.Done()
 }
 
+// 是否已经处理完成
+func (that *callCmd) isDone() bool {
+	select {
+	case <-that.doneChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// 释放请求的上下文
+func (that *callCmd) releaseContext() {
+	if that.cancelCtx != nil {
+		that.cancelCtx()
+	}
+}
+
+// 监听调用方的上下文，上下文结束时还没有收到响应，则放弃该请求
+func (that *callCmd) watchContext(ctxDone <-chan struct{}) {
+	select {
+	case <-that.doneChan:
+	case <-ctxDone:
+		that.abandon()
+	}
+}
+
+// 放弃请求，并通知对端取消处理
+func (that *callCmd) abandon() {
+	that.mu.Lock()
+	if that.isDone() {
+		that.mu.Unlock()
+		return
+	}
+	that.stat = statCallCanceled.Copy(that.output.Context().Err())
+	that.done()
+	that.mu.Unlock()
+	that.sess.writeCancel(that.output.Seq(), that.output.ServiceMethod())
+}
+
 // 取消请求
 func (that *callCmd) cancel(reason string) {
 	that.sess.callCmdMap.Remove(that.output.Seq())
@@ -157,6 +198,7 @@ func (that *callCmd) cancel(reason string) {
 	}
 	that.callCmdChan <- that
 	close(that.doneChan)
+	that.releaseContext()
 	// free count call-launch
 	that.sess.graceCallCmdWaitGroup.Done()
 }
@@ -1,6 +1,9 @@
 package codec
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 // Codec 消息内容的编解码器
 type Codec interface {
@@ -44,6 +47,18 @@ func GetByName(codecName string) (Codec, error) {
 	return codec, nil
 }
 
+// List 获取所有注册的编解码器，按照id排序
+func List() []Codec {
+	list := make([]Codec, 0, len(codecMap.idMap))
+	for _, codec := range codecMap.idMap {
+		list = append(list, codec)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID() < list[j].ID()
+	})
+	return list
+}
+
 // Marshal 使用指定编解码器编码
 func Marshal(codecID byte, v interface{}) ([]byte, error) {
 	codec, err := Get(codecID)
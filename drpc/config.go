@@ -53,6 +53,14 @@ type EndpointConfig struct {
 	//仅限客户端角色使用 试图链接服务端时候，重试的时间间隔.
 	RedialInterval time.Duration `json:"redial_interval" comment:"仅限客户端角色使用 试图链接服务端时候，重试的时间间隔."`
 
+	// 单个流在未收到对端确认之前，最多可以发送的消息帧数量
+	StreamWindow int `json:"stream_window" comment:"单个流在未收到对端确认之前，最多可以发送的消息帧数量"`
+
+	// 作为服务端角色时，端点同时处理的call和push请求的最大数量，0表示不限制
+	MaxInflight int `json:"max_inflight" comment:"作为服务端角色时，端点同时处理的call和push请求的最大数量，0表示不限制"`
+	// 达到最大并发数以后，允许排队等待处理的请求数量，队列已满时拒绝请求
+	MaxQueue int `json:"max_queue" comment:"达到最大并发数以后，允许排队等待处理的请求数量，队列已满时拒绝请求"`
+
 	//该配置是否已经初始化检查
 	checked bool
 }
@@ -124,6 +132,11 @@ func (that *EndpointConfig) check() (err error) {
 		that.RedialInterval = time.Millisecond * 100
 	}
 
+	//流控窗口大小，默认为64帧
+	if that.StreamWindow <= 0 {
+		that.StreamWindow = defaultStreamWindow
+	}
+
 	return nil
 }
 
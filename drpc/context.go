@@ -169,6 +169,7 @@ var (
 	_ CallCtx        = new(handlerCtx)
 	_ UnknownPushCtx = new(handlerCtx)
 	_ UnknownCallCtx = new(handlerCtx)
+	_ StreamCtx      = new(handlerCtx)
 )
 
 var emptyValue = reflect.Value{}
@@ -181,6 +182,7 @@ type handlerCtx struct {
 	handler         *Handler
 	arg             reflect.Value // 消息传入的参数
 	callCmd         *callCmd
+	stream          *stream
 	swap            *gmap.Map
 	start           int64
 	pluginContainer *PluginContainer
@@ -217,6 +219,7 @@ func (that *handlerCtx) clean() {
 	that.arg = emptyValue
 	that.swap = nil
 	that.callCmd = nil
+	that.stream = nil
 	that.pluginContainer = nil
 	that.stat = nil
 	that.context = nil
@@ -326,6 +329,20 @@ func (that *handlerCtx) setContext(ctx context.Context) {
 	that.context = ctx
 }
 
+// 调用方是否已经取消了该请求
+func (that *handlerCtx) callCanceled() bool {
+	return that.input.Context().Err() == context.Canceled
+}
+
+// 根据消息元数据中调用方剩余的超时时间，为parent设置截止时间
+func (that *handlerCtx) withMetaDeadline(parent context.Context) (context.Context, context.CancelFunc) {
+	remain := gconv.Int64(that.input.Meta().Get(message.MetaDeadline))
+	if remain <= 0 {
+		return parent, func() {}
+	}
+	return context.WithTimeout(parent, time.Duration(remain)*time.Millisecond)
+}
+
 // StatusOK 判断该上下文的状态是否是ok
 func (that *handlerCtx) StatusOK() bool {
 	return that.stat.OK()
@@ -393,6 +410,11 @@ func (that *handlerCtx) buildingBody(header message.Header) (body interface{}) {
 		return that.buildPushBody(header)
 	case message.TypeCall:
 		return that.buildCallBody(header)
+	case message.TypeStream:
+		return that.buildStreamBody(header)
+	case message.TypeCancel:
+		//取消消息没有消息体
+		return nil
 	default:
 		that.stat = statCodeMTypeNotAllowed
 		return nil
@@ -476,6 +498,22 @@ func (that *handlerCtx) buildPushBody(header message.Header) interface{} {
 	return that.input.Body()
 }
 
+// 根据消息头构建流消息体，流消息体统一使用原始字节，由接收方自行解码
+func (that *handlerCtx) buildStreamBody(header message.Header) interface{} {
+	//只有打开流的消息需要匹配处理程序
+	if gconv.String(header.Meta().Get(message.MetaStreamFrame)) == streamFrameOpen {
+		var ok bool
+		that.handler, ok = that.sess.getStreamHandler(header.ServiceMethod())
+		if !ok {
+			that.stat = statNotFound
+		} else {
+			that.pluginContainer = that.handler.pluginContainer
+		}
+	}
+	that.input.SetBody(new([]byte))
+	return that.input.Body()
+}
+
 //根据消息头构建Reply消息体
 func (that *handlerCtx) buildReplyBody(header message.Header) interface{} {
 
@@ -485,10 +523,15 @@ func (that *handlerCtx) buildReplyBody(header message.Header) interface{} {
 		internal.Warningf(that.context, "not found call cmd: %v", that.input)
 		return nil
 	}
-	that.callCmd = _callCmd.(*callCmd)
-
+	cmd := _callCmd.(*callCmd)
+	cmd.mu.Lock()
+	//调用方已经放弃了该请求，丢弃迟到的回复
+	if cmd.isDone() {
+		cmd.mu.Unlock()
+		return nil
+	}
 	// 在handleReply方法中解锁
-	that.callCmd.mu.Lock()
+	that.callCmd = cmd
 	//把收到的回复消息中待的服务名赋值给input消息对象，记录日志使用
 	that.input.SetServiceMethod(that.callCmd.output.ServiceMethod())
 	//回复中的交换数据
@@ -580,6 +623,11 @@ func (that *handlerCtx) handle() {
 		that.handleCall()
 		return
 
+	case message.TypeStream:
+		// handles stream
+		that.handleStream()
+		return
+
 	default:
 	}
 E:
@@ -596,12 +644,18 @@ E:
 //处理push消息
 func (that *handlerCtx) handlePush() {
 
+	//恢复调用方传递的截止时间
+	ctx, cancelDeadline := that.withMetaDeadline(context.Background())
+	defer cancelDeadline()
 	//判断push消息时候有处理时间限制
 	age := that.sess.ContextAge()
 	if age > 0 {
-		ctxTimout, cancel := context.WithTimeout(context.Background(), age)
+		ctxTimout, cancel := context.WithTimeout(ctx, age)
 		defer cancel()
-		that.setContext(ctxTimout)
+		ctx = ctxTimout
+	}
+	if ctx != context.Background() {
+		that.setContext(ctx)
 	}
 	defer func() {
 		if p := recover(); p != nil {
@@ -612,6 +666,14 @@ func (that *handlerCtx) handlePush() {
 			that.sess.printRunLog(that.RealIP(), that.CostTime(), that.input, nil, typePushHandle)
 		}
 	}()
+	//获取并发许可，超出限制的push消息直接丢弃
+	if that.stat.OK() && that.handler != nil {
+		release, stat := that.acquireLimit()
+		defer release()
+		if !stat.OK() {
+			that.stat = stat
+		}
+	}
 	//消息状态正确，且有注册的处理函数
 	if that.stat.OK() && that.handler != nil && that.pluginContainer.afterReadPushBody(that) == nil {
 		//执行处理事件
@@ -657,17 +719,37 @@ func (that *handlerCtx) handleCall() {
 	// 设置返回消息的管道处理器
 	that.output.PipeTFilter().AppendFrom(that.input.PipeTFilter())
 
+	//请求处理完毕，释放请求的上下文
+	defer that.sess.cancelHandlingCall(that.input.Seq())
+	//恢复调用方传递的截止时间
+	ctx, cancelDeadline := that.withMetaDeadline(that.input.Context())
+	defer cancelDeadline()
 	age := that.sess.ContextAge()
 	if age > 0 {
-		ctxTimout, cancel := context.WithTimeout(that.input.Context(), age)
+		ctxTimout, cancel := context.WithTimeout(ctx, age)
 		defer cancel()
+		ctx = ctxTimout
+	}
+	if ctx != that.input.Context() {
 		//为自己和响应消息设置生存周期
-		that.setContext(ctxTimout)
-		message.WithContext(ctxTimout)(that.output)
+		that.setContext(ctx)
+		message.WithContext(ctx)(that.output)
 	}
 	if that.stat.OK() {
 		that.stat = that.output.Status()
 	}
+	//调用方已经取消了该请求，不再执行处理程序
+	if that.stat.OK() && that.callCanceled() {
+		that.stat = statCallCanceled
+	}
+	//获取并发许可，队列已满或者排队期间已超过截止时间，不再执行处理程序
+	if that.stat.OK() {
+		release, stat := that.acquireLimit()
+		defer release()
+		if !stat.OK() {
+			that.stat = stat
+		}
+	}
 	if that.stat.OK() {
 		//触发事件
 		that.stat = that.pluginContainer.afterReadCallBody(that)
@@ -680,6 +762,10 @@ func (that *handlerCtx) handleCall() {
 			}
 		}
 	}
+	//调用方已经取消了该请求，不需要回复
+	if that.callCanceled() {
+		return
+	}
 	//响应
 	that.setReplyBodyCodec(!that.stat.OK()) //设置响应正文的编解码器，默认使用请求消息的正文编解码器
 	//触发事件
@@ -702,6 +788,52 @@ func (that *handlerCtx) handleCall() {
 	that.pluginContainer.afterWriteReply(that)
 }
 
+// 处理打开流的请求，处理程序返回以后关闭该流
+func (that *handlerCtx) handleStream() {
+	st := that.stream
+	if st == nil {
+		return
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			internal.Errorf(that.context, "panic:%v\n%s", p, status.PanicStackTrace())
+			if that.stat.OK() {
+				that.stat = statInternalServerError.Copy(p)
+			}
+		}
+		st.closeByAcceptor(that.stat)
+		//打印处理日志
+		if enablePrintRunLog() {
+			that.sess.printRunLog(that.RealIP(), that.CostTime(), that.input, nil, typeStreamHandle)
+		}
+	}()
+	if !that.stat.OK() {
+		return
+	}
+	that.setContext(st.ctx)
+	//回复初始窗口，表示已经接受该流
+	if stat := st.writeWindow(st.recvWindow); !stat.OK() {
+		that.stat = stat
+		return
+	}
+	that.handler.streamHandleFunc(that)
+}
+
+// Send 发送一条消息到流的发起方
+func (that *handlerCtx) Send(body interface{}) *Status {
+	return that.stream.Send(body)
+}
+
+// Recv 从流的发起方接收一条消息
+func (that *handlerCtx) Recv(body interface{}) *Status {
+	return that.stream.Recv(body)
+}
+
+// CloseSend 半关闭流
+func (that *handlerCtx) CloseSend() *Status {
+	return that.stream.CloseSend()
+}
+
 ////计算该请求处理消耗时间
 //func (that *handlerCtx) recordCost() {
 //	that.cost = that.CostTime()
@@ -0,0 +1,98 @@
+package drpc
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/drpc/message"
+	"testing"
+	"time"
+)
+
+var slowCanceled = make(chan struct{}, 1)
+
+type deadlineCall struct {
+	CallCtx
+}
+
+func (that *deadlineCall) Remain(_ *struct{}) (int64, *Status) {
+	deadline, ok := that.Context().Deadline()
+	if !ok {
+		return -1, nil
+	}
+	return int64(time.Until(deadline) / time.Millisecond), nil
+}
+
+func (that *deadlineCall) Slow(_ *struct{}) (bool, *Status) {
+	select {
+	case <-that.Context().Done():
+		slowCanceled <- struct{}{}
+		return false, nil
+	case <-time.After(3 * time.Second):
+		return true, nil
+	}
+}
+
+func TestDeadlinePropagation(t *testing.T) {
+	srv := NewEndpoint(EndpointConfig{ListenPort: 9191})
+	srv.RouteCall(new(deadlineCall))
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+	defer srv.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	cli := NewEndpoint(EndpointConfig{})
+	defer cli.Close()
+	sess, stat := cli.Dial("127.0.0.1:9191")
+	if !stat.OK() {
+		t.Fatal(stat)
+	}
+	gtest.C(t, func(t *gtest.T) {
+		var remain int64
+		stat = sess.Call("/deadline_call/remain", nil, &remain).Status()
+		t.Assert(stat.OK(), true)
+		t.Assert(remain, -1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		stat = sess.Call("/deadline_call/remain", nil, &remain, message.WithContext(ctx)).Status()
+		t.Assert(stat.OK(), true)
+		t.AssertGT(remain, 1000)
+		t.AssertLE(remain, 2000)
+	})
+}
+
+func TestCallCancel(t *testing.T) {
+	srv := NewEndpoint(EndpointConfig{ListenPort: 9192})
+	srv.RouteCall(new(deadlineCall))
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+	defer srv.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	cli := NewEndpoint(EndpointConfig{})
+	defer cli.Close()
+	sess, stat := cli.Dial("127.0.0.1:9192")
+	if !stat.OK() {
+		t.Fatal(stat)
+	}
+	gtest.C(t, func(t *gtest.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(200*time.Millisecond, cancel)
+		var ok bool
+		start := time.Now()
+		stat = sess.Call("/deadline_call/slow", nil, &ok, message.WithContext(ctx)).Status()
+		t.Assert(stat.Code(), CodeCallCanceled)
+		t.Assert(time.Since(start) < time.Second, true)
+		select {
+		case <-slowCanceled:
+		case <-time.After(time.Second):
+			t.Fatal("handler did not observe the cancellation")
+		}
+		// 取消之后会话依然可用
+		var remain int64
+		stat = sess.Call("/deadline_call/remain", nil, &remain).Status()
+		t.Assert(stat.OK(), true)
+	})
+}
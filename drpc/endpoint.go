@@ -67,6 +67,8 @@ type EarlyEndpoint interface {
 	RoutePush(ctrlStruct interface{}, plugin ...Plugin) []string
 	// RoutePushFunc 通过func注册PUSH类型的处理程序，并且返回单个注册路径
 	RoutePushFunc(pushHandleFunc interface{}, plugin ...Plugin) string
+	// RouteStreamFunc 通过func注册STREAM类型的处理程序，并且返回单个注册路径
+	RouteStreamFunc(streamHandleFunc interface{}, plugin ...Plugin) string
 	// SetUnknownCall 设置默认处理程序，当没有找到CALL的处理程序时将调用该处理程序。
 	SetUnknownCall(fn func(UnknownCallCtx) (interface{}, *status.Status), plugin ...Plugin)
 	// SetUnknownPush 设置默认处理程序，当没有找到PUSH的处理程序时将调用该处理程序。
@@ -110,6 +112,8 @@ type endpoint struct {
 	network           string
 	defaultBodyCodec  byte
 	printDetail       bool
+	streamWindow      int32
+	limiter           *limiter
 
 	//只有作为server角色时候才有该对象
 	listerAddr net.Addr
@@ -143,6 +147,8 @@ func NewEndpoint(cfg EndpointConfig, globalLeftPlugin ...Plugin) Endpoint {
 		network:           cfg.Network,
 		listerAddr:        cfg.listenAddr,
 		printDetail:       cfg.PrintDetail,
+		streamWindow:      int32(cfg.StreamWindow),
+		limiter:           newLimiter(cfg.MaxInflight, cfg.MaxQueue),
 		listeners:         make(map[net.Listener]struct{}),
 		dialer: &Dialer{
 			network:        cfg.Network,
@@ -261,6 +267,11 @@ func (that *endpoint) RoutePushFunc(pushHandleFunc interface{}, plugin ...Plugin
 	return that.router.RoutePushFunc(pushHandleFunc, plugin...)
 }
 
+// RouteStreamFunc 通过对象的方法注册STREAM命令的路由
+func (that *endpoint) RouteStreamFunc(streamHandleFunc interface{}, plugin ...Plugin) string {
+	return that.router.RouteStreamFunc(streamHandleFunc, plugin...)
+}
+
 // SetUnknownCall 设置CALL命令的默认路由
 func (that *endpoint) SetUnknownCall(fn func(UnknownCallCtx) (interface{}, *Status), plugin ...Plugin) {
 	that.router.SetUnknownCall(fn, plugin...)
@@ -18,6 +18,9 @@ type Handler struct {
 	// 不能匹配到绑定方法时，默认的处理方法
 	unknownHandleFunc func(*handlerCtx)
 
+	// 处理流消息的方法
+	streamHandleFunc func(*handlerCtx)
+
 	pluginContainer *PluginContainer
 
 	// 路由类型名字
@@ -25,9 +28,12 @@ type Handler struct {
 
 	//是否找到绑定方法
 	isUnknown bool
+
+	// 路由级别的并发限制器，未设置时为nil
+	limiter *limiter
 }
 
-// RouterTypeName 获取处理器的路由方法名 pnPush/pnCall/pnUnknownPush/pnUnknownCall
+// RouterTypeName 获取处理器的路由方法名 pnPush/pnCall/pnUnknownPush/pnUnknownCall/pnStream
 func (that *Handler) RouterTypeName() string {
 	return that.routerTypeName
 }
@@ -62,6 +68,11 @@ func (that *Handler) IsPush() bool {
 	return that.routerTypeName == pnPush || that.routerTypeName == pnUnknownPush
 }
 
+// IsStream 处理程序是否是STREAM
+func (that *Handler) IsStream() bool {
+	return that.routerTypeName == pnStream
+}
+
 // IsUnknown 处理程序是否未找到
 func (that *Handler) IsUnknown() bool {
 	return that.isUnknown
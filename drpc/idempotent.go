@@ -0,0 +1,20 @@
+package drpc
+
+// 幂等标记插件的名称
+const idempotentPluginName = "idempotent"
+
+// Idempotent 幂等标记，注册路由时作为插件传入，表示该路由可以被客户端安全地重试，
+// 例如 svr.RouteCall(new(Math), drpc.Idempotent)
+var Idempotent Plugin = idempotentPlugin{}
+
+type idempotentPlugin struct{}
+
+// Name 插件名称
+func (idempotentPlugin) Name() string {
+	return idempotentPluginName
+}
+
+// IsIdempotent 处理程序注册时是否标记为幂等
+func (that *Handler) IsIdempotent() bool {
+	return that.pluginContainer != nil && that.pluginContainer.GetByName(idempotentPluginName) != nil
+}
@@ -0,0 +1,161 @@
+package drpc
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// 并发限制插件的名称
+const concurrencyLimitPluginName = "concurrency_limit"
+
+// ConcurrencyLimit 路由级别的并发限制，注册路由时作为插件传入，
+// 每个处理程序最多同时执行maxInflight个请求，超出的请求最多排队等待maxQueue个，队列已满时直接返回 CodeOverloaded，
+// 例如 svr.RouteCall(new(Math), drpc.ConcurrencyLimit(100, 1000))
+func ConcurrencyLimit(maxInflight, maxQueue int) Plugin {
+	return &concurrencyLimitPlugin{maxInflight: maxInflight, maxQueue: maxQueue}
+}
+
+type concurrencyLimitPlugin struct {
+	maxInflight int
+	maxQueue    int
+}
+
+// Name 插件名称
+func (that *concurrencyLimitPlugin) Name() string {
+	return concurrencyLimitPluginName
+}
+
+// 根据路由注册时传入的并发限制插件，为处理程序创建限制器
+func newRouteLimiter(pluginContainer *PluginContainer) *limiter {
+	if pluginContainer == nil {
+		return nil
+	}
+	p, ok := pluginContainer.GetByName(concurrencyLimitPluginName).(*concurrencyLimitPlugin)
+	if !ok {
+		return nil
+	}
+	return newLimiter(p.maxInflight, p.maxQueue)
+}
+
+// 并发限制器，限制同时执行的请求数，超出的请求进入有界的等待队列
+type limiter struct {
+	//正在执行的请求占用的许可
+	sem chan struct{}
+	//等待队列的最大长度
+	maxQueue int32
+	//当前正在排队的请求数
+	waiting int32
+}
+
+// 创建并发限制器，maxInflight小于等于0表示不限制，返回nil
+func newLimiter(maxInflight, maxQueue int) *limiter {
+	if maxInflight <= 0 {
+		return nil
+	}
+	if maxQueue < 0 {
+		maxQueue = 0
+	}
+	return &limiter{
+		sem:      make(chan struct{}, maxInflight),
+		maxQueue: int32(maxQueue),
+	}
+}
+
+// 获取执行许可，没有空闲许可时在队列中等待，队列已满或者等待期间ctx结束则返回错误状态
+func (that *limiter) acquire(ctx context.Context) *Status {
+	if that == nil {
+		return nil
+	}
+	select {
+	case that.sem <- struct{}{}:
+		return that.checkDeadline(ctx)
+	default:
+	}
+	if atomic.AddInt32(&that.waiting, 1) > that.maxQueue {
+		atomic.AddInt32(&that.waiting, -1)
+		return statOverloaded.Copy("too many requests in flight")
+	}
+	defer atomic.AddInt32(&that.waiting, -1)
+	select {
+	case that.sem <- struct{}{}:
+		return that.checkDeadline(ctx)
+	case <-ctx.Done():
+		return limitCtxStatus(ctx)
+	}
+}
+
+// 拿到许可时请求已经过了截止时间，则归还许可，处理程序不再执行
+func (that *limiter) checkDeadline(ctx context.Context) *Status {
+	if ctx.Err() == nil {
+		return nil
+	}
+	that.release()
+	return limitCtxStatus(ctx)
+}
+
+// 归还执行许可
+func (that *limiter) release() {
+	if that == nil {
+		return
+	}
+	<-that.sem
+}
+
+// 正在执行的请求数
+func (that *limiter) inflight() int {
+	if that == nil {
+		return 0
+	}
+	return len(that.sem)
+}
+
+// 在队列中等待的请求数
+func (that *limiter) queued() int {
+	if that == nil {
+		return 0
+	}
+	return int(atomic.LoadInt32(&that.waiting))
+}
+
+// 请求在排队期间结束时的状态
+func limitCtxStatus(ctx context.Context) *Status {
+	if ctx.Err() == context.Canceled {
+		return statCallCanceled.Copy(ctx.Err())
+	}
+	return statHandleTimeout.Copy("request expired while waiting in queue")
+}
+
+// 依次获取路由、端点以及并发限制插件的执行许可，返回的函数用于归还已获取的许可
+// 先获取路由许可，避免排队等待路由许可的请求占用端点许可
+func (that *handlerCtx) acquireLimit() (func(), *Status) {
+	var route *limiter
+	if that.handler != nil {
+		route = that.handler.limiter
+	}
+	endpoint := that.sess.endpoint.limiter
+	if route == nil && endpoint == nil {
+		return that.pluginContainer.acquire(that)
+	}
+	ctx := that.Context()
+	if stat := route.acquire(ctx); !stat.OK() {
+		return releaseNothing, stat
+	}
+	if stat := endpoint.acquire(ctx); !stat.OK() {
+		route.release()
+		return releaseNothing, stat
+	}
+	release, stat := that.pluginContainer.acquire(that)
+	if !stat.OK() {
+		endpoint.release()
+		route.release()
+		return releaseNothing, stat
+	}
+	return func() {
+		release()
+		endpoint.release()
+		route.release()
+	}, nil
+}
+
+// 没有获取任何许可时返回的函数
+func releaseNothing() {}
@@ -0,0 +1,208 @@
+package drpc
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/drpc/message"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	limitStarted = make(chan struct{}, 10)
+	limitRelease = make(chan struct{})
+	limitRuns    int32
+)
+
+type limitCall struct {
+	CallCtx
+}
+
+func (that *limitCall) Block(_ *struct{}) (bool, *Status) {
+	atomic.AddInt32(&limitRuns, 1)
+	limitStarted <- struct{}{}
+	<-limitRelease
+	return true, nil
+}
+
+func TestLimiter(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		t.Assert(newLimiter(0, 10) == nil, true)
+		l := newLimiter(1, 1)
+		t.Assert(l.acquire(context.Background()).OK(), true)
+		t.Assert(l.inflight(), 1)
+
+		queued := make(chan *Status)
+		go func() {
+			queued <- l.acquire(context.Background())
+		}()
+		time.Sleep(100 * time.Millisecond)
+		t.Assert(l.queued(), 1)
+		stat := l.acquire(context.Background())
+		t.Assert(stat.Code(), CodeOverloaded)
+		t.Assert(IsOverloaded(stat), true)
+
+		l.release()
+		t.Assert((<-queued).OK(), true)
+		t.Assert(l.queued(), 0)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		t.Assert(l.acquire(ctx).Code(), CodeHandleTimeout)
+		l.release()
+		t.Assert(l.inflight(), 0)
+
+		// 已经过了截止时间的请求即使有空闲许可也不再执行
+		t.Assert(l.acquire(ctx).Code(), CodeHandleTimeout)
+		t.Assert(l.inflight(), 0)
+	})
+}
+
+// 记录许可获取和归还顺序的并发限制插件
+type testLimiterPlugin struct {
+	name   string
+	reject bool
+	events *[]string
+}
+
+func (that *testLimiterPlugin) Name() string {
+	return that.name
+}
+
+func (that *testLimiterPlugin) Acquire(_ ReadCtx) (func(), *Status) {
+	if that.reject {
+		return nil, statOverloaded
+	}
+	*that.events = append(*that.events, "acquire "+that.name)
+	return func() {
+		*that.events = append(*that.events, "release "+that.name)
+	}, nil
+}
+
+func TestAcquireLimit(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		var events []string
+		ctx := &handlerCtx{
+			sess:            &session{endpoint: &endpoint{}},
+			pluginContainer: newPluginContainer(),
+		}
+		// 没有任何限制时不分配内存
+		allocs := testing.AllocsPerRun(100, func() {
+			release, _ := ctx.acquireLimit()
+			release()
+		})
+		t.Assert(allocs, 0)
+
+		ctx.pluginContainer.AppendRight(
+			&testLimiterPlugin{name: "a", events: &events},
+			&testLimiterPlugin{name: "b", events: &events},
+		)
+		release, stat := ctx.acquireLimit()
+		t.Assert(stat.OK(), true)
+		release()
+		t.Assert(events, []string{"acquire a", "acquire b", "release b", "release a"})
+
+		// 被拒绝时归还已经获取的许可
+		events = nil
+		ctx.pluginContainer.AppendRight(&testLimiterPlugin{name: "c", reject: true, events: &events})
+		_, stat = ctx.acquireLimit()
+		t.Assert(stat.Code(), CodeOverloaded)
+		t.Assert(events, []string{"acquire a", "acquire b", "release b", "release a"})
+	})
+}
+
+func TestRouteConcurrencyLimit(t *testing.T) {
+	srv := NewEndpoint(EndpointConfig{ListenPort: 9211})
+	srv.RouteCall(new(limitCall), ConcurrencyLimit(1, 1))
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+	defer srv.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	cli := NewEndpoint(EndpointConfig{})
+	defer cli.Close()
+	sess, stat := cli.Dial("127.0.0.1:9211")
+	if !stat.OK() {
+		t.Fatal(stat)
+	}
+	gtest.C(t, func(t *gtest.T) {
+		atomic.StoreInt32(&limitRuns, 0)
+		limitRelease = make(chan struct{})
+		var wg sync.WaitGroup
+		var results = make([]*Status, 2)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				var ok bool
+				results[i] = sess.Call("/limit_call/block", nil, &ok).Status()
+			}(i)
+			if i == 0 {
+				<-limitStarted
+			}
+		}
+		time.Sleep(200 * time.Millisecond)
+
+		// 一个请求正在执行，一个请求在排队，再来的请求被拒绝
+		var ok bool
+		stat := sess.Call("/limit_call/block", nil, &ok).Status()
+		t.Assert(stat.Code(), CodeOverloaded)
+
+		close(limitRelease)
+		wg.Wait()
+		t.Assert(results[0].OK(), true)
+		t.Assert(results[1].OK(), true)
+		t.Assert(atomic.LoadInt32(&limitRuns), 2)
+		<-limitStarted
+	})
+}
+
+func TestEndpointConcurrencyLimit(t *testing.T) {
+	srv := NewEndpoint(EndpointConfig{ListenPort: 9212, MaxInflight: 1, MaxQueue: 1})
+	srv.RouteCall(new(limitCall))
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+	defer srv.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	cli := NewEndpoint(EndpointConfig{})
+	defer cli.Close()
+	sess, stat := cli.Dial("127.0.0.1:9212")
+	if !stat.OK() {
+		t.Fatal(stat)
+	}
+	gtest.C(t, func(t *gtest.T) {
+		atomic.StoreInt32(&limitRuns, 0)
+		limitRelease = make(chan struct{})
+		done := make(chan *Status)
+		go func() {
+			var ok bool
+			done <- sess.Call("/limit_call/block", nil, &ok).Status()
+		}()
+		<-limitStarted
+
+		// 排队期间调用方的截止时间已过，处理程序不再执行
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		var ok bool
+		stat := sess.Call("/limit_call/block", nil, &ok, message.WithContext(ctx)).Status()
+		t.Assert(stat.OK(), false)
+		// 服务端根据收到请求时剩余的超时时间重新计算截止时间，晚于调用方超时，
+		// 等待服务端丢弃排队的请求以后再释放许可
+		l := srv.(*endpoint).limiter
+		deadline := time.Now().Add(2 * time.Second)
+		for l.queued() > 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Assert(l.queued(), 0)
+
+		close(limitRelease)
+		t.Assert((<-done).OK(), true)
+		time.Sleep(200 * time.Millisecond)
+		t.Assert(atomic.LoadInt32(&limitRuns), 1)
+	})
+}
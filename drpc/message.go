@@ -11,6 +11,7 @@ var (
 
 	MetaRealIP          = message.MetaRealIP
 	MetaAcceptBodyCodec = message.MetaAcceptBodyCodec
+	MetaDeadline        = message.MetaDeadline
 
 	TypeUndefined = message.TypeUndefined
 	TypeCall      = message.TypeCall
@@ -18,6 +19,8 @@ var (
 	TypePush      = message.TypePush
 	TypeAuthCall  = message.TypeAuthCall
 	TypeAuthReply = message.TypeAuthReply
+	TypeStream    = message.TypeStream
+	TypeCancel    = message.TypeCancel
 )
 
 var (
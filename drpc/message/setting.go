@@ -17,6 +17,8 @@ const (
 	TypePush      byte = 3
 	TypeAuthCall  byte = 4
 	TypeAuthReply byte = 5
+	TypeStream    byte = 6 // stream frame
+	TypeCancel    byte = 7 // cancel call
 )
 
 func TypeText(typ byte) string {
@@ -31,6 +33,10 @@ func TypeText(typ byte) string {
 		return "AUTH_CALL"
 	case TypeAuthReply:
 		return "AUTH_REPLY"
+	case TypeStream:
+		return "STREAM"
+	case TypeCancel:
+		return "CANCEL"
 	default:
 		return "Undefined"
 	}
@@ -41,6 +47,18 @@ const (
 	MetaRealIP = "X-Real-IP"
 	// MetaAcceptBodyCodec the key of body codec that the sender wishes to accept
 	MetaAcceptBodyCodec = "X-Accept-Body-Codec"
+	// MetaStreamFrame 流消息的帧类型
+	MetaStreamFrame = "X-Stream-Frame"
+	// MetaStreamSide 流消息的发送方，o表示流的发起方，a表示流的接收方
+	MetaStreamSide = "X-Stream-Side"
+	// MetaStreamSeq 流消息在单个流内部的序列号
+	MetaStreamSeq = "X-Stream-Seq"
+	// MetaStreamWindow 流控窗口的大小
+	MetaStreamWindow = "X-Stream-Window"
+	// MetaDeadline 调用方剩余的超时时间，单位毫秒，接收方据此恢复处理上下文的截止时间
+	MetaDeadline = "X-Deadline"
+	// MetaRetryAfter 请求被限流时，建议调用方等待多久以后再重试，单位毫秒
+	MetaRetryAfter = "X-Retry-After"
 )
 
 var (
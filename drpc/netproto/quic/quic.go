@@ -14,6 +14,24 @@ type Listener struct {
 
 var _ net.Listener = new(Listener)
 
+// quic要求双方协商出相同的应用层协议，证书信息中没有该协议时自动添加
+const defaultNextProto = "drpc"
+
+// 证书信息中没有默认的应用层协议时，复制一份并添加
+func withNextProtos(tlsConf *tls.Config) *tls.Config {
+	if tlsConf == nil {
+		return nil
+	}
+	for _, p := range tlsConf.NextProtos {
+		if p == defaultNextProto {
+			return tlsConf
+		}
+	}
+	tlsConf = tlsConf.Clone()
+	tlsConf.NextProtos = append(tlsConf.NextProtos, defaultNextProto)
+	return tlsConf
+}
+
 // DialAddrContext 使用quic协议链接远端
 // ctx: 上下文
 // network: 网络类型,可选："udp", "udp4", "udp6"
@@ -37,7 +55,7 @@ func DialAddrContext(ctx context.Context, network string, laddr *net.UDPAddr, ra
 	if err != nil {
 		return nil, err
 	}
-	sess, err := quic.DialContext(ctx, udpConn, udpAddr, raddr, tlsConf, config)
+	sess, err := quic.DialContext(ctx, udpConn, udpAddr, raddr, withNextProtos(tlsConf), config)
 	if err != nil {
 		return nil, err
 	}
@@ -85,7 +103,7 @@ func Listen(conn net.PacketConn, tlsConf *tls.Config, config *quic.Config) (*Lis
 	if config == nil {
 		config = &quic.Config{}
 	}
-	lis, err := quic.Listen(conn, tlsConf, config)
+	lis, err := quic.Listen(conn, withNextProtos(tlsConf), config)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,184 @@
+package adaptivelimit
+
+import (
+	"context"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/message"
+	"github.com/osgochina/dmicro/eventbus"
+	"github.com/osgochina/dmicro/logger"
+	"github.com/osgochina/dmicro/metrics"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OnWindowEvent 采样周期结束的事件名称，限制发生变化或者周期内有请求被拒绝时发布，事件参数的key见 metrics.LimiterKeyName 等常量
+const OnWindowEvent = metrics.OnLimiterWindowEvent
+
+// WindowStats 采样周期结束事件
+type WindowStats struct {
+	*eventbus.Event
+	// 限制器名称
+	Limiter string
+	// 周期开始时的限制
+	From int
+	// 重新计算后的限制
+	To int
+	// 周期内被拒绝的请求数
+	Rejected int64
+}
+
+// AdaptiveLimit 自适应并发限制插件
+// 根据处理程序的耗时，使用梯度算法自动调整允许同时处理的call请求数：
+// 短期延迟接近长期延迟时逐步提高限制，短期延迟明显升高时按比例降低限制，超出限制的请求直接返回 drpc.CodeOverloaded
+type AdaptiveLimit struct {
+	// 当前采样周期内被拒绝的请求数，拒绝时不加锁，周期结束时随事件一起发布
+	rejected int64
+	opts     Options
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	// 长期延迟，毫秒
+	longRtt float64
+	// 当前采样周期的统计
+	windowEnd   time.Time
+	windowSum   time.Duration
+	windowCount int
+	maxInflight int
+	// 是否已经发布过限制
+	published bool
+}
+
+var _ drpc.LimiterPlugin = new(AdaptiveLimit)
+
+// NewAdaptiveLimit 创建自适应并发限制插件
+func NewAdaptiveLimit(opts ...Option) *AdaptiveLimit {
+	o := NewOptions(opts...)
+	return &AdaptiveLimit{
+		opts:  o,
+		limit: float64(o.InitialLimit),
+	}
+}
+
+// Name 插件名称
+func (that *AdaptiveLimit) Name() string {
+	return "adaptive_limit(" + that.opts.Name + ")"
+}
+
+// Acquire 处理call请求之前获取执行许可，正在处理的请求数达到限制时拒绝请求
+func (that *AdaptiveLimit) Acquire(ctx drpc.ReadCtx) (func(), *drpc.Status) {
+	if ctx.Input().MType() != message.TypeCall {
+		return nil, nil
+	}
+	that.mu.Lock()
+	limit := int(that.limit)
+	if that.inflight >= limit {
+		that.mu.Unlock()
+		atomic.AddInt64(&that.rejected, 1)
+		return nil, drpc.NewStatusByCodeText(drpc.CodeOverloaded, "adaptive concurrency limit "+that.opts.Name+" exceeded", false)
+	}
+	that.inflight++
+	if that.inflight > that.maxInflight {
+		that.maxInflight = that.inflight
+	}
+	that.mu.Unlock()
+	return func() {
+		that.onRelease(ctx.CostTime(), time.Now())
+	}, nil
+}
+
+// Limit 当前的并发限制
+func (that *AdaptiveLimit) Limit() int {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	return int(that.limit)
+}
+
+// Inflight 正在处理的请求数
+func (that *AdaptiveLimit) Inflight() int {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	return that.inflight
+}
+
+// 请求处理完毕，记录耗时，采样周期结束时重新计算限制
+func (that *AdaptiveLimit) onRelease(rtt time.Duration, now time.Time) {
+	that.mu.Lock()
+	that.inflight--
+	that.windowSum += rtt
+	that.windowCount++
+	if that.windowEnd.IsZero() {
+		that.windowEnd = now.Add(that.opts.Window)
+	}
+	if now.Before(that.windowEnd) || that.windowCount < that.opts.MinSamples {
+		that.mu.Unlock()
+		return
+	}
+	from := int(that.limit)
+	shortRtt := float64(that.windowSum) / float64(that.windowCount) / float64(time.Millisecond)
+	that.update(shortRtt)
+	that.windowEnd = now.Add(that.opts.Window)
+	that.windowSum = 0
+	that.windowCount = 0
+	that.maxInflight = that.inflight
+	to := int(that.limit)
+	rejected := atomic.SwapInt64(&that.rejected, 0)
+	changed := from != to || !that.published || rejected > 0
+	that.published = true
+	that.mu.Unlock()
+
+	if changed {
+		that.publish(&WindowStats{
+			Event: eventbus.NewEvent(OnWindowEvent, map[interface{}]interface{}{
+				metrics.LimiterKeyName:     that.opts.Name,
+				metrics.LimiterKeyLimit:    to,
+				metrics.LimiterKeyRejected: rejected,
+			}),
+			Limiter:  that.opts.Name,
+			From:     from,
+			To:       to,
+			Rejected: rejected,
+		})
+	}
+}
+
+// 根据采样周期的平均延迟调整限制
+func (that *AdaptiveLimit) update(shortRtt float64) {
+	if shortRtt <= 0 {
+		shortRtt = math.SmallestNonzeroFloat64
+	}
+	if that.longRtt == 0 {
+		that.longRtt = shortRtt
+	} else {
+		that.longRtt += (shortRtt - that.longRtt) / float64(that.opts.LongWindow)
+	}
+	// 长期延迟远高于短期延迟，说明负载已经下降，让长期延迟更快地回落
+	if that.longRtt/shortRtt > 2 {
+		that.longRtt *= 0.95
+	}
+	// 请求量不足以用满当前限制，延迟不能反映限制是否合适，不提高限制
+	if float64(that.maxInflight) < that.limit/2 {
+		return
+	}
+	gradient := math.Max(0.5, math.Min(1.0, that.opts.Tolerance*that.longRtt/shortRtt))
+	newLimit := that.limit*gradient + float64(that.opts.QueueSize)
+	newLimit = that.limit*(1-that.opts.Smoothing) + newLimit*that.opts.Smoothing
+	newLimit = math.Max(float64(that.opts.MinLimit), math.Min(float64(that.opts.MaxLimit), newLimit))
+	that.limit = newLimit
+}
+
+// 发布事件
+func (that *AdaptiveLimit) publish(e eventbus.IEvent) {
+	var err error
+	if that.opts.EventBus != nil {
+		if that.opts.EventBus.HasListeners(e.Name()) {
+			err = that.opts.EventBus.Publish(e)
+		}
+	} else if eventbus.HasListeners(e.Name()) {
+		err = eventbus.Publish(e)
+	}
+	if err != nil {
+		logger.Warning(context.TODO(), err)
+	}
+}
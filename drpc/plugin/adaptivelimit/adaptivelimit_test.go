@@ -0,0 +1,153 @@
+package adaptivelimit
+
+import (
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/eventbus"
+	"github.com/osgochina/dmicro/metrics"
+	"testing"
+	"time"
+)
+
+// 模拟一个采样周期：同时处理n个请求，每个请求耗时rtt
+func simulate(l *AdaptiveLimit, n int, rtt time.Duration, now time.Time) {
+	l.mu.Lock()
+	l.inflight += n
+	if l.inflight > l.maxInflight {
+		l.maxInflight = l.inflight
+	}
+	l.mu.Unlock()
+	for i := 0; i < n; i++ {
+		l.onRelease(rtt, now)
+	}
+}
+
+func TestAdaptiveLimit_Gradient(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		l := NewAdaptiveLimit(OptInitialLimit(20), OptLimitRange(5, 100), OptWindow(time.Second, 10))
+		now := time.Now()
+		simulate(l, 20, 10*time.Millisecond, now)
+		now = now.Add(time.Second)
+
+		// 延迟稳定并且用满了限制，限制逐步提高
+		for i := 0; i < 10; i++ {
+			simulate(l, l.Limit(), 10*time.Millisecond, now)
+			now = now.Add(time.Second)
+		}
+		grown := l.Limit()
+		t.AssertGT(grown, 20)
+		t.AssertLE(grown, 100)
+
+		// 延迟明显升高，限制降低
+		for i := 0; i < 10; i++ {
+			simulate(l, l.Limit(), 100*time.Millisecond, now)
+			now = now.Add(time.Second)
+		}
+		t.AssertLT(l.Limit(), grown)
+		t.AssertGE(l.Limit(), 5)
+		t.Assert(l.Inflight(), 0)
+	})
+}
+
+func TestAdaptiveLimit_AppLimited(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		l := NewAdaptiveLimit(OptInitialLimit(50), OptWindow(time.Second, 10))
+		now := time.Now()
+		// 请求量远小于限制时，限制不提高
+		for i := 0; i < 10; i++ {
+			simulate(l, 10, 10*time.Millisecond, now)
+			now = now.Add(time.Second)
+		}
+		t.Assert(l.Limit(), 50)
+	})
+}
+
+func TestAdaptiveLimit_MinSamples(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		bus := eventbus.New("adaptive_limit_test")
+		var changes []*WindowStats
+		_ = bus.Listen(OnWindowEvent, eventbus.ListenerFunc(func(e eventbus.IEvent) error {
+			changes = append(changes, e.(*WindowStats))
+			return nil
+		}))
+		l := NewAdaptiveLimit(OptName("min"), OptInitialLimit(4), OptWindow(time.Second, 10), OptEventBus(bus))
+		now := time.Now()
+		simulate(l, 4, 10*time.Millisecond, now)
+		now = now.Add(2 * time.Second)
+		// 样本不足，不调整限制
+		simulate(l, 4, 10*time.Millisecond, now)
+		t.Assert(len(changes), 0)
+		simulate(l, 4, 10*time.Millisecond, now)
+		t.Assert(len(changes), 1)
+		t.Assert(changes[0].Limiter, "min")
+		t.Assert(changes[0].From, 4)
+		t.Assert(changes[0].To, l.Limit())
+	})
+}
+
+var blockRelease = make(chan struct{})
+
+type limitCall struct {
+	drpc.CallCtx
+}
+
+func (that *limitCall) Block(_ *struct{}) (bool, *drpc.Status) {
+	<-blockRelease
+	return true, nil
+}
+
+func TestAdaptiveLimit_Reject(t *testing.T) {
+	bus := eventbus.New("adaptive_limit_reject_test")
+	windows := make(chan *WindowStats, 1)
+	_ = bus.Listen(OnWindowEvent, eventbus.ListenerFunc(func(e eventbus.IEvent) error {
+		windows <- e.(*WindowStats)
+		return nil
+	}))
+	plugin := NewAdaptiveLimit(OptInitialLimit(1), OptLimitRange(1, 1), OptWindow(50*time.Millisecond, 1), OptEventBus(bus))
+	srv := drpc.NewEndpoint(drpc.EndpointConfig{ListenPort: 9213})
+	srv.RouteCall(new(limitCall), plugin)
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+	defer srv.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	cli := drpc.NewEndpoint(drpc.EndpointConfig{})
+	defer cli.Close()
+	sess, stat := cli.Dial("127.0.0.1:9213")
+	if !stat.OK() {
+		t.Fatal(stat)
+	}
+	gtest.C(t, func(t *gtest.T) {
+		done := make(chan *drpc.Status)
+		go func() {
+			var ok bool
+			done <- sess.Call("/limit_call/block", nil, &ok).Status()
+		}()
+		time.Sleep(200 * time.Millisecond)
+		t.Assert(plugin.Inflight(), 1)
+
+		var ok bool
+		stat := sess.Call("/limit_call/block", nil, &ok).Status()
+		t.Assert(stat.Code(), drpc.CodeOverloaded)
+		t.Assert(sess.Call("/limit_call/block", nil, &ok).Status().Code(), drpc.CodeOverloaded)
+
+		close(blockRelease)
+		t.Assert((<-done).OK(), true)
+		// 回复写入以后才归还许可
+		time.Sleep(100 * time.Millisecond)
+		t.Assert(plugin.Inflight(), 0)
+
+		// 被拒绝的请求数在采样周期结束时随事件一起发布
+		t.Assert(sess.Call("/limit_call/block", nil, &ok).Status().OK(), true)
+		select {
+		case w := <-windows:
+			t.Assert(w.Limiter, plugin.opts.Name)
+			t.Assert(w.To, 1)
+			t.Assert(w.Rejected, 2)
+			t.Assert(w.Get(metrics.LimiterKeyRejected), 2)
+		case <-time.After(3 * time.Second):
+			t.Fatal("window event not published")
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package adaptivelimit
+
+import (
+	"github.com/osgochina/dmicro/eventbus"
+	"time"
+)
+
+// 默认配置
+const (
+	defaultName         = "default"
+	defaultInitialLimit = 20
+	defaultMinLimit     = 1
+	defaultMaxLimit     = 1000
+	defaultSmoothing    = 0.2
+	defaultTolerance    = 1.5
+	defaultQueueSize    = 4
+	defaultLongWindow   = 600
+	defaultWindow       = time.Second
+	defaultMinSamples   = 10
+)
+
+// Options 自适应并发限制的配置
+type Options struct {
+	// 限制器名称，用于区分指标和事件
+	Name string
+	// 初始的并发限制
+	InitialLimit int
+	// 并发限制的下限
+	MinLimit int
+	// 并发限制的上限
+	MaxLimit int
+	// 新计算出的限制所占的权重，取值(0,1]，越大调整越快
+	Smoothing float64
+	// 短期延迟超过长期延迟多少倍后才开始降低限制
+	Tolerance float64
+	// 延迟没有增长时，每个采样周期允许增加的并发数
+	QueueSize int
+	// 长期延迟的平滑周期数，越大长期延迟越稳定
+	LongWindow int
+	// 采样周期
+	Window time.Duration
+	// 每个采样周期至少需要的样本数，样本不足时不调整限制
+	MinSamples int
+	// 限制变化和拒绝事件发布到的事件总线，为空则使用默认事件总线
+	EventBus *eventbus.EventBus
+}
+
+// Option 配置项
+type Option func(*Options)
+
+// NewOptions 初始化配置
+func NewOptions(opts ...Option) Options {
+	o := Options{
+		Name:         defaultName,
+		InitialLimit: defaultInitialLimit,
+		MinLimit:     defaultMinLimit,
+		MaxLimit:     defaultMaxLimit,
+		Smoothing:    defaultSmoothing,
+		Tolerance:    defaultTolerance,
+		QueueSize:    defaultQueueSize,
+		LongWindow:   defaultLongWindow,
+		Window:       defaultWindow,
+		MinSamples:   defaultMinSamples,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.MinLimit < 1 {
+		o.MinLimit = 1
+	}
+	if o.MaxLimit < o.MinLimit {
+		o.MaxLimit = o.MinLimit
+	}
+	if o.InitialLimit < o.MinLimit {
+		o.InitialLimit = o.MinLimit
+	}
+	if o.InitialLimit > o.MaxLimit {
+		o.InitialLimit = o.MaxLimit
+	}
+	if o.Smoothing <= 0 || o.Smoothing > 1 {
+		o.Smoothing = defaultSmoothing
+	}
+	if o.Tolerance < 1 {
+		o.Tolerance = 1
+	}
+	if o.LongWindow < 1 {
+		o.LongWindow = 1
+	}
+	return o
+}
+
+// OptName 设置限制器名称
+func OptName(name string) Option {
+	return func(o *Options) {
+		o.Name = name
+	}
+}
+
+// OptInitialLimit 设置初始的并发限制
+func OptInitialLimit(n int) Option {
+	return func(o *Options) {
+		o.InitialLimit = n
+	}
+}
+
+// OptLimitRange 设置并发限制的上下限
+func OptLimitRange(min, max int) Option {
+	return func(o *Options) {
+		o.MinLimit = min
+		o.MaxLimit = max
+	}
+}
+
+// OptSmoothing 设置新计算出的限制所占的权重
+func OptSmoothing(smoothing float64) Option {
+	return func(o *Options) {
+		o.Smoothing = smoothing
+	}
+}
+
+// OptTolerance 设置短期延迟超过长期延迟多少倍后才开始降低限制
+func OptTolerance(tolerance float64) Option {
+	return func(o *Options) {
+		o.Tolerance = tolerance
+	}
+}
+
+// OptQueueSize 设置延迟没有增长时，每个采样周期允许增加的并发数
+func OptQueueSize(n int) Option {
+	return func(o *Options) {
+		o.QueueSize = n
+	}
+}
+
+// OptLongWindow 设置长期延迟的平滑周期数
+func OptLongWindow(n int) Option {
+	return func(o *Options) {
+		o.LongWindow = n
+	}
+}
+
+// OptWindow 设置采样周期，以及每个周期至少需要的样本数
+func OptWindow(d time.Duration, minSamples int) Option {
+	return func(o *Options) {
+		o.Window = d
+		o.MinSamples = minSamples
+	}
+}
+
+// OptEventBus 设置采样周期事件发布到的事件总线
+func OptEventBus(bus *eventbus.EventBus) Option {
+	return func(o *Options) {
+		o.EventBus = bus
+	}
+}
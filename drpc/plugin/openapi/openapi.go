@@ -0,0 +1,236 @@
+// Package openapi 根据注册的路由生成OpenAPI 3文档，描述通过httpproto协议暴露的接口
+package openapi
+
+import (
+	"encoding/json"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/proto/httpproto"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Version 生成的文档使用的OpenAPI版本
+const Version = "3.0.3"
+
+// DefaultPath 默认的文档访问路径
+const DefaultPath = "/openapi.json"
+
+// Document OpenAPI文档
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info 文档信息
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server 服务地址
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem 路由，httpproto只支持POST请求
+type PathItem struct {
+	Post *Operation `json:"post,omitempty"`
+}
+
+// Operation 接口
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Tags        []string             `json:"tags,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// RequestBody 请求内容
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response 响应内容
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType 内容类型对应的结构
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Components 可复用的结构定义
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// OpenAPI 生成OpenAPI文档的插件，需要在注册路由之前加入endpoint的全局插件
+type OpenAPI struct {
+	opts      Options
+	mu        sync.Mutex
+	handlers  []*drpc.Handler
+	reflector *SchemaReflector
+}
+
+var (
+	_ drpc.AfterRegRouterPlugin = new(OpenAPI)
+	_ http.Handler              = new(OpenAPI)
+)
+
+// NewOpenAPI 创建OpenAPI文档插件
+func NewOpenAPI(opts ...Option) *OpenAPI {
+	return &OpenAPI{
+		opts:      NewOptions(opts...),
+		reflector: NewSchemaReflector(),
+	}
+}
+
+// Name 插件名称
+func (that *OpenAPI) Name() string {
+	return "openapi"
+}
+
+// AfterRegRouter 记录注册的call路由，httpproto不支持push和stream消息
+func (that *OpenAPI) AfterRegRouter(h *drpc.Handler) error {
+	if !h.IsCall() || h.IsUnknown() {
+		return nil
+	}
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	that.handlers = append(that.handlers, h)
+	return nil
+}
+
+// Document 生成OpenAPI文档
+func (that *OpenAPI) Document() *Document {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       that.opts.Title,
+			Description: that.opts.Description,
+			Version:     that.opts.Version,
+		},
+		Paths: make(map[string]*PathItem, len(that.handlers)),
+	}
+	for _, u := range that.opts.Servers {
+		doc.Servers = append(doc.Servers, Server{URL: u})
+	}
+	for _, h := range that.handlers {
+		doc.Paths[h.Name()] = &PathItem{Post: that.operation(h)}
+	}
+	doc.Components.Schemas = make(map[string]*Schema, len(that.reflector.Schemas())+1)
+	for name, s := range that.reflector.Schemas() {
+		doc.Components.Schemas[name] = s
+	}
+	doc.Components.Schemas[statusSchemaName] = statusSchema()
+	return doc
+}
+
+// MarshalJSON 生成JSON格式的OpenAPI文档
+func (that *OpenAPI) MarshalJSON() ([]byte, error) {
+	return json.Marshal(that.Document())
+}
+
+// ServeHTTP 在配置的路径上提供OpenAPI文档，路径为空时对所有路径都返回文档
+func (that *OpenAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if len(that.opts.Path) > 0 && r.URL.Path != that.opts.Path {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	b, err := json.MarshalIndent(that.Document(), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	_, _ = w.Write(b)
+}
+
+// ListenAndServe 启动http服务提供OpenAPI文档
+func (that *OpenAPI) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, that)
+}
+
+// 生成路由对应的接口描述，调用方需持有锁
+func (that *OpenAPI) operation(h *drpc.Handler) *Operation {
+	op := &Operation{
+		OperationID: h.Name(),
+		Responses: map[string]*Response{
+			"200": {
+				Description: "OK",
+				Content:     that.content(that.reflector.Reflect(h.ReplyType())),
+			},
+			// httpproto使用299状态码返回业务错误
+			"299": {
+				Description: "Business Error",
+				Content: map[string]*MediaType{
+					"application/json": {Schema: &Schema{Ref: "#/components/schemas/" + statusSchemaName}},
+				},
+			},
+		},
+	}
+	if tag := tagOf(h.Name()); len(tag) > 0 {
+		op.Tags = []string{tag}
+	}
+	if arg := h.ArgElemType(); arg != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  that.content(that.reflector.Reflect(arg)),
+		}
+	}
+	return op
+}
+
+// 每种支持的内容类型使用相同的结构
+func (that *OpenAPI) content(s *Schema) map[string]*MediaType {
+	content := make(map[string]*MediaType, len(that.opts.BodyCodecs))
+	for _, codecID := range that.opts.BodyCodecs {
+		contentType := httpproto.GetContentType(codecID, "")
+		if len(contentType) == 0 {
+			continue
+		}
+		if i := strings.Index(contentType, ";"); i != -1 {
+			contentType = contentType[:i]
+		}
+		content[contentType] = &MediaType{Schema: s}
+	}
+	return content
+}
+
+// 使用路由的第一段作为分组，例如 /math/add 的分组为 math
+func tagOf(serviceMethod string) string {
+	parts := strings.Split(strings.Trim(serviceMethod, "/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0]
+}
+
+const statusSchemaName = "drpc.Status"
+
+// 业务错误的结构，和 drpc.Status 的json编码一致
+func statusSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":  {Type: "integer", Format: "int32"},
+			"msg":   {Type: "string"},
+			"cause": {Type: "string"},
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/codec"
+	"github.com/osgochina/dmicro/drpc/plugin/openapi"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type Base struct {
+	ID int64 `json:"id"`
+}
+
+type Node struct {
+	Name     string  `json:"name"`
+	Children []*Node `json:"children,omitempty"`
+}
+
+type Arg struct {
+	Base
+	Title   string         `json:"title"`
+	Count   uint32         `json:"count,string"`
+	Tags    []string       `json:"tags"`
+	Extra   map[string]int `json:"extra"`
+	Data    []byte         `json:"data"`
+	Created time.Time      `json:"created"`
+	Tree    *Node          `json:"tree"`
+	Any     interface{}    `json:"any"`
+	Ignored string         `json:"-"`
+	private string
+	Default bool
+}
+
+type Reply struct {
+	OK bool `json:"ok"`
+}
+
+type Doc struct {
+	drpc.CallCtx
+}
+
+func (that *Doc) Create(arg *Arg) (*Reply, *drpc.Status) {
+	return &Reply{OK: true}, nil
+}
+
+func (that *Doc) Sum(arg *[]int) (int, *drpc.Status) {
+	return 0, nil
+}
+
+type Notify struct {
+	drpc.PushCtx
+}
+
+func (that *Notify) Ping(arg *string) *drpc.Status {
+	return nil
+}
+
+func TestOpenAPI(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		doc := openapi.NewOpenAPI(
+			openapi.OptTitle("test"),
+			openapi.OptServers("http://127.0.0.1:9208"),
+			openapi.OptBodyCodecs(codec.JsonId, codec.FormId),
+		)
+		endpoint := drpc.NewEndpoint(drpc.EndpointConfig{}, doc)
+		defer endpoint.Close()
+		endpoint.RouteCall(new(Doc))
+		endpoint.RoutePush(new(Notify))
+
+		d := doc.Document()
+		t.Assert(d.OpenAPI, openapi.Version)
+		t.Assert(d.Info.Title, "test")
+		t.Assert(d.Servers[0].URL, "http://127.0.0.1:9208")
+		// push路由不通过httpproto暴露
+		t.Assert(len(d.Paths), 2)
+
+		op := d.Paths["/doc/create"].Post
+		t.Assert(op.Tags, []string{"doc"})
+		t.Assert(op.RequestBody.Content["application/json"].Schema.Ref, "#/components/schemas/Arg")
+		t.Assert(op.RequestBody.Content["application/x-www-form-urlencoded"].Schema.Ref, "#/components/schemas/Arg")
+		t.Assert(op.Responses["200"].Content["application/json"].Schema.Ref, "#/components/schemas/Reply")
+		t.Assert(op.Responses["299"].Content["application/json"].Schema.Ref, "#/components/schemas/drpc.Status")
+
+		arg := d.Components.Schemas["Arg"]
+		t.Assert(arg.Type, "object")
+		t.Assert(arg.Properties["id"].Type, "integer")
+		t.Assert(arg.Properties["title"].Type, "string")
+		t.Assert(arg.Properties["count"].Type, "string")
+		t.Assert(arg.Properties["tags"].Items.Type, "string")
+		t.Assert(arg.Properties["extra"].AdditionalProperties.Format, "int64")
+		t.Assert(arg.Properties["data"].Format, "byte")
+		t.Assert(arg.Properties["created"].Format, "date-time")
+		t.Assert(arg.Properties["tree"].Ref, "#/components/schemas/Node")
+		t.Assert(arg.Properties["Default"].Type, "boolean")
+		t.AssertNil(arg.Properties["Ignored"])
+		t.AssertNil(arg.Properties["private"])
+		// 递归类型使用引用
+		t.Assert(d.Components.Schemas["Node"].Properties["children"].Items.Ref, "#/components/schemas/Node")
+
+		sum := d.Paths["/doc/sum"].Post
+		t.Assert(sum.RequestBody.Content["application/json"].Schema.Items.Type, "integer")
+		t.Assert(sum.Responses["200"].Content["application/json"].Schema.Type, "integer")
+
+		// 通过http获取文档
+		srv := httptest.NewServer(doc)
+		defer srv.Close()
+		resp, err := http.Get(srv.URL + openapi.DefaultPath)
+		t.AssertNil(err)
+		var got openapi.Document
+		t.AssertNil(json.NewDecoder(resp.Body).Decode(&got))
+		_ = resp.Body.Close()
+		t.Assert(len(got.Paths), 2)
+
+		resp, err = http.Get(srv.URL + "/other")
+		t.AssertNil(err)
+		_ = resp.Body.Close()
+		t.Assert(resp.StatusCode, http.StatusNotFound)
+	})
+}
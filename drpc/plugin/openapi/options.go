@@ -0,0 +1,73 @@
+package openapi
+
+import (
+	"github.com/osgochina/dmicro/drpc/codec"
+)
+
+// Options 文档配置
+type Options struct {
+	Title       string   // 文档标题
+	Description string   // 文档描述
+	Version     string   // 接口版本
+	Servers     []string // 服务地址，例如 http://127.0.0.1:8080
+	Path        string   // 通过http提供文档的路径
+	BodyCodecs  []byte   // 接口支持的消息编码，对应httpproto的Content-Type
+}
+
+type Option func(*Options)
+
+// NewOptions 初始化配置
+func NewOptions(opts ...Option) Options {
+	o := Options{
+		Title:      "dmicro",
+		Version:    "1.0.0",
+		Path:       DefaultPath,
+		BodyCodecs: []byte{codec.JsonId},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// OptTitle 设置文档标题
+func OptTitle(title string) Option {
+	return func(o *Options) {
+		o.Title = title
+	}
+}
+
+// OptDescription 设置文档描述
+func OptDescription(description string) Option {
+	return func(o *Options) {
+		o.Description = description
+	}
+}
+
+// OptVersion 设置接口版本
+func OptVersion(version string) Option {
+	return func(o *Options) {
+		o.Version = version
+	}
+}
+
+// OptServers 设置服务地址
+func OptServers(urls ...string) Option {
+	return func(o *Options) {
+		o.Servers = urls
+	}
+}
+
+// OptPath 设置通过http提供文档的路径，为空则所有路径都返回文档
+func OptPath(path string) Option {
+	return func(o *Options) {
+		o.Path = path
+	}
+}
+
+// OptBodyCodecs 设置接口支持的消息编码，默认只支持json
+func OptBodyCodecs(codecIDs ...byte) Option {
+	return func(o *Options) {
+		o.BodyCodecs = codecIDs
+	}
+}
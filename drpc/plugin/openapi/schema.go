@@ -0,0 +1,174 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema JSON Schema，只包含描述Go类型需要的字段
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	durationType    = reflect.TypeOf(time.Duration(0))
+	rawMessageType  = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// SchemaReflector 把Go类型转换为JSON Schema，命名的结构体放到components中并返回引用，不能并发使用
+type SchemaReflector struct {
+	// components中的schema map[name]*Schema
+	schemas map[string]*Schema
+	// 结构体类型对应的schema名称
+	names map[reflect.Type]string
+}
+
+// NewSchemaReflector 创建类型转换器
+func NewSchemaReflector() *SchemaReflector {
+	return &SchemaReflector{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// Schemas 已经转换的命名结构体 map[name]*Schema
+func (that *SchemaReflector) Schemas() map[string]*Schema {
+	return that.schemas
+}
+
+// Reflect 获取类型的schema，类型为空返回nil
+func (that *SchemaReflector) Reflect(t reflect.Type) *Schema {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	case rawMessageType:
+		return &Schema{}
+	}
+	// 自定义了json编码的类型，无法推断结构
+	if t.Kind() != reflect.Interface && (t.Implements(jsonMarshalType) || reflect.PtrTo(t).Implements(jsonMarshalType)) {
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Minimum: new(float64)}
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64", Minimum: new(float64)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		// json把[]byte编码为base64字符串
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: that.Reflect(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: that.Reflect(t.Elem())}
+	case reflect.Struct:
+		return that.reflectStruct(t)
+	default:
+		// interface{}等任意类型
+		return &Schema{}
+	}
+}
+
+// 命名的结构体放入components中，匿名结构体直接展开
+func (that *SchemaReflector) reflectStruct(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return that.structSchema(t)
+	}
+	if name, ok := that.names[t]; ok {
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	name := that.schemaName(t)
+	that.names[t] = name
+	// 先占位，避免递归类型无限展开
+	that.schemas[name] = &Schema{}
+	*that.schemas[name] = *that.structSchema(t)
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// schema名称使用类型名称，不同包里有同名类型时加上包名
+func (that *SchemaReflector) schemaName(t reflect.Type) string {
+	name := t.Name()
+	if _, ok := that.schemas[name]; !ok {
+		return name
+	}
+	qualified := path.Base(t.PkgPath()) + "." + t.Name()
+	name = qualified
+	for i := 2; ; i++ {
+		if _, ok := that.schemas[name]; !ok {
+			return name
+		}
+		name = qualified + strconv.Itoa(i)
+	}
+}
+
+// 按照encoding/json的规则生成结构体的属性
+func (that *SchemaReflector) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	that.addFields(s, t)
+	return s
+}
+
+func (that *SchemaReflector) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		// 没有设置名称的匿名结构体字段，属性提升到外层
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			that.addFields(s, ft)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs := that.Reflect(f.Type)
+		// 使用string选项时，数字和布尔值编码为字符串
+		if strings.Contains(opts, "string") && (fs.Type == "integer" || fs.Type == "number" || fs.Type == "boolean") {
+			fs = &Schema{Type: "string", Format: fs.Format}
+		}
+		s.Properties[name] = fs
+	}
+}
@@ -81,9 +81,14 @@ func (that *proxy) call(ctx drpc.UnknownCallCtx) (interface{}, *drpc.Status) {
 	)
 	label.SessionID = ctx.Session().ID()
 	ctx.VisitMeta(func(key, value interface{}) bool {
+		// 截止时间由转发消息的上下文重新计算
+		if gconv.String(key) == drpc.MetaDeadline {
+			return true
+		}
 		settings = append(settings, drpc.WithSetMeta(gconv.String(key), gconv.String(value)))
 		return true
 	})
+	settings = append(settings, message.WithContext(ctx.Context()))
 	var (
 		result      []byte
 		realIPBytes = ctx.PeekMeta(drpc.MetaRealIP)
@@ -116,9 +121,14 @@ func (that *proxy) push(ctx drpc.UnknownPushCtx) *drpc.Status {
 	)
 	label.SessionID = ctx.Session().ID()
 	ctx.VisitMeta(func(key, value interface{}) bool {
+		// 截止时间由转发消息的上下文重新计算
+		if gconv.String(key) == drpc.MetaDeadline {
+			return true
+		}
 		settings = append(settings, drpc.WithSetMeta(gconv.String(key), gconv.String(value)))
 		return true
 	})
+	settings = append(settings, message.WithContext(ctx.Context()))
 	if realIPBytes := ctx.PeekMeta(drpc.MetaRealIP); len(gconv.String(realIPBytes)) == 0 {
 		label.RealIP = ctx.IP()
 		settings = append(settings, drpc.WithSetMeta(drpc.MetaRealIP, label.RealIP))
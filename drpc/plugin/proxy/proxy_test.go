@@ -5,6 +5,7 @@ import (
 	"github.com/gogf/gf/v2/test/gtest"
 	"github.com/osgochina/dmicro/drpc"
 	"github.com/osgochina/dmicro/drpc/internal"
+	"github.com/osgochina/dmicro/drpc/message"
 	"github.com/osgochina/dmicro/drpc/plugin/proxy"
 	"strconv"
 	"testing"
@@ -163,3 +164,83 @@ func TestPushProxy(t *testing.T) {
 		time.Sleep(1 * time.Second)
 	})
 }
+
+var (
+	deadlineRemain  = make(chan time.Duration, 1)
+	deadlineExpired = make(chan struct{}, 1)
+)
+
+type deadlineCall struct{ drpc.CallCtx }
+
+// Wait 记录剩余时间，然后等待上下文结束
+func (that *deadlineCall) Wait(_ *struct{}) (bool, *drpc.Status) {
+	deadline, ok := that.Context().Deadline()
+	if !ok {
+		deadlineRemain <- -1
+		return false, nil
+	}
+	deadlineRemain <- time.Until(deadline)
+	select {
+	case <-that.Context().Done():
+		deadlineExpired <- struct{}{}
+		return false, nil
+	case <-time.After(3 * time.Second):
+		return true, nil
+	}
+}
+
+// 截止时间经过代理转发后，后端服务看到的剩余时间扣除了代理上的耗时，并且到期后处理方法的上下文结束
+func TestProxyDeadline(t *testing.T) {
+	srv := drpc.NewEndpoint(drpc.EndpointConfig{ListenPort: 9223})
+	srv.RouteCall(new(deadlineCall))
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+	defer srv.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	forwarder := drpc.NewEndpoint(drpc.EndpointConfig{})
+	defer forwarder.Close()
+	upstream, stat := forwarder.Dial("127.0.0.1:9223")
+	if !stat.OK() {
+		t.Fatal(stat)
+	}
+	proxySrv := drpc.NewEndpoint(drpc.EndpointConfig{ListenPort: 9224},
+		proxy.NewProxyCallPlugin(func(*proxy.Label) proxy.CallForwarder {
+			// 模拟代理上的耗时
+			time.Sleep(100 * time.Millisecond)
+			return upstream
+		}),
+	)
+	go func() {
+		_ = proxySrv.ListenAndServe()
+	}()
+	defer proxySrv.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	cli := drpc.NewEndpoint(drpc.EndpointConfig{})
+	defer cli.Close()
+	sess, stat := cli.Dial("127.0.0.1:9224")
+	if !stat.OK() {
+		t.Fatal(stat)
+	}
+	gtest.C(t, func(t *gtest.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		var ok bool
+		stat := sess.Call("/deadline_call/wait", nil, &ok, message.WithContext(ctx)).Status()
+		t.Assert(stat.OK(), false)
+		select {
+		case remain := <-deadlineRemain:
+			t.Assert(remain > 0, true)
+			t.Assert(remain <= 400*time.Millisecond, true)
+		case <-time.After(time.Second):
+			t.Fatal("request was not forwarded")
+		}
+		select {
+		case <-deadlineExpired:
+		case <-time.After(time.Second):
+			t.Fatal("handler context did not expire")
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// 清理空闲令牌桶的间隔
+const sweepInterval = time.Minute
+
+// 令牌桶，按固定速率生成令牌，最多存放burst个
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// 创建令牌桶，初始时桶是满的
+func newBucket(rule Rule, now time.Time) *bucket {
+	return &bucket{
+		rate:   rule.Rate,
+		burst:  float64(rule.burst()),
+		tokens: float64(rule.burst()),
+		last:   now,
+	}
+}
+
+// 取出一个令牌，令牌不足时返回需要等待的时间
+func (that *bucket) take(now time.Time) (bool, time.Duration) {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	that.refill(now)
+	if that.tokens >= 1 {
+		that.tokens--
+		return true, 0
+	}
+	wait := (1 - that.tokens) / that.rate * float64(time.Second)
+	return false, time.Duration(math.Ceil(wait))
+}
+
+// 归还一个取出的令牌
+func (that *bucket) refund() {
+	that.mu.Lock()
+	that.tokens = math.Min(that.burst, that.tokens+1)
+	that.mu.Unlock()
+}
+
+// 桶是否已经重新装满，装满的桶与新建的桶等价，可以被清理
+func (that *bucket) full(now time.Time) bool {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	that.refill(now)
+	return that.tokens >= that.burst
+}
+
+// 按经过的时间补充令牌
+func (that *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(that.last); elapsed > 0 {
+		that.tokens = math.Min(that.burst, that.tokens+elapsed.Seconds()*that.rate)
+		that.last = now
+	}
+}
+
+// 按key区分的一组令牌桶，每个会话或者每个IP各自拥有一个令牌桶
+type keyedBuckets struct {
+	rule      Rule
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	nextSweep time.Time
+}
+
+func newKeyedBuckets(rule Rule) *keyedBuckets {
+	return &keyedBuckets{
+		rule:    rule,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// 获取key对应的令牌桶，不存在时创建
+func (that *keyedBuckets) get(key string, now time.Time) *bucket {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	if now.After(that.nextSweep) {
+		that.sweep(now)
+		that.nextSweep = now.Add(sweepInterval)
+	}
+	b, ok := that.buckets[key]
+	if !ok {
+		b = newBucket(that.rule, now)
+		that.buckets[key] = b
+	}
+	return b
+}
+
+// 移除key对应的令牌桶
+func (that *keyedBuckets) remove(key string) {
+	that.mu.Lock()
+	delete(that.buckets, key)
+	that.mu.Unlock()
+}
+
+// 清理已经装满的令牌桶，避免长期不活跃的会话和IP占用内存
+func (that *keyedBuckets) sweep(now time.Time) {
+	for key, b := range that.buckets {
+		if b.full(now) {
+			delete(that.buckets, key)
+		}
+	}
+}
+
+// 令牌桶的数量
+func (that *keyedBuckets) len() int {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	return len(that.buckets)
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/os/gcfg"
+	"github.com/osgochina/dmicro/logger"
+	"math"
+	"reflect"
+	"time"
+)
+
+// Rule 令牌桶规则
+type Rule struct {
+	// 每秒生成的令牌数，小于等于0表示不限制
+	Rate float64 `json:"rate"`
+	// 桶的容量，即允许的突发请求数，小于等于0时使用Rate向上取整
+	Burst int `json:"burst"`
+}
+
+// 是否启用该规则
+func (that Rule) enabled() bool {
+	return that.Rate > 0
+}
+
+// 桶的容量，至少为1
+func (that Rule) burst() int {
+	if that.Burst > 0 {
+		return that.Burst
+	}
+	return int(math.Max(1, math.Ceil(that.Rate)))
+}
+
+// Config 限流配置
+type Config struct {
+	// 整个端点的限制
+	Global Rule `json:"global"`
+	// 每个路由的限制，key为服务方法，例如 /math/add
+	Route map[string]Rule `json:"route"`
+	// 每个会话各自的限制
+	Session Rule `json:"session"`
+	// 每个来源IP各自的限制，来源IP取自 RealIP
+	IP Rule `json:"ip"`
+}
+
+// LoadConfig 从配置文件的pattern节点载入限流配置，节点不存在时返回空配置
+func LoadConfig(ctx context.Context, cfg *gcfg.Config, pattern string) (Config, error) {
+	var c Config
+	v, err := cfg.Get(ctx, pattern)
+	if err != nil || v == nil || v.IsNil() {
+		return c, err
+	}
+	err = v.Scan(&c)
+	return c, err
+}
+
+// WatchConfig 每隔interval从配置文件的pattern节点重新载入限流配置，配置变化时热更新限制，
+// 返回的函数用于停止监听
+func (that *RateLimit) WatchConfig(cfg *gcfg.Config, pattern string, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last := that.Config()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			c, err := LoadConfig(context.TODO(), cfg, pattern)
+			if err != nil {
+				logger.Warningf(context.TODO(), "ratelimit load config %s failed: %v", pattern, err)
+				continue
+			}
+			if reflect.DeepEqual(c, last) {
+				continue
+			}
+			last = c
+			that.Update(c)
+			logger.Infof(context.TODO(), "ratelimit config %s reloaded", pattern)
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
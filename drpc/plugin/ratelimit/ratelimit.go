@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/message"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimit 令牌桶限流插件
+// 按全局、路由、会话和来源IP四个维度限制call和push的频率，在读取消息头以后立即检查，被限流的请求不再解码消息体。
+// 被限流的call请求返回 drpc.CodeRateLimited 状态，并在元数据 message.MetaRetryAfter 中携带建议的重试等待时间，被限流的push消息直接丢弃。
+type RateLimit struct {
+	mu      sync.Mutex
+	limits  atomic.Value
+	timeNow func() time.Time
+}
+
+var (
+	_ drpc.AfterReadCallHeaderPlugin = new(RateLimit)
+	_ drpc.AfterReadPushHeaderPlugin = new(RateLimit)
+	_ drpc.AfterDisconnectPlugin     = new(RateLimit)
+)
+
+// 根据配置生成的一组令牌桶
+type limits struct {
+	config  Config
+	global  *bucket
+	routes  map[string]*bucket
+	session *keyedBuckets
+	ip      *keyedBuckets
+}
+
+// NewRateLimit 创建限流插件，该插件需要注册为端点的全局插件
+func NewRateLimit(cfg Config) *RateLimit {
+	r := &RateLimit{timeNow: time.Now}
+	r.Update(cfg)
+	return r
+}
+
+// Name 插件名称
+func (that *RateLimit) Name() string {
+	return "ratelimit"
+}
+
+// Config 当前生效的限流配置
+func (that *RateLimit) Config() Config {
+	return that.load().config
+}
+
+// Update 热更新限流配置，规则没有变化的维度保留原有的令牌桶
+func (that *RateLimit) Update(cfg Config) {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	now := that.timeNow()
+	old, _ := that.limits.Load().(*limits)
+	l := &limits{
+		config: cfg,
+		routes: make(map[string]*bucket, len(cfg.Route)),
+	}
+	if cfg.Global.enabled() {
+		if old != nil && old.global != nil && old.config.Global == cfg.Global {
+			l.global = old.global
+		} else {
+			l.global = newBucket(cfg.Global, now)
+		}
+	}
+	for route, rule := range cfg.Route {
+		if !rule.enabled() {
+			continue
+		}
+		if old != nil && old.routes[route] != nil && old.config.Route[route] == rule {
+			l.routes[route] = old.routes[route]
+		} else {
+			l.routes[route] = newBucket(rule, now)
+		}
+	}
+	if cfg.Session.enabled() {
+		if old != nil && old.session != nil && old.config.Session == cfg.Session {
+			l.session = old.session
+		} else {
+			l.session = newKeyedBuckets(cfg.Session)
+		}
+	}
+	if cfg.IP.enabled() {
+		if old != nil && old.ip != nil && old.config.IP == cfg.IP {
+			l.ip = old.ip
+		} else {
+			l.ip = newKeyedBuckets(cfg.IP)
+		}
+	}
+	that.limits.Store(l)
+}
+
+// AfterReadCallHeader 读取call消息头以后检查频率限制
+func (that *RateLimit) AfterReadCallHeader(ctx drpc.ReadCtx) *drpc.Status {
+	stat, wait := that.allow(ctx)
+	if !stat.OK() {
+		if c, ok := ctx.(interface{ SetMeta(key, value string) }); ok {
+			c.SetMeta(message.MetaRetryAfter, strconv.FormatInt(int64((wait+time.Millisecond-1)/time.Millisecond), 10))
+		}
+	}
+	return stat
+}
+
+// AfterReadPushHeader 读取push消息头以后检查频率限制
+func (that *RateLimit) AfterReadPushHeader(ctx drpc.ReadCtx) *drpc.Status {
+	stat, _ := that.allow(ctx)
+	return stat
+}
+
+// AfterDisconnect 会话断开以后移除该会话的令牌桶
+func (that *RateLimit) AfterDisconnect(sess drpc.BaseSession) *drpc.Status {
+	if l := that.load(); l.session != nil {
+		l.session.remove(sess.ID())
+	}
+	return nil
+}
+
+// 依次检查会话、来源IP、路由和全局的限制，先检查范围小的限制，避免个别调用方耗尽全局的令牌。
+// 任意一个限制拒绝时，归还已经从其他令牌桶中取出的令牌，被拒绝的请求不消耗任何限制的配额
+func (that *RateLimit) allow(ctx drpc.ReadCtx) (*drpc.Status, time.Duration) {
+	l := that.load()
+	now := that.timeNow()
+	var checks [4]struct {
+		scope  string
+		bucket *bucket
+	}
+	if l.session != nil {
+		checks[0].scope, checks[0].bucket = "session", l.session.get(ctx.Session().ID(), now)
+	}
+	if l.ip != nil {
+		checks[1].scope, checks[1].bucket = "ip", l.ip.get(ctx.RealIP(), now)
+	}
+	if b, found := l.routes[ctx.ServiceMethod()]; found {
+		checks[2].scope, checks[2].bucket = "route", b
+	}
+	if l.global != nil {
+		checks[3].scope, checks[3].bucket = "global", l.global
+	}
+	for i, c := range checks {
+		if c.bucket == nil {
+			continue
+		}
+		if ok, wait := c.bucket.take(now); !ok {
+			for _, taken := range checks[:i] {
+				if taken.bucket != nil {
+					taken.bucket.refund()
+				}
+			}
+			return rateLimited(c.scope), wait
+		}
+	}
+	return nil, 0
+}
+
+// 获取当前生效的令牌桶
+func (that *RateLimit) load() *limits {
+	return that.limits.Load().(*limits)
+}
+
+// 被限流时返回的状态
+func rateLimited(scope string) *drpc.Status {
+	return drpc.NewStatusByCodeText(drpc.CodeRateLimited, scope+" rate limit exceeded", false)
+}
+
+// RetryAfter 从被限流的调用结果中获取服务端建议的重试等待时间，没有建议时返回0
+func RetryAfter(cmd drpc.CallCmd) time.Duration {
+	return time.Duration(gconv.Int64(cmd.InputMeta().Get(message.MetaRetryAfter))) * time.Millisecond
+}
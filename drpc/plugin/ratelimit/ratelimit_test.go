@@ -0,0 +1,250 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/os/gcfg"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/drpc"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		now := time.Now()
+		b := newBucket(Rule{Rate: 10, Burst: 2}, now)
+		ok, _ := b.take(now)
+		t.Assert(ok, true)
+		ok, _ = b.take(now)
+		t.Assert(ok, true)
+		ok, wait := b.take(now)
+		t.Assert(ok, false)
+		t.Assert(wait == 100*time.Millisecond, true)
+
+		// 经过50毫秒补充了半个令牌，还需要等待50毫秒
+		now = now.Add(50 * time.Millisecond)
+		ok, wait = b.take(now)
+		t.Assert(ok, false)
+		t.Assert(wait == 50*time.Millisecond, true)
+
+		now = now.Add(50 * time.Millisecond)
+		ok, _ = b.take(now)
+		t.Assert(ok, true)
+		t.Assert(b.full(now), false)
+		t.Assert(b.full(now.Add(time.Second)), true)
+
+		// 未设置Burst时使用Rate向上取整
+		t.Assert(Rule{Rate: 0.5}.burst(), 1)
+		t.Assert(Rule{Rate: 2.5}.burst(), 3)
+	})
+}
+
+func TestKeyedBuckets(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		now := time.Now()
+		k := newKeyedBuckets(Rule{Rate: 1, Burst: 1})
+		ok, _ := k.get("a", now).take(now)
+		t.Assert(ok, true)
+		ok, _ = k.get("a", now).take(now)
+		t.Assert(ok, false)
+		ok, _ = k.get("b", now).take(now)
+		t.Assert(ok, true)
+		t.Assert(k.len(), 2)
+
+		k.remove("b")
+		t.Assert(k.len(), 1)
+
+		// 装满的令牌桶在清理时被移除
+		later := now.Add(2 * sweepInterval)
+		ok, _ = k.get("c", later).take(later)
+		t.Assert(ok, true)
+		t.Assert(k.len(), 1)
+	})
+}
+
+func TestRateLimit_Update(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		r := NewRateLimit(Config{
+			Global: Rule{Rate: 100},
+			Route:  map[string]Rule{"/a": {Rate: 1}, "/b": {Rate: 1}},
+			IP:     Rule{Rate: 10},
+		})
+		l := r.load()
+		t.Assert(l.session == nil, true)
+
+		r.Update(Config{
+			Global: Rule{Rate: 100},
+			Route:  map[string]Rule{"/a": {Rate: 1}, "/b": {Rate: 2}, "/c": {Rate: 0}},
+			IP:     Rule{Rate: 20},
+		})
+		nl := r.load()
+		// 没有变化的规则保留原有的令牌桶
+		t.Assert(nl.global == l.global, true)
+		t.Assert(nl.routes["/a"] == l.routes["/a"], true)
+		t.Assert(nl.routes["/b"] == l.routes["/b"], false)
+		t.Assert(nl.ip == l.ip, false)
+		_, found := nl.routes["/c"]
+		t.Assert(found, false)
+		t.Assert(r.Config().IP.Rate, 20)
+	})
+}
+
+const configContent = `
+[ratelimit]
+    [ratelimit.session]
+        rate  = 5
+        burst = 10
+    [ratelimit.route."/math/add"]
+        rate = 1
+`
+
+func TestLoadConfig(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		// 只有实例中的配置才会在内容变化后清理缓存
+		cfg := gcfg.Instance("ratelimit_test")
+		adapter := cfg.GetAdapter().(*gcfg.AdapterFile)
+		adapter.SetContent(configContent, "ratelimit_test")
+		defer adapter.RemoveContent("ratelimit_test")
+
+		c, err := LoadConfig(context.TODO(), cfg, "ratelimit")
+		t.AssertNil(err)
+		t.Assert(c.Session.Rate, 5)
+		t.Assert(c.Session.Burst, 10)
+		t.Assert(c.Route["/math/add"].Rate, 1)
+		t.Assert(c.Global.enabled(), false)
+
+		c, err = LoadConfig(context.TODO(), cfg, "none")
+		t.AssertNil(err)
+		t.Assert(len(c.Route), 0)
+
+		// 配置变化以后热更新限制
+		r := NewRateLimit(Config{})
+		stop := r.WatchConfig(cfg, "ratelimit", 50*time.Millisecond)
+		defer stop()
+		time.Sleep(200 * time.Millisecond)
+		t.Assert(r.Config().Session.Rate, 5)
+		adapter.SetContent(`
+[ratelimit]
+    [ratelimit.global]
+        rate = 100
+`, "ratelimit_test")
+		time.Sleep(200 * time.Millisecond)
+		t.Assert(r.Config().Global.Rate, 100)
+		t.Assert(r.Config().Session.enabled(), false)
+	})
+}
+
+type Math struct {
+	drpc.CallCtx
+}
+
+func (m *Math) Add(arg *[]int) (int, *drpc.Status) {
+	var r int
+	for _, a := range *arg {
+		r += a
+	}
+	return r, nil
+}
+
+var pushed = make(chan string, 10)
+
+type Notify struct {
+	drpc.PushCtx
+}
+
+func (n *Notify) Ping(arg *string) *drpc.Status {
+	pushed <- *arg
+	return nil
+}
+
+func TestRateLimit(t *testing.T) {
+	plugin := NewRateLimit(Config{
+		Route: map[string]Rule{"/math/add": {Rate: 1, Burst: 2}},
+	})
+	srv := drpc.NewEndpoint(drpc.EndpointConfig{ListenPort: 9214}, plugin)
+	srv.RouteCall(new(Math))
+	srv.RoutePush(new(Notify))
+	go func() {
+		_ = srv.ListenAndServe()
+	}()
+	defer srv.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	cli := drpc.NewEndpoint(drpc.EndpointConfig{})
+	defer cli.Close()
+	sess, stat := cli.Dial("127.0.0.1:9214")
+	if !stat.OK() {
+		t.Fatal(stat)
+	}
+	gtest.C(t, func(t *gtest.T) {
+		var result int
+		for i := 0; i < 2; i++ {
+			stat = sess.Call("/math/add", []int{1, 2}, &result).Status()
+			t.Assert(stat.OK(), true)
+			t.Assert(result, 3)
+		}
+		cmd := sess.Call("/math/add", []int{1, 2}, &result)
+		t.Assert(cmd.Status().Code(), drpc.CodeRateLimited)
+		t.Assert(drpc.IsRateLimited(cmd.Status()), true)
+		wait := RetryAfter(cmd)
+		t.Assert(wait > 0 && wait <= time.Second, true)
+
+		// 热更新以后新的限制立即生效
+		plugin.Update(Config{Session: Rule{Rate: 1000}})
+		stat = sess.Call("/math/add", []int{1, 2}, &result).Status()
+		t.Assert(stat.OK(), true)
+
+		// 被路由限制拒绝的请求不消耗会话的令牌
+		session := Rule{Rate: 0.001, Burst: 2}
+		plugin.Update(Config{Session: session, Route: map[string]Rule{"/math/add": {Rate: 0.001, Burst: 1}}})
+		t.Assert(sess.Call("/math/add", []int{1, 2}, &result).Status().OK(), true)
+		stat = sess.Call("/math/add", []int{1, 2}, &result).Status()
+		t.Assert(stat.Code(), drpc.CodeRateLimited)
+		t.Assert(strings.Contains(stat.String(), "route"), true)
+		plugin.Update(Config{Session: session, Route: map[string]Rule{"/math/add": {Rate: 0.001, Burst: 5}}})
+		t.Assert(sess.Call("/math/add", []int{1, 2}, &result).Status().OK(), true)
+		stat = sess.Call("/math/add", []int{1, 2}, &result).Status()
+		t.Assert(stat.Code(), drpc.CodeRateLimited)
+		t.Assert(strings.Contains(stat.String(), "session"), true)
+
+		// 来源IP的限制
+		plugin.Update(Config{IP: Rule{Rate: 0.001, Burst: 1}})
+		t.Assert(sess.Call("/math/add", []int{1, 2}, &result).Status().OK(), true)
+		stat = sess.Call("/math/add", []int{1, 2}, &result).Status()
+		t.Assert(stat.Code(), drpc.CodeRateLimited)
+		t.Assert(strings.Contains(stat.String(), "ip"), true)
+
+		// 全局的限制
+		plugin.Update(Config{Global: Rule{Rate: 0.001, Burst: 1}})
+		t.Assert(sess.Call("/math/add", []int{1, 2}, &result).Status().OK(), true)
+		stat = sess.Call("/math/add", []int{1, 2}, &result).Status()
+		t.Assert(stat.Code(), drpc.CodeRateLimited)
+		t.Assert(strings.Contains(stat.String(), "global"), true)
+
+		// 被限流的push消息直接丢弃，不会到达处理函数
+		plugin.Update(Config{Route: map[string]Rule{"/notify/ping": {Rate: 0.001, Burst: 1}}})
+		t.Assert(sess.Push("/notify/ping", "a").OK(), true)
+		t.Assert(sess.Push("/notify/ping", "b").OK(), true)
+		select {
+		case v := <-pushed:
+			t.Assert(v, "a")
+		case <-time.After(3 * time.Second):
+			t.Fatal("push not received")
+		}
+		select {
+		case v := <-pushed:
+			t.Fatalf("rate limited push reached the handler: %s", v)
+		case <-time.After(300 * time.Millisecond):
+		}
+		// 放开限制以后push消息正常到达
+		plugin.Update(Config{})
+		t.Assert(sess.Push("/notify/ping", "c").OK(), true)
+		select {
+		case v := <-pushed:
+			t.Assert(v, "c")
+		case <-time.After(3 * time.Second):
+			t.Fatal("push not received")
+		}
+	})
+}
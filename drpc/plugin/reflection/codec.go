@@ -0,0 +1,65 @@
+package reflection
+
+import (
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/codec"
+	"google.golang.org/protobuf/proto"
+	"net/url"
+	"reflect"
+)
+
+var (
+	protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+	urlValuesType    = reflect.TypeOf(url.Values{})
+	emptyStructType  = reflect.TypeOf(struct{}{})
+)
+
+// 方法的参数和返回值都能使用的编码格式
+func codecsOf(h *drpc.Handler) []string {
+	types := []reflect.Type{h.ArgElemType()}
+	if h.IsCall() {
+		types = append(types, h.ReplyType())
+	}
+	var names []string
+	for _, c := range codec.List() {
+		ok := true
+		for _, t := range types {
+			if !supportCodec(c.Name(), t) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			names = append(names, c.Name())
+		}
+	}
+	return names
+}
+
+// 判断编码格式是否支持该类型，未知的编码格式认为支持
+func supportCodec(codecName string, t reflect.Type) bool {
+	if t == nil {
+		return true
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch codecName {
+	case codec.ProtobufName:
+		return t == emptyStructType || reflect.PtrTo(t).Implements(protoMessageType)
+	case codec.PlainName:
+		switch t.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return true
+		case reflect.Slice:
+			return t.Elem().Kind() == reflect.Uint8
+		}
+		return false
+	case codec.FormName:
+		return t.Kind() == reflect.Struct || t.ConvertibleTo(urlValuesType)
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package reflection
+
+import (
+	"encoding/json"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/codec"
+	"github.com/osgochina/dmicro/drpc/message"
+)
+
+// List 获取服务端注册的所有方法
+func List(caller drpc.Caller, setting ...message.MsgSetting) (*ListReply, *drpc.Status) {
+	reply := new(ListReply)
+	stat := caller.Call(ListServiceMethod, struct{}{}, reply, jsonSetting(setting)...).Status()
+	if !stat.OK() {
+		return nil, stat
+	}
+	return reply, nil
+}
+
+// Describe 获取服务端方法的详细描述，为空则描述所有方法
+func Describe(caller drpc.Caller, serviceMethods []string, setting ...message.MsgSetting) (*DescribeReply, *drpc.Status) {
+	reply := new(DescribeReply)
+	args := &DescribeArgs{ServiceMethods: serviceMethods}
+	stat := caller.Call(DescribeServiceMethod, args, reply, jsonSetting(setting)...).Status()
+	if !stat.OK() {
+		return nil, stat
+	}
+	return reply, nil
+}
+
+// Invoke 使用JSON格式的参数调用任意call方法，返回JSON格式的结果
+func Invoke(caller drpc.Caller, serviceMethod string, args json.RawMessage, setting ...message.MsgSetting) (json.RawMessage, *drpc.Status) {
+	var reply json.RawMessage
+	stat := caller.Call(serviceMethod, jsonArgs(args), &reply, jsonSetting(setting)...).Status()
+	if !stat.OK() {
+		return nil, stat
+	}
+	return reply, nil
+}
+
+// InvokePush 使用JSON格式的参数发送任意push消息
+func InvokePush(caller drpc.Caller, serviceMethod string, args json.RawMessage, setting ...message.MsgSetting) *drpc.Status {
+	return caller.Push(serviceMethod, jsonArgs(args), jsonSetting(setting)...)
+}
+
+// 使用json编码消息，调用方传入的设置可以覆盖
+func jsonSetting(setting []message.MsgSetting) []message.MsgSetting {
+	return append([]message.MsgSetting{drpc.WithBodyCodec(codec.JsonName)}, setting...)
+}
+
+// 空参数编码为null，服务端解码后得到参数的零值
+func jsonArgs(args json.RawMessage) interface{} {
+	if len(args) == 0 {
+		return json.RawMessage("null")
+	}
+	return args
+}
@@ -0,0 +1,201 @@
+// Package reflection 提供内置的反射路由，列出endpoint注册的call和push方法、参数和返回值的结构以及支持的编码格式，
+// 配合JSON调用，工具可以在没有生成代码的情况下调用任意方法
+package reflection
+
+import (
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/plugin/openapi"
+	"sort"
+	"sync"
+)
+
+const (
+	// ListServiceMethod 列出所有方法的路由，使用保留的前缀，不受路由映射规则影响
+	ListServiceMethod = "/__reflect/list"
+	// DescribeServiceMethod 获取方法详细描述的路由
+	DescribeServiceMethod = "/__reflect/describe"
+)
+
+const (
+	// TypeCall call方法
+	TypeCall = "call"
+	// TypePush push方法
+	TypePush = "push"
+)
+
+const pluginName = "reflection"
+
+// Method 方法的描述
+type Method struct {
+	// 路由名称
+	Name string `json:"name"`
+	// 方法类型 call/push
+	Type string `json:"type"`
+	// 支持的编码格式
+	Codecs []string `json:"codecs"`
+	// 参数的结构，只有describe返回
+	Arg *openapi.Schema `json:"arg,omitempty"`
+	// 返回值的结构，只有describe返回call方法的返回值
+	Reply *openapi.Schema `json:"reply,omitempty"`
+}
+
+// ListReply 列出的所有方法
+type ListReply struct {
+	Methods []*Method `json:"methods"`
+}
+
+// DescribeArgs 需要描述的方法
+type DescribeArgs struct {
+	// 方法的路由名称，为空则描述所有方法
+	ServiceMethods []string `json:"service_methods"`
+}
+
+// DescribeReply 方法的详细描述
+type DescribeReply struct {
+	Methods []*Method `json:"methods"`
+	// 参数和返回值引用的命名结构 map[name]*Schema
+	Schemas map[string]*openapi.Schema `json:"schemas"`
+}
+
+// Reflection 反射插件，需要在注册路由之前加入endpoint的全局插件
+type Reflection struct {
+	mu       sync.Mutex
+	handlers []*drpc.Handler
+	// 正在注册反射路由自身
+	registering bool
+}
+
+var (
+	_ drpc.AfterNewEndpointPlugin = new(Reflection)
+	_ drpc.AfterRegRouterPlugin   = new(Reflection)
+)
+
+// NewReflection 创建反射插件
+func NewReflection() *Reflection {
+	return new(Reflection)
+}
+
+// Name 插件名称
+func (that *Reflection) Name() string {
+	return pluginName
+}
+
+// AfterNewEndpoint 注册反射路由
+func (that *Reflection) AfterNewEndpoint(endpoint drpc.EarlyEndpoint) error {
+	that.mu.Lock()
+	that.registering = true
+	that.mu.Unlock()
+	// 路由映射规则会合并"__"，所以直接指定路由名称
+	endpoint.Router().RouteCallFuncWithName(ListServiceMethod, (*reflectCall).list)
+	endpoint.Router().RouteCallFuncWithName(DescribeServiceMethod, (*reflectCall).describe)
+	that.mu.Lock()
+	that.registering = false
+	that.mu.Unlock()
+	return nil
+}
+
+// AfterRegRouter 记录注册的call和push路由，反射路由自身和stream路由除外
+func (that *Reflection) AfterRegRouter(h *drpc.Handler) error {
+	if h.IsStream() || h.IsUnknown() {
+		return nil
+	}
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	if that.registering {
+		return nil
+	}
+	that.handlers = append(that.handlers, h)
+	return nil
+}
+
+// List 列出所有方法，按照路由名称排序
+func (that *Reflection) List() *ListReply {
+	reply := &ListReply{}
+	for _, h := range that.sortedHandlers() {
+		reply.Methods = append(reply.Methods, newMethod(h))
+	}
+	return reply
+}
+
+// Describe 描述指定的方法，为空则描述所有方法
+func (that *Reflection) Describe(serviceMethods ...string) (*DescribeReply, *drpc.Status) {
+	handlers := that.sortedHandlers()
+	if len(serviceMethods) > 0 {
+		byName := make(map[string]*drpc.Handler, len(handlers))
+		for _, h := range handlers {
+			byName[h.Name()] = h
+		}
+		handlers = handlers[:0:0]
+		for _, name := range serviceMethods {
+			h, ok := byName[name]
+			if !ok {
+				return nil, drpc.NewStatus(drpc.CodeNotFound, drpc.CodeText(drpc.CodeNotFound), name)
+			}
+			handlers = append(handlers, h)
+		}
+	}
+	reflector := openapi.NewSchemaReflector()
+	reply := &DescribeReply{}
+	for _, h := range handlers {
+		m := newMethod(h)
+		m.Arg = reflector.Reflect(h.ArgElemType())
+		if h.IsCall() {
+			m.Reply = reflector.Reflect(h.ReplyType())
+		}
+		reply.Methods = append(reply.Methods, m)
+	}
+	reply.Schemas = reflector.Schemas()
+	return reply, nil
+}
+
+func (that *Reflection) sortedHandlers() []*drpc.Handler {
+	that.mu.Lock()
+	handlers := make([]*drpc.Handler, len(that.handlers))
+	copy(handlers, that.handlers)
+	that.mu.Unlock()
+	sort.Slice(handlers, func(i, j int) bool {
+		if handlers[i].Name() != handlers[j].Name() {
+			return handlers[i].Name() < handlers[j].Name()
+		}
+		// 同名的call排在push前面
+		return handlers[i].IsCall() && !handlers[j].IsCall()
+	})
+	return handlers
+}
+
+func newMethod(h *drpc.Handler) *Method {
+	m := &Method{Name: h.Name(), Type: TypePush, Codecs: codecsOf(h)}
+	if h.IsCall() {
+		m.Type = TypeCall
+	}
+	return m
+}
+
+// 反射路由的处理程序，通过endpoint的插件容器找到反射插件
+type reflectCall struct {
+	drpc.CallCtx
+}
+
+func (that *reflectCall) plugin() (*Reflection, *drpc.Status) {
+	p, ok := that.Endpoint().PluginContainer().GetByName(pluginName).(*Reflection)
+	if !ok {
+		return nil, drpc.NewStatus(drpc.CodeInternalServerError, "reflection plugin not found")
+	}
+	return p, nil
+}
+
+func (that *reflectCall) list(_ *struct{}) (*ListReply, *drpc.Status) {
+	p, stat := that.plugin()
+	if stat != nil {
+		return nil, stat
+	}
+	return p.List(), nil
+}
+
+func (that *reflectCall) describe(arg *DescribeArgs) (*DescribeReply, *drpc.Status) {
+	p, stat := that.plugin()
+	if stat != nil {
+		return nil, stat
+	}
+	return p.Describe(arg.ServiceMethods...)
+}
@@ -0,0 +1,96 @@
+package reflection_test
+
+import (
+	"encoding/json"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/drpc/plugin/reflection"
+	"testing"
+	"time"
+)
+
+type AddArgs struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type AddReply struct {
+	Sum int `json:"sum"`
+}
+
+type Math struct {
+	drpc.CallCtx
+}
+
+func (that *Math) Add(arg *AddArgs) (*AddReply, *drpc.Status) {
+	return &AddReply{Sum: arg.A + arg.B}, nil
+}
+
+func (that *Math) Echo(arg *string) (string, *drpc.Status) {
+	return *arg, nil
+}
+
+var notified = make(chan string, 1)
+
+type Notify struct {
+	drpc.PushCtx
+}
+
+func (that *Notify) Ping(arg *string) *drpc.Status {
+	notified <- *arg
+	return nil
+}
+
+func TestReflection(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		srv := drpc.NewEndpoint(drpc.EndpointConfig{ListenPort: 9208}, reflection.NewReflection())
+		defer srv.Close()
+		srv.RouteCall(new(Math))
+		srv.RoutePush(new(Notify))
+		go srv.ListenAndServe()
+		time.Sleep(500 * time.Millisecond)
+
+		cli := drpc.NewEndpoint(drpc.EndpointConfig{})
+		defer cli.Close()
+		sess, stat := cli.Dial(":9208")
+		t.Assert(stat.OK(), true)
+
+		list, stat := reflection.List(sess)
+		t.AssertNil(stat)
+		t.Assert(len(list.Methods), 3)
+		t.Assert(list.Methods[0].Name, "/math/add")
+		t.Assert(list.Methods[0].Type, reflection.TypeCall)
+		t.AssertIN("json", list.Methods[0].Codecs)
+		t.AssertNI("protobuf", list.Methods[0].Codecs)
+		t.AssertNI("plain", list.Methods[0].Codecs)
+		t.Assert(list.Methods[1].Name, "/math/echo")
+		t.AssertIN("plain", list.Methods[1].Codecs)
+		t.Assert(list.Methods[2].Name, "/notify/ping")
+		t.Assert(list.Methods[2].Type, reflection.TypePush)
+		t.AssertNil(list.Methods[0].Arg)
+
+		desc, stat := reflection.Describe(sess, []string{"/math/add"})
+		t.AssertNil(stat)
+		t.Assert(len(desc.Methods), 1)
+		t.Assert(desc.Methods[0].Arg.Ref, "#/components/schemas/AddArgs")
+		t.Assert(desc.Methods[0].Reply.Ref, "#/components/schemas/AddReply")
+		t.Assert(desc.Schemas["AddArgs"].Properties["a"].Type, "integer")
+		t.Assert(desc.Schemas["AddReply"].Properties["sum"].Type, "integer")
+
+		_, stat = reflection.Describe(sess, []string{"/math/sub"})
+		t.Assert(stat.Code(), drpc.CodeNotFound)
+
+		reply, stat := reflection.Invoke(sess, "/math/add", json.RawMessage(`{"a":1,"b":2}`))
+		t.AssertNil(stat)
+		t.Assert(string(reply), `{"sum":3}`)
+
+		stat = reflection.InvokePush(sess, "/notify/ping", json.RawMessage(`"hello"`))
+		t.AssertNil(stat)
+		select {
+		case v := <-notified:
+			t.Assert(v, "hello")
+		case <-time.After(3 * time.Second):
+			t.Fatal("push not received")
+		}
+	})
+}
@@ -419,6 +419,44 @@ func (that *pluginSingleContainer) beforeReadCallBody(ctx ReadCtx) *Status {
 	return nil
 }
 
+// LimiterPlugin 并发限制插件，执行call和push的处理程序之前获取执行许可，
+// 获取失败时请求被拒绝，获取成功时返回的release函数在处理程序结束以后调用，即使处理程序发生了panic
+type LimiterPlugin interface {
+	Plugin
+	Acquire(ctx ReadCtx) (release func(), stat *Status)
+}
+
+// 执行处理程序之前获取执行许可，返回的函数用于归还已获取的许可
+func (that *pluginSingleContainer) acquire(ctx ReadCtx) (func(), *Status) {
+	var releases []func()
+	for _, plugin := range that.plugins {
+		if _plugin, ok := plugin.(LimiterPlugin); ok {
+			release, stat := _plugin.Acquire(ctx)
+			if !stat.OK() {
+				internal.Debugf(context.TODO(), "[LimiterPlugin:%s] %s", plugin.Name(), stat.String())
+				for i := len(releases) - 1; i >= 0; i-- {
+					releases[i]()
+				}
+				return releaseNothing, stat
+			}
+			if release != nil {
+				releases = append(releases, release)
+			}
+		}
+	}
+	switch len(releases) {
+	case 0:
+		return releaseNothing, nil
+	case 1:
+		return releases[0], nil
+	}
+	return func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}, nil
+}
+
 // AfterReadCallBodyPlugin 读取CALL消息的body之后触发该事件
 type AfterReadCallBodyPlugin interface {
 	Plugin
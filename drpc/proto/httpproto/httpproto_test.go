@@ -11,6 +11,7 @@ import (
 	"github.com/osgochina/dmicro/drpc/proto/httpproto"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 )
@@ -94,3 +95,26 @@ func TestHTTProto(t *testing.T) {
 		}
 	})
 }
+
+// 流消息无法使用http协议表示，打开流时返回明确的错误
+func TestStreamUnsupported(t *testing.T) {
+	srv := drpc.NewEndpoint(drpc.EndpointConfig{ListenPort: 9221})
+	uri := srv.RouteStreamFunc(func(ctx drpc.StreamCtx) *drpc.Status { return nil })
+	go func() {
+		_ = srv.ListenAndServe(httpproto.NewHTTProtoFunc())
+	}()
+	defer srv.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	cli := drpc.NewEndpoint(drpc.EndpointConfig{})
+	defer cli.Close()
+	sess, stat := cli.Dial(":9221", httpproto.NewHTTProtoFunc())
+	if !stat.OK() {
+		t.Fatal(stat)
+	}
+	gtest.C(t, func(t *gtest.T) {
+		_, stat := sess.OpenStream(uri)
+		t.Assert(stat.Code(), drpc.CodeWriteFailed)
+		t.Assert(strings.Contains(stat.Cause().Error(), "unsupport message type"), true)
+	})
+}
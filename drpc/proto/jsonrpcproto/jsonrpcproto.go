@@ -1,6 +1,7 @@
 package jsonrpcproto
 
 import (
+	"fmt"
 	"github.com/gogf/gf/v2/encoding/gjson"
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/util/gconv"
@@ -77,6 +78,10 @@ type ErrorResponse struct {
 
 // Pack 打包
 func (that *jsonRPCProto) Pack(m proto.Message) error {
+	// jsonrpc协议无法表示流消息和取消消息
+	if m.MType() == message.TypeStream || m.MType() == message.TypeCancel {
+		return fmt.Errorf("unsupport message type: %d(%s)", m.MType(), message.TypeText(m.MType()))
+	}
 	bb := dbuffer.GetByteBuffer()
 	defer dbuffer.ReleaseByteBuffer(bb)
 	if m.MType() == message.TypeCall {
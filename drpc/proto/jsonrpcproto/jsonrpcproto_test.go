@@ -2,11 +2,13 @@ package jsonrpcproto_test
 
 import (
 	"context"
+	"github.com/gogf/gf/v2/test/gtest"
 	"github.com/gogf/gf/v2/util/gconv"
 	"github.com/osgochina/dmicro/drpc"
 	"github.com/osgochina/dmicro/drpc/internal"
 	"github.com/osgochina/dmicro/drpc/message"
 	"github.com/osgochina/dmicro/drpc/proto/jsonrpcproto"
+	"strings"
 	"testing"
 	"time"
 )
@@ -63,3 +65,26 @@ func (p *Push) Test(arg *map[string]string) *drpc.Status {
 	internal.Infof(context.TODO(), "receive push(%s):\narg: %#v\n", p.IP(), arg)
 	return nil
 }
+
+// 流消息无法使用jsonrpc协议表示，打开流时返回明确的错误
+func TestStreamUnsupported(t *testing.T) {
+	srv := drpc.NewEndpoint(drpc.EndpointConfig{ListenPort: 9222})
+	uri := srv.RouteStreamFunc(func(ctx drpc.StreamCtx) *drpc.Status { return nil })
+	go func() {
+		_ = srv.ListenAndServe(jsonrpcproto.NewJSONRPCProtoFunc())
+	}()
+	defer srv.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	cli := drpc.NewEndpoint(drpc.EndpointConfig{})
+	defer cli.Close()
+	sess, stat := cli.Dial(":9222", jsonrpcproto.NewJSONRPCProtoFunc())
+	if !stat.OK() {
+		t.Fatal(stat)
+	}
+	gtest.C(t, func(t *gtest.T) {
+		_, stat := sess.OpenStream(uri)
+		t.Assert(stat.Code(), drpc.CodeWriteFailed)
+		t.Assert(strings.Contains(stat.Cause().Error(), "unsupport message type"), true)
+	})
+}
@@ -96,6 +96,7 @@ const (
 	pnCall        = "CALL"
 	pnUnknownPush = "UNKNOWN_PUSH"
 	pnUnknownCall = "UNKNOWN_CALL"
+	pnStream      = "STREAM"
 )
 
 // Router 路由器
@@ -108,6 +109,7 @@ type SubRouter struct {
 	root            *Router
 	callHandlers    map[string]*Handler
 	pushHandlers    map[string]*Handler
+	streamHandlers  map[string]*Handler
 	unknownCall     **Handler
 	unknownPush     **Handler
 	prefix          string
@@ -121,6 +123,7 @@ func newRouter(pluginContainer *PluginContainer) *Router {
 		subRouter: &SubRouter{
 			callHandlers:    make(map[string]*Handler),
 			pushHandlers:    make(map[string]*Handler),
+			streamHandlers:  make(map[string]*Handler),
 			unknownCall:     new(*Handler),
 			unknownPush:     new(*Handler),
 			prefix:          rootGroup,
@@ -146,6 +149,11 @@ func (that *Router) RouteCallFunc(callHandleFunc interface{}, plugin ...Plugin)
 	return that.subRouter.RouteCallFunc(callHandleFunc, plugin...)
 }
 
+// RouteCallFuncWithName 使用指定的路由名称注册func对象到路由器
+func (that *Router) RouteCallFuncWithName(serviceMethod string, callHandleFunc interface{}, plugin ...Plugin) string {
+	return that.subRouter.RouteCallFuncWithName(serviceMethod, callHandleFunc, plugin...)
+}
+
 // RoutePush 注册 PUSH 类型的处理程序到路由器
 func (that *Router) RoutePush(pushCtrlStruct interface{}, plugin ...Plugin) []string {
 	return that.subRouter.RoutePush(pushCtrlStruct, plugin...)
@@ -156,6 +164,11 @@ func (that *Router) RoutePushFunc(pushHandleFunc interface{}, plugin ...Plugin)
 	return that.subRouter.RoutePushFunc(pushHandleFunc, plugin...)
 }
 
+// RouteStreamFunc 通过func注册STREAM类型的处理程序到路由器
+func (that *Router) RouteStreamFunc(streamHandleFunc interface{}, plugin ...Plugin) string {
+	return that.subRouter.RouteStreamFunc(streamHandleFunc, plugin...)
+}
+
 // SetUnknownCall 注册默认的未知CALL处理方法
 func (that *Router) SetUnknownCall(fn func(UnknownCallCtx) (interface{}, *status.Status), plugin ...Plugin) {
 	pluginContainer := that.subRouter.pluginContainer.cloneAndAppendMiddle(plugin...)
@@ -166,6 +179,7 @@ func (that *Router) SetUnknownCall(fn func(UnknownCallCtx) (interface{}, *status
 		isUnknown:       true,
 		argElem:         reflect.TypeOf([]byte{}),
 		pluginContainer: pluginContainer,
+		limiter:         newRouteLimiter(pluginContainer),
 		unknownHandleFunc: func(ctx *handlerCtx) {
 			body, stat := fn(ctx)
 			if !stat.OK() {
@@ -194,6 +208,7 @@ func (that *Router) SetUnknownPush(fn func(UnknownPushCtx) *status.Status, plugi
 		isUnknown:       true,
 		argElem:         reflect.TypeOf([]byte{}),
 		pluginContainer: pluginContainer,
+		limiter:         newRouteLimiter(pluginContainer),
 		unknownHandleFunc: func(ctx *handlerCtx) {
 			ctx.stat = fn(ctx)
 		},
@@ -225,6 +240,7 @@ func (that *SubRouter) SubRoute(prefix string, plugin ...Plugin) *SubRouter {
 		root:            that.root,
 		callHandlers:    that.callHandlers,
 		pushHandlers:    that.pushHandlers,
+		streamHandlers:  that.streamHandlers,
 		unknownPush:     that.unknownPush,
 		unknownCall:     that.unknownCall,
 		prefix:          globalServiceMethodMapper(that.prefix, prefix),
@@ -242,6 +258,19 @@ func (that *SubRouter) RouteCallFunc(callHandleFunc interface{}, plugin ...Plugi
 	return that.reg(pnCall, makeCallHandlersFromFunc, callHandleFunc, plugin)[0]
 }
 
+// RouteCallFuncWithName 通过func注册单个 CALL 类型的处理程序，路由名称直接使用serviceMethod，不经过分组前缀和路由映射规则，
+// 用于注册框架保留的路由
+func (that *SubRouter) RouteCallFuncWithName(serviceMethod string, callHandleFunc interface{}, plugin ...Plugin) string {
+	handlerMaker := func(prefix string, callHandleFunc interface{}, pluginContainer *PluginContainer) ([]*Handler, error) {
+		handlers, err := makeCallHandlersFromFunc(prefix, callHandleFunc, pluginContainer)
+		for _, h := range handlers {
+			h.name = serviceMethod
+		}
+		return handlers, err
+	}
+	return that.reg(pnCall, handlerMaker, callHandleFunc, plugin)[0]
+}
+
 // RoutePush 通过struct批量注册 PUSH 类型的处理程序，并返回它们的路径
 func (that *SubRouter) RoutePush(pushCtrlStruct interface{}, plugin ...Plugin) []string {
 	return that.reg(pnPush, makePushHandlersFromStruct, pushCtrlStruct, plugin)
@@ -252,6 +281,11 @@ func (that *SubRouter) RoutePushFunc(pushHandleFunc interface{}, plugin ...Plugi
 	return that.reg(pnPush, makePushHandlersFromFunc, pushHandleFunc, plugin)[0]
 }
 
+// RouteStreamFunc 通过func注册STREAM类型的处理程序，并返回它的路径
+func (that *SubRouter) RouteStreamFunc(streamHandleFunc interface{}, plugin ...Plugin) string {
+	return that.reg(pnStream, makeStreamHandlersFromFunc, streamHandleFunc, plugin)[0]
+}
+
 //注册路由器
 func (that *SubRouter) reg(
 	routerTypeName string,
@@ -273,9 +307,12 @@ func (that *SubRouter) reg(
 	var names []string
 	var hadHandlers map[string]*Handler
 
-	if routerTypeName == pnCall {
+	switch routerTypeName {
+	case pnCall:
 		hadHandlers = that.callHandlers
-	} else {
+	case pnStream:
+		hadHandlers = that.streamHandlers
+	default:
 		hadHandlers = that.pushHandlers
 	}
 
@@ -284,6 +321,10 @@ func (that *SubRouter) reg(
 			internal.Fatalf(context.TODO(), "there is a handler conflict: %s", h.name)
 		}
 		h.routerTypeName = routerTypeName
+		//流是长连接的交互，不受并发限制
+		if routerTypeName != pnStream {
+			h.limiter = newRouteLimiter(pluginContainer)
+		}
 		hadHandlers[h.name] = h
 		//触发路由注册事件
 		pluginContainer.afterRegRouter(h)
@@ -317,6 +358,12 @@ func (that *SubRouter) getPush(uriPath string) (*Handler, bool) {
 	return nil, false
 }
 
+// 获取路由器中指定路径的STREAM处理方法，流没有默认处理方法
+func (that *SubRouter) getStream(uriPath string) (*Handler, bool) {
+	t, ok := that.streamHandlers[uriPath]
+	return t, ok
+}
+
 // callCtrlStruct 需要实现 CallCtx 接口
 func makeCallHandlersFromStruct(prefix string, callCtrlStruct interface{}, pluginContainer *PluginContainer) ([]*Handler, error) {
 
@@ -777,9 +824,51 @@ func makePushHandlersFromFunc(prefix string, pushHandleFunc interface{}, pluginC
 	}}, nil
 }
 
+// 创建stream消息的处理器，传入的参数是 func(StreamCtx) *Status
+func makeStreamHandlersFromFunc(prefix string, streamHandleFunc interface{}, pluginContainer *PluginContainer) ([]*Handler, error) {
+
+	var (
+		cType      = reflect.TypeOf(streamHandleFunc)
+		cValue     = reflect.ValueOf(streamHandleFunc)
+		typeString = objectName(cValue)
+	)
+	if cType.Kind() != reflect.Func {
+		return nil, gerror.Newf("stream-handler: the type is not function: %s", typeString)
+	}
+	// needs one in: StreamCtx.
+	if cType.NumIn() != 1 {
+		return nil, gerror.Newf("stream-handler: %s needs one in argument, but have %d", typeString, cType.NumIn())
+	}
+	if cType.In(0) != typeOfStreamCtx {
+		return nil, gerror.Newf("stream-handler: %s's first arg must be drpc.StreamCtx type: %s", typeString, cType.In(0))
+	}
+	// needs one out: *Status.
+	if cType.NumOut() != 1 {
+		return nil, gerror.Newf("stream-handler: %s needs one out arguments, but have %d", typeString, cType.NumOut())
+	}
+	if returnType := cType.Out(0); !isStatusType(returnType.String()) {
+		return nil, gerror.Newf("stream-handler: %s out argument %s is not *drpc.Status", typeString, returnType)
+	}
+
+	var handleFunc = func(ctx *handlerCtx) {
+		rets := cValue.Call([]reflect.Value{reflect.ValueOf(ctx)})
+		ctx.stat = (*status.Status)(unsafe.Pointer(rets[0].Pointer()))
+	}
+
+	if pluginContainer == nil {
+		pluginContainer = newPluginContainer()
+	}
+	return []*Handler{{
+		name:             globalServiceMethodMapper(prefix, handlerFuncName(cValue)),
+		streamHandleFunc: handleFunc,
+		pluginContainer:  pluginContainer,
+	}}, nil
+}
+
 var (
-	typeOfCallCtx = reflect.TypeOf((*CallCtx)(nil)).Elem()
-	typeOfPushCtx = reflect.TypeOf((*PushCtx)(nil)).Elem()
+	typeOfStreamCtx = reflect.TypeOf((*StreamCtx)(nil)).Elem()
+	typeOfCallCtx   = reflect.TypeOf((*CallCtx)(nil)).Elem()
+	typeOfPushCtx   = reflect.TypeOf((*PushCtx)(nil)).Elem()
 )
 
 //判断方法是否属于 CallCtx
@@ -14,6 +14,7 @@ import (
 	"github.com/osgochina/dmicro/drpc/status"
 	"io"
 	"net"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -119,6 +120,9 @@ type CtxSession interface {
 	// Push 发送消息，不接收响应，只返回发送状态
 	Push(serviceMethod string, args interface{}, setting ...message.MsgSetting) *status.Status
 
+	// OpenStream 打开一个双向流
+	OpenStream(serviceMethod string, setting ...message.MsgSetting) (Stream, *status.Status)
+
 	// SessionAge 获取session最大的生存周期
 	SessionAge() time.Duration
 
@@ -138,6 +142,13 @@ type Session interface {
 	CtxSession
 }
 
+// Caller 发送请求的接口，Session 和 client.RpcClient 都实现了该接口，
+// protoc-gen-dmicro 生成的客户端代码和 reflection 插件都依赖该接口
+type Caller interface {
+	Call(serviceMethod string, args interface{}, result interface{}, setting ...message.MsgSetting) CallCmd
+	Push(serviceMethod string, args interface{}, setting ...message.MsgSetting) *status.Status
+}
+
 // 会话的状态
 //不能改变枚举值的顺序
 const (
@@ -155,8 +166,11 @@ type session struct {
 	endpoint              *endpoint
 	getCallHandler        func(serviceMethodPath string) (*Handler, bool)
 	getPushHandler        func(serviceMethodPath string) (*Handler, bool)
+	getStreamHandler      func(serviceMethodPath string) (*Handler, bool)
 	timeNow               func() int64
 	callCmdMap            *gmap.Map
+	handlingCalls         *gmap.IntAnyMap // 正在处理的对端call请求 map[seq]context.CancelFunc
+	streams               *gmap.Map
 	protoFuncList         []proto.ProtoFunc
 	socket                socket.Socket
 	closeNotifyCh         chan struct{}
@@ -188,17 +202,20 @@ var (
 
 func newSession(e *endpoint, conn net.Conn, protoFunc []proto.ProtoFunc) *session {
 	var s = &session{
-		endpoint:       e,
-		getCallHandler: e.router.subRouter.getCall,
-		getPushHandler: e.router.subRouter.getPush,
-		timeNow:        e.timeNow,
-		protoFuncList:  protoFunc,
-		status:         statusPreparing,
-		socket:         socket.NewSocket(conn, protoFunc...),
-		closeNotifyCh:  make(chan struct{}),
-		callCmdMap:     gmap.New(true),
-		sessionAge:     e.defaultSessionAge,
-		contextAge:     e.defaultContextAge,
+		endpoint:         e,
+		getCallHandler:   e.router.subRouter.getCall,
+		getPushHandler:   e.router.subRouter.getPush,
+		getStreamHandler: e.router.subRouter.getStream,
+		timeNow:          e.timeNow,
+		protoFuncList:    protoFunc,
+		status:           statusPreparing,
+		socket:           socket.NewSocket(conn, protoFunc...),
+		closeNotifyCh:    make(chan struct{}),
+		callCmdMap:       gmap.New(true),
+		handlingCalls:    gmap.NewIntAnyMap(true),
+		streams:          gmap.New(true),
+		sessionAge:       e.defaultSessionAge,
+		contextAge:       e.defaultContextAge,
 	}
 	return s
 }
@@ -670,9 +687,11 @@ func (that *session) AsyncCall(serviceMethod string, args interface{}, result in
 	if output.BodyCodec() == codec.NilCodecID {
 		output.SetBodyCodec(that.endpoint.defaultBodyCodec)
 	}
+	//请求的上下文在收到响应或者放弃请求以后才释放
+	var cancelCtx context.CancelFunc
 	if age := that.ContextAge(); age > 0 {
-		ctxTimout, cancel := context.WithTimeout(output.Context(), age)
-		defer cancel()
+		var ctxTimout context.Context
+		ctxTimout, cancelCtx = context.WithTimeout(output.Context(), age)
 		message.WithContext(ctxTimout)(output)
 	}
 	cmd := &callCmd{
@@ -683,6 +702,7 @@ func (that *session) AsyncCall(serviceMethod string, args interface{}, result in
 		doneChan:    make(chan struct{}),
 		start:       that.timeNow(),
 		swap:        gmap.New(true),
+		cancelCtx:   cancelCtx,
 	}
 	// 计数 call cmd
 	that.graceCallCmdWaitGroup.Add(1)
@@ -716,6 +736,10 @@ W:
 	}
 	//发送call消息之后，执行插件
 	that.endpoint.pluginContainer.afterWriteCall(cmd)
+	//调用方的上下文结束时，放弃该请求
+	if ctxDone := output.Context().Done(); ctxDone != nil {
+		go cmd.watchContext(ctxDone)
+	}
 	return cmd
 }
 
@@ -758,6 +782,8 @@ func (that *session) write(msg message.Message) (net.Conn, *Status) {
 	default:
 		//设置写入超时时间
 		_ = that.socket.SetWriteDeadline(deadline)
+		//把剩余的超时时间传递给接收方
+		setMetaDeadline(msg, deadline)
 		//写入消息
 		err = that.socket.WriteMessage(msg)
 	}
@@ -774,6 +800,49 @@ ERR:
 	return usedConn, statWriteFailed.Copy(err)
 }
 
+//记录对端正在处理的call请求，使其可以被对端取消
+func (that *session) trackHandlingCall(input message.Message) {
+	ctx, cancel := context.WithCancel(input.Context())
+	message.WithContext(ctx)(input)
+	that.handlingCalls.Set(int(input.Seq()), cancel)
+}
+
+//取消对端正在处理的call请求，call请求处理完毕时也用它释放上下文
+func (that *session) cancelHandlingCall(seq int32) {
+	if cancel := that.handlingCalls.Remove(int(seq)); cancel != nil {
+		cancel.(context.CancelFunc)()
+	}
+}
+
+//通知对端放弃了seq对应的call请求
+func (that *session) writeCancel(seq int32, serviceMethod string) {
+	msg := message.GetMessage()
+	defer message.PutMessage(msg)
+	msg.SetMType(message.TypeCancel)
+	msg.SetSeq(seq)
+	msg.SetServiceMethod(serviceMethod)
+	if _, stat := that.write(msg); !stat.OK() {
+		internal.Debugf(context.TODO(), "write cancel message fail: %s", stat.String())
+	}
+}
+
+//CALL和PUSH消息把剩余的超时时间写入元数据，用于跨服务传递调用方的截止时间
+func setMetaDeadline(msg message.Message, deadline time.Time) {
+	if deadline.IsZero() {
+		return
+	}
+	switch msg.MType() {
+	case message.TypeCall, message.TypePush:
+	default:
+		return
+	}
+	remain := time.Until(deadline).Milliseconds()
+	if remain < 1 {
+		remain = 1
+	}
+	msg.Meta().Set(message.MetaDeadline, strconv.FormatInt(remain, 10))
+}
+
 //重新链接
 func (that *session) redialForClient(oldConn net.Conn) bool {
 	if that.redialForClientLocked == nil {
@@ -804,6 +873,8 @@ func (that *session) closeLocked() error {
 	that.endpoint.sessHub.delete(that.ID())
 	//发送会话准备关闭通知
 	that.notifyClosed()
+	//重置会话中的流，避免流处理程序阻塞优雅关闭
+	that.resetStreams("")
 	// 优雅的结束会话
 	that.graceCtxWait()
 	// 优雅的等待会话中的链接关闭
@@ -863,6 +934,20 @@ func (that *session) startReadAndHandle(c context.Context) {
 		if err != nil {
 			ctx.stat = statBadMessage.Copy(err)
 		}
+		//流消息帧需要在读协程中按顺序分发
+		if ctx.input.MType() == message.TypeStream && that.preHandleStream(ctx) {
+			that.endpoint.putHandleCtx(ctx, false)
+			continue
+		}
+		switch ctx.input.MType() {
+		case message.TypeCancel:
+			//对端放弃了请求，取消正在处理的call
+			that.cancelHandlingCall(ctx.input.Seq())
+			that.endpoint.putHandleCtx(ctx, false)
+			continue
+		case message.TypeCall:
+			that.trackHandlingCall(ctx.input)
+		}
 		// 给优雅处理器添加一次记录,优雅的结束会话之前，需要等待改协程处理完毕
 		that.graceCtxWaitGroup.Add(1)
 
@@ -870,6 +955,7 @@ func (that *session) startReadAndHandle(c context.Context) {
 			defer that.endpoint.putHandleCtx(ctx, true)
 			ctx.handle()
 		}); err != nil {
+			that.cancelHandlingCall(ctx.input.Seq())
 			that.endpoint.putHandleCtx(ctx, true)
 		}
 	}
@@ -901,6 +987,8 @@ func (that *session) readDisconnected(oldConn net.Conn, err error) {
 			internal.Warningf(context.TODO(), "disconnect when reading: %T %s", err, errStr)
 		}
 	}
+	//重置会话中的流
+	that.resetStreams(reason)
 	//优化的等待所有处理程序结束
 	that.graceCtxWait()
 	// 循环处理该会话中的各个请求
@@ -23,18 +23,23 @@ const (
 	CodeConnClosed          int32 = 102
 	CodeWriteFailed         int32 = 104
 	CodeDialFailed          int32 = 105
+	CodeStreamEOF           int32 = 106
+	CodeStreamReset         int32 = 107
+	CodeCallCanceled        int32 = 108
+	CodeCircuitOpen         int32 = 109
 	CodeBadMessage          int32 = 400
 	CodeUnauthorized        int32 = 401
 	CodeNotFound            int32 = 404
 	CodeMTypeNotAllowed     int32 = 405
 	CodeHandleTimeout       int32 = 408
+	CodeRateLimited         int32 = 429
 	CodeInternalServerError int32 = 500
 	CodeBadGateway          int32 = 502
+	CodeOverloaded          int32 = 503
 
 	CodeConflict int32 = 409
 	// CodeUnsupportedTx                 int32 = 410
 	// CodeUnsupportedCodecType          int32 = 415
-	// CodeServiceUnavailable            int32 = 503
 	// CodeGatewayTimeout                int32 = 504
 	// CodeVariantAlsoNegotiates         int32 = 506
 	// CodeInsufficientStorage           int32 = 507
@@ -70,16 +75,28 @@ func CodeText(statCode int32) string {
 		return "Connection Closed"
 	case CodeWriteFailed:
 		return "Write Failed"
+	case CodeStreamEOF:
+		return "Stream EOF"
+	case CodeStreamReset:
+		return "Stream Reset"
+	case CodeCallCanceled:
+		return "Call Canceled"
+	case CodeCircuitOpen:
+		return "Circuit Open"
 	case CodeNotFound:
 		return "Not Found"
 	case CodeHandleTimeout:
 		return "Handle Timeout"
+	case CodeRateLimited:
+		return "Rate Limited"
 	case CodeMTypeNotAllowed:
 		return "Message Type Not Allowed"
 	case CodeInternalServerError:
 		return "Internal Server Error"
 	case CodeBadGateway:
 		return "Bad Gateway"
+	case CodeOverloaded:
+		return "Overloaded"
 	case CodeUnknownError:
 		fallthrough
 	default:
@@ -98,10 +115,29 @@ var (
 	statCodeMTypeNotAllowed = NewStatus(CodeMTypeNotAllowed, CodeText(CodeMTypeNotAllowed), "")
 	statHandleTimeout       = NewStatus(CodeHandleTimeout, CodeText(CodeHandleTimeout), "")
 	statInternalServerError = NewStatus(CodeInternalServerError, CodeText(CodeInternalServerError), "")
+	statStreamEOF           = NewStatus(CodeStreamEOF, CodeText(CodeStreamEOF), "")
+	statStreamReset         = NewStatus(CodeStreamReset, CodeText(CodeStreamReset), "")
+	statCallCanceled        = NewStatus(CodeCallCanceled, CodeText(CodeCallCanceled), "")
+	statOverloaded          = NewStatus(CodeOverloaded, CodeText(CodeOverloaded), "")
 	// 必须要在 post dial和post accept阶段调用，不然就报错
 	statUnpreparedError = statInvalidOpError.Copy("Cannot be called during the Non-PostDial and Non-PostAccept phase")
 )
 
+// IsStreamEOF 判断流是否已经被对端正常关闭
+func IsStreamEOF(stat *Status) bool {
+	return stat != nil && stat.Code() == CodeStreamEOF
+}
+
+// IsOverloaded 判断是否是服务端过载拒绝了请求
+func IsOverloaded(stat *Status) bool {
+	return stat != nil && stat.Code() == CodeOverloaded
+}
+
+// IsRateLimited 判断是否是请求频率超出了服务端的限制
+func IsRateLimited(stat *Status) bool {
+	return stat != nil && stat.Code() == CodeRateLimited
+}
+
 // IsConnError 判断是否是链接出错
 func IsConnError(stat *Status) bool {
 	if stat == nil {
@@ -0,0 +1,470 @@
+package drpc
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/osgochina/dmicro/drpc/codec"
+	"github.com/osgochina/dmicro/drpc/internal"
+	"github.com/osgochina/dmicro/drpc/message"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Stream 会话上的双向流
+type Stream interface {
+	// ID 流的编号，由发起方的序列号生成
+	ID() int32
+
+	// ServiceMethod 流请求的服务名
+	ServiceMethod() string
+
+	// Context 流的上下文，流结束以后会被取消
+	Context() context.Context
+
+	// Send 发送一条消息，对端窗口耗尽的时候会阻塞等待
+	Send(body interface{}) *Status
+
+	// Recv 接收一条消息，对端半关闭以后返回 CodeStreamEOF 状态
+	Recv(body interface{}) *Status
+
+	// CloseSend 半关闭，通知对端本端不会再发送消息
+	CloseSend() *Status
+
+	// Close 关闭流，如果流还没有正常结束，则通知对端重置该流
+	Close()
+}
+
+// StreamCtx stream消息使用的上下文
+type StreamCtx interface {
+	inputCtx
+
+	// GetBodyCodec 获取流消息的编码格式
+	GetBodyCodec() byte
+
+	// Send 发送一条消息到流的发起方
+	Send(body interface{}) *Status
+
+	// Recv 从流的发起方接收一条消息
+	Recv(body interface{}) *Status
+
+	// CloseSend 半关闭，通知流的发起方不会再发送消息
+	CloseSend() *Status
+}
+
+//流消息的帧类型
+const (
+	streamFrameOpen      = "open"
+	streamFrameData      = "data"
+	streamFrameHalfClose = "half_close"
+	streamFrameReset     = "reset"
+	streamFrameWindow    = "window"
+)
+
+//流消息的发送方
+const (
+	streamSideOpener   = "o"
+	streamSideAcceptor = "a"
+)
+
+// 默认的流控窗口大小
+const defaultStreamWindow = 64
+
+var _ Stream = new(stream)
+
+// 流在会话中的索引，两端都可以发起流，所以需要区分是否由本端发起
+type streamKey struct {
+	id    int32
+	local bool
+}
+
+// 接收到的流消息帧
+type streamFrame struct {
+	bodyCodec byte
+	body      []byte
+	eof       bool
+}
+
+type stream struct {
+	sess          *session
+	id            int32
+	local         bool // 是否由本端发起
+	serviceMethod string
+	bodyCodec     byte
+	ctx           context.Context
+	cancel        context.CancelFunc
+
+	recvCh     chan *streamFrame
+	recvMu     sync.Mutex
+	recvSeq    int32 // 只在会话的读协程中访问
+	recvWindow int32
+	consumed   int32
+	recvEOF    bool
+	peerClosed int32
+
+	sendMu     sync.Mutex
+	sendSeq    int32
+	sendWindow int32
+	sendClosed int32
+	windowCh   chan struct{}
+
+	doneCh   chan struct{}
+	doneOnce sync.Once
+	err      *Status
+}
+
+//创建流对象
+func newStream(sess *session, id int32, local bool, serviceMethod string, bodyCodec byte, parent context.Context) *stream {
+	window := sess.endpoint.streamWindow
+	if window <= 0 {
+		window = defaultStreamWindow
+	}
+	if bodyCodec == codec.NilCodecID {
+		bodyCodec = sess.endpoint.defaultBodyCodec
+	}
+	ctx, cancel := context.WithCancel(parent)
+	return &stream{
+		sess:          sess,
+		id:            id,
+		local:         local,
+		serviceMethod: serviceMethod,
+		bodyCodec:     bodyCodec,
+		ctx:           ctx,
+		cancel:        cancel,
+		recvWindow:    window,
+		// 多留一个位置给半关闭帧
+		recvCh:   make(chan *streamFrame, window+1),
+		windowCh: make(chan struct{}, 1),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// ID 流的编号
+func (that *stream) ID() int32 {
+	return that.id
+}
+
+// ServiceMethod 流请求的服务名
+func (that *stream) ServiceMethod() string {
+	return that.serviceMethod
+}
+
+// Context 流的上下文
+func (that *stream) Context() context.Context {
+	return that.ctx
+}
+
+// Send 发送一条消息
+func (that *stream) Send(body interface{}) *Status {
+	that.sendMu.Lock()
+	defer that.sendMu.Unlock()
+	if atomic.LoadInt32(&that.sendClosed) == 1 {
+		return statStreamEOF.Copy("send on closed stream")
+	}
+	// 等待对端的窗口
+	for atomic.LoadInt32(&that.sendWindow) <= 0 {
+		select {
+		case <-that.windowCh:
+		case <-that.doneCh:
+			return that.err
+		case <-that.ctx.Done():
+			return that.abortByContext()
+		}
+	}
+	select {
+	case <-that.doneCh:
+		return that.err
+	default:
+	}
+	atomic.AddInt32(&that.sendWindow, -1)
+	that.sendSeq++
+	return that.writeFrame(streamFrameData,
+		message.WithBody(body),
+		message.WithSetMeta(message.MetaStreamSeq, strconv.FormatInt(int64(that.sendSeq), 10)),
+		message.WithContext(that.ctx),
+	)
+}
+
+// Recv 接收一条消息
+func (that *stream) Recv(body interface{}) *Status {
+	that.recvMu.Lock()
+	defer that.recvMu.Unlock()
+	if that.recvEOF {
+		return statStreamEOF
+	}
+	var frame *streamFrame
+	select {
+	case frame = <-that.recvCh:
+	default:
+		select {
+		case frame = <-that.recvCh:
+		case <-that.doneCh:
+			// 流结束之前收到的消息依然可以读取
+			select {
+			case frame = <-that.recvCh:
+			default:
+				return that.err
+			}
+		case <-that.ctx.Done():
+			return that.abortByContext()
+		}
+	}
+	if frame.eof {
+		that.recvEOF = true
+		return statStreamEOF
+	}
+	// 消费了一半的窗口以后，把额度归还给对端
+	that.consumed++
+	if that.consumed >= that.recvWindow/2 || that.recvWindow == 1 {
+		_ = that.writeWindow(that.consumed)
+		that.consumed = 0
+	}
+	m := message.GetMessage(message.WithBody(body))
+	defer message.PutMessage(m)
+	m.SetBodyCodec(frame.bodyCodec)
+	if err := m.UnmarshalBody(frame.body); err != nil {
+		return statBadMessage.Copy(err)
+	}
+	return nil
+}
+
+// CloseSend 半关闭
+func (that *stream) CloseSend() *Status {
+	that.sendMu.Lock()
+	if !atomic.CompareAndSwapInt32(&that.sendClosed, 0, 1) {
+		that.sendMu.Unlock()
+		return nil
+	}
+	that.sendSeq++
+	stat := that.writeFrame(streamFrameHalfClose,
+		message.WithSetMeta(message.MetaStreamSeq, strconv.FormatInt(int64(that.sendSeq), 10)),
+	)
+	that.sendMu.Unlock()
+	that.tryFinish()
+	return stat
+}
+
+// Close 关闭流
+func (that *stream) Close() {
+	if that.isDone() {
+		return
+	}
+	that.abort(statStreamReset.Copy("stream canceled"), true)
+}
+
+// 作为流的接收方，处理程序结束以后关闭流
+func (that *stream) closeByAcceptor(stat *Status) {
+	if !stat.OK() {
+		that.abort(stat, true)
+		return
+	}
+	_ = that.CloseSend()
+	if !that.isDone() {
+		// 对端还没有半关闭，告诉它不需要再发送了
+		that.abort(statStreamEOF, true)
+	}
+}
+
+// 上下文被取消，重置流
+func (that *stream) abortByContext() *Status {
+	that.abort(statStreamReset.Copy(that.ctx.Err()), true)
+	return that.err
+}
+
+// 重置流，notifyPeer 为true时发送重置帧给对端
+func (that *stream) abort(stat *Status, notifyPeer bool) {
+	if that.isDone() {
+		return
+	}
+	if notifyPeer {
+		_ = that.writeFrame(streamFrameReset, message.WithStatus(stat))
+	}
+	that.finish(stat)
+}
+
+// 两端都半关闭以后，流正常结束
+func (that *stream) tryFinish() {
+	if atomic.LoadInt32(&that.sendClosed) == 1 && atomic.LoadInt32(&that.peerClosed) == 1 {
+		that.finish(statStreamEOF)
+	}
+}
+
+// 结束流，并从会话中移除
+func (that *stream) finish(stat *Status) {
+	that.doneOnce.Do(func() {
+		if stat.OK() {
+			stat = statStreamEOF
+		}
+		that.err = stat
+		that.sess.streams.Remove(that.key())
+		close(that.doneCh)
+		that.cancel()
+	})
+}
+
+// 流是否已经结束
+func (that *stream) isDone() bool {
+	select {
+	case <-that.doneCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// 流在会话中的索引
+func (that *stream) key() streamKey {
+	return streamKey{id: that.id, local: that.local}
+}
+
+// 本端在流中的角色
+func (that *stream) side() string {
+	if that.local {
+		return streamSideOpener
+	}
+	return streamSideAcceptor
+}
+
+// 归还窗口额度给对端
+func (that *stream) writeWindow(n int32) *Status {
+	return that.writeFrame(streamFrameWindow,
+		message.WithSetMeta(message.MetaStreamWindow, strconv.FormatInt(int64(n), 10)),
+	)
+}
+
+// 增加发送窗口，并唤醒等待的发送者
+func (that *stream) addSendWindow(n int32) {
+	if n <= 0 {
+		return
+	}
+	atomic.AddInt32(&that.sendWindow, n)
+	select {
+	case that.windowCh <- struct{}{}:
+	default:
+	}
+}
+
+// 写入一个流消息帧
+func (that *stream) writeFrame(frame string, setting ...message.MsgSetting) *Status {
+	output := message.GetMessage(setting...)
+	defer message.PutMessage(output)
+	output.SetMType(message.TypeStream)
+	output.SetSeq(that.id)
+	output.SetBodyCodec(that.bodyCodec)
+	output.Meta().Set(message.MetaStreamFrame, frame)
+	output.Meta().Set(message.MetaStreamSide, that.side())
+	_, stat := that.sess.write(output)
+	return stat
+}
+
+// 收到对端的流消息帧
+func (that *stream) onFrame(frame string, input message.Message) {
+	switch frame {
+	case streamFrameWindow:
+		that.addSendWindow(gconv.Int32(input.Meta().Get(message.MetaStreamWindow)))
+		return
+	case streamFrameReset:
+		stat := input.Status()
+		if stat.OK() {
+			stat = statStreamReset
+		}
+		that.finish(stat)
+		return
+	case streamFrameData, streamFrameHalfClose:
+	default:
+		that.abort(statBadMessage.Copy("unknown stream frame: "+frame), true)
+		return
+	}
+	// 消息帧必须按顺序到达
+	seq := gconv.Int32(input.Meta().Get(message.MetaStreamSeq))
+	if seq != that.recvSeq+1 {
+		that.abort(statBadMessage.Copy("stream frame out of order"), true)
+		return
+	}
+	that.recvSeq = seq
+	f := &streamFrame{eof: frame == streamFrameHalfClose}
+	if !f.eof {
+		f.bodyCodec = input.BodyCodec()
+		if b, ok := input.Body().(*[]byte); ok && b != nil {
+			f.body = make([]byte, len(*b))
+			copy(f.body, *b)
+		}
+	}
+	select {
+	case that.recvCh <- f:
+	default:
+		that.abort(statStreamReset.Copy("stream window exceeded"), true)
+		return
+	}
+	if f.eof {
+		atomic.StoreInt32(&that.peerClosed, 1)
+		that.tryFinish()
+	}
+}
+
+// OpenStream 打开一个到对端的双向流
+func (that *session) OpenStream(serviceMethod string, setting ...message.MsgSetting) (Stream, *Status) {
+	output := message.GetMessage(setting...)
+	defer message.PutMessage(output)
+
+	id := atomic.AddInt32(&that.seq, 1)
+	st := newStream(that, id, true, serviceMethod, output.BodyCodec(), output.Context())
+	that.streams.Set(st.key(), st)
+
+	output.SetMType(message.TypeStream)
+	output.SetSeq(id)
+	output.SetServiceMethod(serviceMethod)
+	output.SetBodyCodec(st.bodyCodec)
+	output.SetBody(nil)
+	output.Meta().Set(message.MetaStreamFrame, streamFrameOpen)
+	output.Meta().Set(message.MetaStreamSide, streamSideOpener)
+	output.Meta().Set(message.MetaStreamWindow, strconv.FormatInt(int64(st.recvWindow), 10))
+	if _, stat := that.write(output); !stat.OK() {
+		st.finish(stat)
+		return nil, stat
+	}
+	return st, nil
+}
+
+// 读协程中预处理流消息，返回true表示该消息已经处理完毕
+// 除了打开流的消息需要交给处理程序，其他的消息帧都在读协程中按顺序分发
+func (that *session) preHandleStream(ctx *handlerCtx) bool {
+	input := ctx.input
+	frame := gconv.String(input.Meta().Get(message.MetaStreamFrame))
+	if frame != streamFrameOpen {
+		if ctx.stat.OK() {
+			that.dispatchStream(frame, input)
+		} else {
+			internal.Warningf(context.TODO(), "drop stream frame: %d %s %s", input.Seq(), frame, ctx.stat.String())
+		}
+		return true
+	}
+	st := newStream(that, input.Seq(), false, input.ServiceMethod(), input.BodyCodec(), context.Background())
+	st.sendWindow = gconv.Int32(input.Meta().Get(message.MetaStreamWindow))
+	that.streams.Set(st.key(), st)
+	ctx.stream = st
+	return false
+}
+
+// 把流消息帧分发给对应的流
+func (that *session) dispatchStream(frame string, input message.Message) {
+	// 对端是发起方，则该流由对端打开
+	local := gconv.String(input.Meta().Get(message.MetaStreamSide)) == streamSideAcceptor
+	v, ok := that.streams.Search(streamKey{id: input.Seq(), local: local})
+	if !ok {
+		internal.Debugf(context.TODO(), "not found stream: %d %s", input.Seq(), frame)
+		return
+	}
+	v.(*stream).onFrame(frame, input)
+}
+
+// 重置会话中所有的流
+func (that *session) resetStreams(reason string) {
+	stat := statConnClosed
+	if reason != "" {
+		stat = statConnClosed.Copy(reason)
+	}
+	for _, v := range that.streams.Values() {
+		v.(*stream).finish(stat)
+	}
+}
@@ -0,0 +1,118 @@
+package drpc
+
+import (
+	"fmt"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/drpc/proto"
+	"github.com/osgochina/dmicro/drpc/proto/jsonproto"
+	"github.com/osgochina/dmicro/drpc/proto/rawproto"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	protos := []struct {
+		name      string
+		protoFunc proto.ProtoFunc
+	}{
+		{"rawproto", rawproto.NewRawProtoFunc()},
+		{"jsonproto", jsonproto.NewJSONProtoFunc()},
+	}
+	// 每种协议使用不同的端口，避免关闭后的端口被立即复用
+	networks := []struct {
+		network string
+		ports   []uint16
+	}{
+		{"tcp", []uint16{9190, 9216}},
+		{"unix", []uint16{0, 0}},
+		{"kcp", []uint16{9217, 9218}},
+		{"quic", []uint16{9219, 9220}},
+	}
+	for i, p := range protos {
+		for _, n := range networks {
+			p, n, port := p, n, n.ports[i]
+			t.Run(p.name+"/"+n.network, func(t *testing.T) {
+				cfg := EndpointConfig{Network: n.network, ListenPort: port}
+				addr := fmt.Sprintf("127.0.0.1:%d", port)
+				if n.network == "unix" {
+					addr = filepath.Join(os.TempDir(), fmt.Sprintf("drpc_stream_%s.sock", p.name))
+					_ = os.Remove(addr)
+					cfg.ListenIP = addr
+				}
+				testStream(t, cfg, addr, p.protoFunc)
+			})
+		}
+	}
+}
+
+func testStream(t *testing.T, cfg EndpointConfig, addr string, protoFunc proto.ProtoFunc) {
+	srv := NewEndpoint(cfg)
+	uri := srv.RouteStreamFunc(streamSum)
+	go func() {
+		_ = srv.ListenAndServe(protoFunc)
+	}()
+	defer srv.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	cliCfg := EndpointConfig{Network: cfg.Network}
+	if cfg.Network == "unix" {
+		// unix socket 客户端也需要绑定本地路径
+		cliCfg.LocalIP = addr + ".cli"
+		_ = os.Remove(cliCfg.LocalIP)
+	}
+	cli := NewEndpoint(cliCfg)
+	defer cli.Close()
+	sess, stat := cli.Dial(addr, protoFunc)
+	if !stat.OK() {
+		t.Fatal(stat)
+	}
+	gtest.C(t, func(t *gtest.T) {
+		st, stat := sess.OpenStream(uri)
+		t.Assert(stat.OK(), true)
+		for i := 1; i <= 100; i++ {
+			t.Assert(st.Send(i).OK(), true)
+		}
+		t.Assert(st.CloseSend().OK(), true)
+		var last, count int
+		for {
+			var n int
+			stat = st.Recv(&n)
+			if IsStreamEOF(stat) {
+				break
+			}
+			t.Assert(stat.OK(), true)
+			last = n
+			count++
+		}
+		t.Assert(count, 100)
+		t.Assert(last, 5050)
+	})
+	gtest.C(t, func(t *gtest.T) {
+		st, stat := sess.OpenStream("/notfound")
+		t.Assert(stat.OK(), true)
+		var n int
+		stat = st.Recv(&n)
+		t.Assert(stat.Code(), CodeNotFound)
+	})
+}
+
+func streamSum(ctx StreamCtx) *Status {
+	var sum int
+	for {
+		var n int
+		stat := ctx.Recv(&n)
+		if IsStreamEOF(stat) {
+			break
+		}
+		if !stat.OK() {
+			return stat
+		}
+		sum += n
+		if stat = ctx.Send(sum); !stat.OK() {
+			return stat
+		}
+	}
+	return nil
+}
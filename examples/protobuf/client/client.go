@@ -5,6 +5,7 @@ import (
 	"github.com/osgochina/dmicro/drpc"
 	"github.com/osgochina/dmicro/drpc/message"
 	"github.com/osgochina/dmicro/drpc/proto/pbproto"
+	"github.com/osgochina/dmicro/examples/protobuf/pb"
 	"github.com/osgochina/dmicro/logger"
 	"time"
 )
@@ -14,23 +15,21 @@ func main() {
 	cli := drpc.NewEndpoint(drpc.EndpointConfig{Network: "unix", PrintDetail: true, RedialTimes: -1, RedialInterval: time.Second})
 	defer cli.Close()
 
-	cli.RoutePush(new(Push))
+	pb.RoutePush(cli.Router(), new(Push))
 
 	sess, stat := cli.Dial("127.0.0.1:9091", pbproto.NewPbProtoFunc())
 	if !stat.OK() {
 		logger.Fatalf(context.TODO(), "%v", stat)
 	}
+	math := pb.NewMathClient(sess)
 	for i := 0; i < 100; i++ {
-		var result int
-		stat = sess.Call("/math/add",
-			[]int{1, 2, 3, 4, 5},
-			&result,
+		result, stat := math.Add(&pb.AddArgs{Nums: []int32{1, 2, 3, 4, 5}},
 			message.WithSetMeta("author", "liuzhiming"),
-		).Status()
+		)
 		if !stat.OK() {
 			logger.Fatalf(context.TODO(), "%v", stat)
 		}
-		logger.Printf(context.TODO(), "result: %d", result)
+		logger.Printf(context.TODO(), "result: %d", result.Sum)
 		logger.Printf(context.TODO(), "Wait 10 seconds to receive the push...")
 		time.Sleep(time.Second * 1)
 	}
@@ -40,7 +39,7 @@ type Push struct {
 	drpc.PushCtx
 }
 
-func (that *Push) Status(arg *string) *drpc.Status {
-	logger.Printf(context.TODO(), "%s", *arg)
+func (that *Push) Status(arg *pb.StatusArgs) *drpc.Status {
+	logger.Printf(context.TODO(), "%s", arg.Msg)
 	return nil
 }
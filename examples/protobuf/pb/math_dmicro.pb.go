@@ -0,0 +1,104 @@
+// Code generated by protoc-gen-dmicro. DO NOT EDIT.
+// versions:
+// 	protoc-gen-dmicro v1.1.0
+// source: math.proto
+
+package pb
+
+import (
+	fmt "fmt"
+	drpc "github.com/osgochina/dmicro/drpc"
+	codec "github.com/osgochina/dmicro/drpc/codec"
+	message "github.com/osgochina/dmicro/drpc/message"
+)
+
+// Math服务的路由，使用 drpc.HTTPServiceMethodMapper 映射规则
+const (
+	MathAddServiceMethod = "/math/add"
+)
+
+// MathCall Math服务的控制器接口。
+// 实现该接口的结构体需要匿名嵌入 drpc.CallCtx，并且结构体名称为Math，注册的路由才和客户端一致，否则注册时panic
+type MathCall interface {
+	drpc.CallCtx
+	Add(arg *AddArgs) (*AddReply, *drpc.Status)
+}
+
+// RouteMathCall 把Math服务的控制器注册到路由
+func RouteMathCall(router *drpc.Router, ctrl MathCall, plugin ...drpc.Plugin) []string {
+	names := router.RouteCall(ctrl, plugin...)
+	file_math_proto_checkRoutes("MathCall", names, MathAddServiceMethod)
+	return names
+}
+
+// MathClient Math服务的客户端，消息使用protobuf编码
+type MathClient struct {
+	caller drpc.Caller
+}
+
+// NewMathClient 创建Math服务的客户端，caller可以是 *client.RpcClient 或者 drpc.Session
+func NewMathClient(caller drpc.Caller) *MathClient {
+	return &MathClient{caller: caller}
+}
+
+// Add 调用Add方法
+func (that *MathClient) Add(arg *AddArgs, setting ...message.MsgSetting) (*AddReply, *drpc.Status) {
+	result := new(AddReply)
+	stat := that.caller.Call(MathAddServiceMethod, arg, result, file_math_proto_callSetting(setting)...).Status()
+	return result, stat
+}
+
+// Push服务的路由，使用 drpc.HTTPServiceMethodMapper 映射规则
+const (
+	PushStatusServiceMethod = "/push/status"
+)
+
+// Push Push服务的push控制器接口。
+// 实现该接口的结构体需要匿名嵌入 drpc.PushCtx，并且结构体名称为Push，注册的路由才和客户端一致，否则注册时panic
+type Push interface {
+	drpc.PushCtx
+	Status(arg *StatusArgs) *drpc.Status
+}
+
+// RoutePush 把Push服务的push控制器注册到路由
+func RoutePush(router *drpc.Router, ctrl Push, plugin ...drpc.Plugin) []string {
+	names := router.RoutePush(ctrl, plugin...)
+	file_math_proto_checkRoutes("Push", names, PushStatusServiceMethod)
+	return names
+}
+
+// PushClient Push服务的客户端，消息使用protobuf编码
+type PushClient struct {
+	caller drpc.Caller
+}
+
+// NewPushClient 创建Push服务的客户端，caller可以是 *client.RpcClient 或者 drpc.Session
+func NewPushClient(caller drpc.Caller) *PushClient {
+	return &PushClient{caller: caller}
+}
+
+// Status 推送Status消息
+func (that *PushClient) Status(arg *StatusArgs, setting ...message.MsgSetting) *drpc.Status {
+	return that.caller.Push(PushStatusServiceMethod, arg, file_math_proto_callSetting(setting)...)
+}
+
+// file_math_proto_callSetting 使用protobuf编码消息，调用方传入的设置优先
+func file_math_proto_callSetting(setting []message.MsgSetting) []message.MsgSetting {
+	return append([]message.MsgSetting{drpc.WithBodyCodec(codec.ProtobufName)}, setting...)
+}
+
+// file_math_proto_checkRoutes 检查控制器注册的路由包含所有生成的路由，结构体名称不一致或者修改了路由映射规则时panic
+func file_math_proto_checkRoutes(ctrlName string, names []string, serviceMethods ...string) {
+	for _, serviceMethod := range serviceMethods {
+		found := false
+		for _, name := range names {
+			if name == serviceMethod {
+				found = true
+				break
+			}
+		}
+		if !found {
+			panic(fmt.Sprintf("%s: route %s is not registered, got %v, check the name of the controller struct and use drpc.HTTPServiceMethodMapper", ctrlName, serviceMethod, names))
+		}
+	}
+}
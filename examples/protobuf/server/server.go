@@ -7,6 +7,7 @@ import (
 	"github.com/osgochina/dmicro/drpc"
 	"github.com/osgochina/dmicro/drpc/plugin/ignorecase"
 	"github.com/osgochina/dmicro/drpc/proto/pbproto"
+	"github.com/osgochina/dmicro/examples/protobuf/pb"
 	"github.com/osgochina/dmicro/utils/graceful"
 	"time"
 )
@@ -19,17 +20,16 @@ func main() {
 		PrintDetail: true,
 	}, ignorecase.NewIgnoreCase())
 
-	svr.RouteCall(new(Math))
+	pb.RouteMathCall(svr.Router(), new(Math))
 
 	// broadcast per 5s
 	go func() {
 		for {
 			time.Sleep(time.Second * 5)
 			svr.RangeSession(func(sess drpc.Session) bool {
-				sess.Push(
-					"/push/status",
-					fmt.Sprintf("this is a broadcast, server time: %v", time.Now()),
-				)
+				pb.NewPushClient(sess).Status(&pb.StatusArgs{
+					Msg: fmt.Sprintf("this is a broadcast, server time: %v", time.Now()),
+				})
 				return true
 			})
 		}
@@ -42,14 +42,14 @@ type Math struct {
 	drpc.CallCtx
 }
 
-func (m *Math) Add(arg *[]int) (int, *drpc.Status) {
+func (m *Math) Add(arg *pb.AddArgs) (*pb.AddReply, *drpc.Status) {
 	// test meta
 	glog.Infof(context.TODO(), "author: %s", m.PeekMeta("author"))
 	// add
-	var r int
-	for _, a := range *arg {
+	var r int32
+	for _, a := range arg.Nums {
 		r += a
 	}
 	// response
-	return r, nil
+	return &pb.AddReply{Sum: r}, nil
 }
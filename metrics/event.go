@@ -0,0 +1,30 @@
+package metrics
+
+// 组件通过事件总线发布的指标事件。
+// 事件名称和参数的key在这里定义，发布事件的组件和监听事件的metrics实现都只依赖本包，互相之间没有依赖。
+
+// 熔断器状态变化事件
+const (
+	// OnBreakerStateChangeEvent 熔断器状态变化的事件名称
+	OnBreakerStateChangeEvent = "breaker_state_change"
+	// BreakerKeyName 熔断器名称，string
+	BreakerKeyName = "breaker"
+	// BreakerKeyFrom 变化前的状态名称，string
+	BreakerKeyFrom = "from"
+	// BreakerKeyTo 变化后的状态名称，string
+	BreakerKeyTo = "to"
+	// BreakerKeyState 变化后的状态值，int，0关闭，1打开，2半开
+	BreakerKeyState = "state"
+)
+
+// 自适应并发限制的采样周期事件，每个采样周期结束时，限制发生变化或者周期内有请求被拒绝时发布
+const (
+	// OnLimiterWindowEvent 采样周期结束的事件名称
+	OnLimiterWindowEvent = "adaptive_limit_window"
+	// LimiterKeyName 限制器名称，string
+	LimiterKeyName = "limiter"
+	// LimiterKeyLimit 当前的并发限制，int
+	LimiterKeyLimit = "limit"
+	// LimiterKeyRejected 周期内被拒绝的请求数，int64
+	LimiterKeyRejected = "rejected"
+)
@@ -1,6 +1,9 @@
 package metrics
 
-import "github.com/osgochina/dmicro/drpc"
+import (
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/eventbus"
+)
 
 type Options struct {
 	Host        string
@@ -8,6 +11,8 @@ type Options struct {
 	Path        string
 	ServiceName string
 	Plugins     []drpc.Plugin
+	// 监听熔断器等组件事件的事件总线，为空则使用默认事件总线
+	EventBus *eventbus.EventBus
 }
 
 func OptHost(host string) Option {
@@ -39,3 +44,9 @@ func OptPlugin(plugin drpc.Plugin) Option {
 		options.Plugins = append(options.Plugins, plugin)
 	}
 }
+
+func OptEventBus(bus *eventbus.EventBus) Option {
+	return func(options *Options) {
+		options.EventBus = bus
+	}
+}
@@ -0,0 +1,38 @@
+package prometheus
+
+import (
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/osgochina/dmicro/eventbus"
+	"github.com/osgochina/dmicro/metrics"
+)
+
+var clientNamespace = "rpc_client"
+
+// 客户端熔断器当前状态，0关闭，1打开，2半开
+var metricsBreakerState = NewGaugeVec(&GaugeVecOpts{
+	Namespace: clientNamespace,
+	Subsystem: "breaker",
+	Name:      "state",
+	Help:      "rpc client circuit breaker state, 0 closed, 1 open, 2 half-open.",
+	Labels:    []string{"name", "breaker"},
+})
+
+// 客户端熔断器状态变化次数统计
+var metricsBreakerTransitions = NewCounterVec(&CounterVecOpts{
+	Namespace: clientNamespace,
+	Subsystem: "breaker",
+	Name:      "transitions_total",
+	Help:      "rpc client circuit breaker state transitions count.",
+	Labels:    []string{"name", "breaker", "from", "to"},
+})
+
+// 监听熔断器状态变化事件，熔断器设置了事件总线时，需要通过 metrics.OptEventBus 设置相同的事件总线
+func (that *PromMetrics) listenBreaker() {
+	that.listen(metrics.OnBreakerStateChangeEvent, func(e eventbus.IEvent) error {
+		name := that.options.ServiceName
+		b := gconv.String(e.Get(metrics.BreakerKeyName))
+		metricsBreakerState.Set(gconv.Float64(e.Get(metrics.BreakerKeyState)), name, b)
+		metricsBreakerTransitions.Inc(name, b, gconv.String(e.Get(metrics.BreakerKeyFrom)), gconv.String(e.Get(metrics.BreakerKeyTo)))
+		return nil
+	})
+}
@@ -0,0 +1,29 @@
+package prometheus
+
+import (
+	"errors"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/client/breaker"
+	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/eventbus"
+	"github.com/osgochina/dmicro/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"testing"
+)
+
+func TestListenBreaker(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		bus := eventbus.New("breaker_metrics")
+		pm := NewPromMetrics(metrics.OptServiceName("breaker_metrics"), metrics.OptEventBus(bus))
+		pm.listenBreaker()
+
+		g := breaker.NewGroup(breaker.OptConsecutiveFailures(1), breaker.OptEventBus(bus))
+		b := g.Get(g.Name("foo", "127.0.0.1:1", ""))
+		report, stat := b.Allow()
+		t.AssertNil(stat)
+		report(drpc.NewStatus(drpc.CodeDialFailed, "", errors.New("fail")))
+
+		state := metricsBreakerState.(*gaugeVec).gauge.WithLabelValues("breaker_metrics", b.Name())
+		t.Assert(testutil.ToFloat64(state), float64(breaker.StateOpen))
+	})
+}
@@ -0,0 +1,38 @@
+package prometheus
+
+import (
+	"github.com/gogf/gf/v2/util/gconv"
+	"github.com/osgochina/dmicro/eventbus"
+	"github.com/osgochina/dmicro/metrics"
+)
+
+// 服务端自适应并发限制的当前限制
+var metricsLimiterLimit = NewGaugeVec(&GaugeVecOpts{
+	Namespace: serverNamespace,
+	Subsystem: "limiter",
+	Name:      "limit",
+	Help:      "rpc server adaptive concurrency limit.",
+	Labels:    []string{"name", "limiter"},
+})
+
+// 服务端因超出并发限制而拒绝的请求数统计
+var metricsLimiterRejected = NewCounterVec(&CounterVecOpts{
+	Namespace: serverNamespace,
+	Subsystem: "limiter",
+	Name:      "rejected_total",
+	Help:      "rpc server calls rejected by adaptive concurrency limit count.",
+	Labels:    []string{"name", "limiter"},
+})
+
+// 监听自适应并发限制的采样周期事件，插件设置了事件总线时，需要通过 metrics.OptEventBus 设置相同的事件总线
+func (that *PromMetrics) listenLimiter() {
+	that.listen(metrics.OnLimiterWindowEvent, func(e eventbus.IEvent) error {
+		name := that.options.ServiceName
+		limiter := gconv.String(e.Get(metrics.LimiterKeyName))
+		metricsLimiterLimit.Set(gconv.Float64(e.Get(metrics.LimiterKeyLimit)), name, limiter)
+		if rejected := gconv.Float64(e.Get(metrics.LimiterKeyRejected)); rejected > 0 {
+			metricsLimiterRejected.Add(rejected, name, limiter)
+		}
+		return nil
+	})
+}
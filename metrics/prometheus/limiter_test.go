@@ -0,0 +1,32 @@
+package prometheus
+
+import (
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/eventbus"
+	"github.com/osgochina/dmicro/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"testing"
+)
+
+func TestListenLimiter(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		bus := eventbus.New("limiter_metrics")
+		pm := NewPromMetrics(metrics.OptServiceName("limiter_metrics"), metrics.OptEventBus(bus))
+		pm.listenLimiter()
+
+		t.AssertNil(bus.Publish(eventbus.NewEvent(metrics.OnLimiterWindowEvent, map[interface{}]interface{}{
+			metrics.LimiterKeyName:     "foo",
+			metrics.LimiterKeyLimit:    8,
+			metrics.LimiterKeyRejected: int64(3),
+		})))
+		t.AssertNil(bus.Publish(eventbus.NewEvent(metrics.OnLimiterWindowEvent, map[interface{}]interface{}{
+			metrics.LimiterKeyName:     "foo",
+			metrics.LimiterKeyLimit:    8,
+			metrics.LimiterKeyRejected: int64(0),
+		})))
+		limit := metricsLimiterLimit.(*gaugeVec).gauge.WithLabelValues("limiter_metrics", "foo")
+		t.Assert(testutil.ToFloat64(limit), float64(8))
+		rejected := metricsLimiterRejected.(*counterVec).counter.WithLabelValues("limiter_metrics", "foo")
+		t.Assert(testutil.ToFloat64(rejected), float64(3))
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/gogf/gf/v2/container/gtype"
 	"github.com/osgochina/dmicro/drpc"
+	"github.com/osgochina/dmicro/eventbus"
 	"github.com/osgochina/dmicro/logger"
 	"github.com/osgochina/dmicro/metrics"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -70,6 +71,8 @@ func (that *PromMetrics) Enabled() bool {
 func (that *PromMetrics) Start() {
 	once.Do(func() {
 		enabled.Cas(false, true)
+		that.listenBreaker()
+		that.listenLimiter()
 		go func() {
 			http.Handle(that.options.Path, promhttp.Handler())
 			addr := fmt.Sprintf("%s:%d", that.options.Host, that.options.Port)
@@ -81,6 +84,15 @@ func (that *PromMetrics) Start() {
 	})
 }
 
+// 监听事件，设置了事件总线时监听该总线，否则监听默认事件总线
+func (that *PromMetrics) listen(name string, listener eventbus.ListenerFunc) {
+	if that.options.EventBus != nil {
+		_ = that.options.EventBus.Listen(name, listener)
+		return
+	}
+	_ = eventbus.Listen(name, listener)
+}
+
 // Shutdown prometheus 组件不需要停止
 func (that *PromMetrics) Shutdown() {
 
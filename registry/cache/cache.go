@@ -284,14 +284,16 @@ func (that *cache) update(res *registry.Result) {
 	}
 	switch res.Action {
 	case registry.Create, registry.Update:
+		// 事件中的服务可能同时发给了其他监听者，复制后再修改
+		updated := registryUtil.CopyService(res.Service)
 		// 如果service为nil，则此次触发的事件，更新的信息不存在缓存中，则表示需要把该信息写入缓存
 		if service == nil {
-			that.set(res.Service.Name, append(services, res.Service))
+			that.set(updated.Name, append(services, updated))
 			return
 		}
 		for _, cur := range service.Nodes {
 			var seen bool
-			for _, node := range res.Service.Nodes {
+			for _, node := range updated.Nodes {
 				if cur.Id == node.Id {
 					seen = true
 					break
@@ -299,12 +301,12 @@ func (that *cache) update(res *registry.Result) {
 			}
 			// 如果node节点的配置信息不存在缓存中，则需要把缓存中的节点追加到配置中
 			if !seen {
-				res.Service.Nodes = append(res.Service.Nodes, cur)
+				updated.Nodes = append(updated.Nodes, cur)
 			}
 		}
 		// 更新配置信息到缓存
-		services[index] = res.Service
-		that.set(res.Service.Name, services)
+		services[index] = updated
+		that.set(updated.Name, services)
 	case registry.Delete:
 		//如果已经删除，则不需要在执行了
 		if service == nil {
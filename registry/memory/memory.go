@@ -122,6 +122,7 @@ func (that *memRegistry) Register(s *registry.Service, opts ...registry.Register
 				Node: &registry.Node{
 					Id:       n.Id,
 					Address:  n.Address,
+					Paths:    n.Paths,
 					Metadata: metadata,
 				},
 				TTL:      options.TTL,
@@ -61,7 +61,8 @@ func recordToService(r *record) *registry.Service {
 		nodes[i] = &registry.Node{
 			Id:       n.Id,
 			Address:  n.Address,
-			Metadata: metadata,
+			Paths:    n.Paths,
+			Metadata: md,
 		}
 		i++
 	}
@@ -12,10 +12,14 @@ import (
 	"time"
 )
 
+// MetadataIdempotent 节点元数据中幂等路由列表的key，多个路由以逗号分隔
+const MetadataIdempotent = "idempotent"
+
 type PluginRegistry struct {
 	registry       Registry
 	service        *Service
 	allApis        *garray.StrArray
+	idempotentApis *garray.StrArray
 	addr           net.Addr
 	registered     bool
 	mu             sync.RWMutex
@@ -31,9 +35,10 @@ var _ drpc.BeforeCloseEndpointPlugin = new(PluginRegistry)
 // NewRegistryPlugin 创建服务注册插件
 func NewRegistryPlugin(registry Registry) *PluginRegistry {
 	return &PluginRegistry{
-		registry: registry,
-		allApis:  garray.NewStrArray(),
-		exit:     make(chan bool),
+		registry:       registry,
+		allApis:        garray.NewStrArray(),
+		idempotentApis: garray.NewStrArray(),
+		exit:           make(chan bool),
 	}
 }
 
@@ -43,6 +48,9 @@ func (that *PluginRegistry) Name() string {
 
 func (that *PluginRegistry) AfterRegRouter(handler *drpc.Handler) error {
 	that.allApis.Append(handler.Name())
+	if handler.IsIdempotent() {
+		that.idempotentApis.Append(handler.Name())
+	}
 	return nil
 }
 
@@ -140,6 +148,9 @@ func (that *PluginRegistry) Register() error {
 	}
 	node.Metadata["registry"] = that.registry.String()
 	node.Metadata["server"] = that.serviceName
+	if that.idempotentApis.Len() > 0 {
+		node.Metadata[MetadataIdempotent] = that.idempotentApis.Join(",")
+	}
 
 	svr := &Service{
 		Name:    that.serviceName,
@@ -8,14 +8,19 @@ import (
 
 // 服务选择器
 type registrySelector struct {
-	so Options
-	rc cache.Cache
+	so    Options
+	rc    cache.Cache
+	od    *outlierDetector
+	state *State
+	// 默认的节点选择策略，有状态的策略使用选择器的状态创建
+	strategy Strategy
 }
 
 // NewSelector 创建选择器
 func NewSelector(opts ...Option) Selector {
 	sOpt := Options{
-		Strategy: Random,
+		Strategy:     Random,
+		HashStrategy: RingHash,
 	}
 
 	for _, opt := range opts {
@@ -28,7 +33,7 @@ func NewSelector(opts ...Option) Selector {
 	s := &registrySelector{
 		so: sOpt,
 	}
-	s.rc = s.newCache()
+	s.reset()
 	return s
 }
 
@@ -38,7 +43,7 @@ func (that *registrySelector) Init(opts ...Option) error {
 		o(&that.so)
 	}
 	that.rc.Stop()
-	that.rc = that.newCache()
+	that.reset()
 
 	return nil
 }
@@ -51,23 +56,45 @@ func (that *registrySelector) Options() Options {
 // Select 选择服务
 func (that *registrySelector) Select(service string, opts ...SelectOption) (Next, error) {
 	sOpts := SelectOptions{
-		Strategy: that.so.Strategy,
+		Strategy: that.strategy,
 	}
 	for _, opt := range opts {
 		opt(&sOpts)
 	}
+	// 传入了哈希key，使用一致性哈希策略
+	if len(sOpts.HashKey) > 0 {
+		hashStrategy := that.so.HashStrategy
+		if hashStrategy == nil {
+			hashStrategy = RingHash
+		}
+		sOpts.StrategyFactory = hashStrategy(sOpts.HashKey)
+	}
+	if sOpts.StrategyFactory != nil {
+		sOpts.Strategy = sOpts.StrategyFactory(that.state)
+	}
 	// 通过缓存获取到服务列表
 	services, err := that.rc.GetService(service)
 	if err != nil {
 		if err == registry.ErrNotFound {
+			// 服务的节点已经全部下线
+			that.od.reset(service)
+			that.state.reset(service)
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
+	// 记录缓存中的全部节点，一致性哈希策略使用未经过滤的节点创建查找表。
+	// 缓存由注册表的监听更新，节点被删除后清理节点的统计信息和负载信息
+	if removed := that.state.setRegistered(service, services); len(removed) > 0 {
+		that.od.remove(service, removed)
+		that.state.remove(service, removed)
+	}
 	// 过滤服务
 	for _, filter := range sOpts.Filters {
 		services = filter(services)
 	}
+	// 过滤被剔除的节点
+	services = that.od.filter(service, services)
 	// 没有可用的服务
 	if len(services) == 0 {
 		return nil, ErrNoneAvailable
@@ -76,12 +103,43 @@ func (that *registrySelector) Select(service string, opts ...SelectOption) (Next
 	return sOpts.Strategy(services), nil
 }
 
-// Mark 设置针对节点的成功或错误
-func (that *registrySelector) Mark(_ string, _ *registry.Node, _ error) {
+var _ OutlierSelector = (*registrySelector)(nil)
+
+// GetService 从缓存中获取服务的全部节点，不经过过滤，被剔除的节点也会返回
+func (that *registrySelector) GetService(service string) ([]*registry.Service, error) {
+	services, err := that.rc.GetService(service)
+	if err != nil {
+		if err == registry.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return services, nil
+}
+
+// Ejected 节点当前是否处于剔除期内，试探期内的节点不算作被剔除
+func (that *registrySelector) Ejected(service string, node *registry.Node) bool {
+	return that.od.ejected(service, node)
+}
+
+// Mark 设置针对节点的成功或错误，节点连续失败会被暂时剔除
+func (that *registrySelector) Mark(service string, node *registry.Node, err error) {
+	that.od.mark(service, node, err, 0)
+}
+
+// MarkLatency 设置针对节点的成功或错误，同时记录本次调用的耗时
+func (that *registrySelector) MarkLatency(service string, node *registry.Node, err error, latency time.Duration) {
+	that.od.mark(service, node, err, latency)
+}
+
+// Begin 记录节点开始处理一个请求，返回的方法在请求结束时调用
+func (that *registrySelector) Begin(service string, node *registry.Node) (end func()) {
+	return that.state.Begin(service, node)
 }
 
 // Reset 重置服务的状态
-func (that *registrySelector) Reset(_ string) {
+func (that *registrySelector) Reset(service string) {
+	that.od.reset(service)
 }
 
 // Close 关闭选择器
@@ -105,3 +163,17 @@ func (that *registrySelector) newCache() cache.Cache {
 	}
 	return cache.New(that.so.Registry, opts...)
 }
+
+// 创建缓存以及节点状态
+func (that *registrySelector) reset() {
+	that.rc = that.newCache()
+	that.od = newOutlierDetector(that.so.MaxFailures, that.so.EjectBackoff)
+	that.state = newState(that.od)
+	that.strategy = that.so.Strategy
+	if that.so.StrategyFactory != nil {
+		that.strategy = that.so.StrategyFactory(that.state)
+	}
+	if that.strategy == nil {
+		that.strategy = Random
+	}
+}
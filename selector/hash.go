@@ -0,0 +1,231 @@
+package selector
+
+import (
+	"github.com/osgochina/dmicro/registry"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// 哈希环上每个权重对应的虚拟节点数量
+	ringReplicas = 160
+	// maglev 查找表的大小，必须是质数
+	maglevTableSize = 65537
+)
+
+// 一致性哈希负载均衡器
+type hashBalancer interface {
+	// 选择key对应的节点，不可用的节点被跳过，没有可用的节点时返回nil
+	pick(hash uint64, available map[string]*registry.Node) *registry.Node
+}
+
+// 缓存的一致性哈希负载均衡器，节点列表不变时复用
+type cachedBalancer struct {
+	sign     string
+	balancer hashBalancer
+}
+
+// RingHash 使用一致性哈希环选择节点，相同key的请求总是落在同一个节点上，
+// 节点上下线时只有相邻区间的key会被重新映射。节点的权重决定虚拟节点的数量
+func RingHash(key string) StrategyFactory {
+	return func(state *State) Strategy {
+		return func(services []*registry.Service) Next {
+			return hashNext("ring", key, services, state, newRing)
+		}
+	}
+}
+
+// Maglev 使用maglev哈希选择节点，相比哈希环分布更均匀，节点上下线时的重新映射略多
+func Maglev(key string) StrategyFactory {
+	return func(state *State) Strategy {
+		return func(services []*registry.Service) Next {
+			return hashNext("maglev", key, services, state, newMaglev)
+		}
+	}
+}
+
+// 生成按key选择节点的Next方法
+func hashNext(kind string, key string, services []*registry.Service, state *State, build func([]*registry.Node) hashBalancer) Next {
+	nodes := allNodes(services)
+	if len(nodes) == 0 {
+		return func() (*registry.Node, error) {
+			return nil, ErrNoneAvailable
+		}
+	}
+	name := serviceName(services)
+	// 使用注册表中的全部节点创建查找表，被过滤或者剔除的节点在查找时跳过，
+	// 节点的剔除和试探不会导致查找表重建，key也不会在节点之间来回迁移
+	all := state.registeredNodes(name)
+	if len(all) == 0 {
+		all = nodes
+	}
+	balancer := state.loadBalancer(name, kind, all, build)
+	available := make(map[string]*registry.Node, len(nodes))
+	for _, node := range nodes {
+		available[nodeKey(node)] = node
+	}
+	hash := hashKey(key)
+	node := balancer.pick(hash, available)
+	if node == nil {
+		// 可用的节点不在查找表中，注册表中的节点已经发生变化
+		node = nodes[hash%uint64(len(nodes))]
+	}
+	return func() (*registry.Node, error) {
+		return node, nil
+	}
+}
+
+// 获取负载均衡器，节点列表发生变化时重新创建
+func (that *State) loadBalancer(service string, kind string, nodes []*registry.Node, build func([]*registry.Node) hashBalancer) hashBalancer {
+	sign := nodesSign(nodes)
+	that.mu.Lock()
+	cached, ok := that.balancers[service][kind]
+	that.mu.Unlock()
+	if ok && cached.sign == sign {
+		return cached.balancer
+	}
+	// 创建查找表的耗时较长，不在锁内进行
+	balancer := build(nodes)
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	balancers, ok := that.balancers[service]
+	if !ok {
+		balancers = make(map[string]*cachedBalancer)
+		that.balancers[service] = balancers
+	}
+	balancers[kind] = &cachedBalancer{sign: sign, balancer: balancer}
+	return balancer
+}
+
+// 节点列表的签名，节点、地址或权重发生变化时签名改变
+func nodesSign(nodes []*registry.Node) string {
+	keys := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		keys = append(keys, nodeKey(node)+"@"+node.Address+"#"+strconv.Itoa(nodeWeight(node)))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// 计算字符串的哈希值
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return mix64(h.Sum64())
+}
+
+// fnv对相近字符串的分布不够均匀，再做一次混淆
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// 按节点标识排序，保证不同客户端生成相同的结构
+func sortedNodes(nodes []*registry.Node) []*registry.Node {
+	sorted := make([]*registry.Node, len(nodes))
+	copy(sorted, nodes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return nodeKey(sorted[i]) < nodeKey(sorted[j])
+	})
+	return sorted
+}
+
+// 一致性哈希环
+type ring struct {
+	hashes []uint64
+	nodes  []*registry.Node
+}
+
+func newRing(nodes []*registry.Node) hashBalancer {
+	type point struct {
+		hash uint64
+		node *registry.Node
+	}
+	var points []point
+	for _, node := range sortedNodes(nodes) {
+		key := nodeKey(node)
+		replicas := ringReplicas * nodeWeight(node)
+		for i := 0; i < replicas; i++ {
+			points = append(points, point{hash: hashKey(key + "#" + strconv.Itoa(i)), node: node})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].hash < points[j].hash
+	})
+	r := &ring{
+		hashes: make([]uint64, len(points)),
+		nodes:  make([]*registry.Node, len(points)),
+	}
+	for i, p := range points {
+		r.hashes[i] = p.hash
+		r.nodes[i] = p.node
+	}
+	return r
+}
+
+// 顺时针找到第一个可用节点的虚拟节点
+func (that *ring) pick(hash uint64, available map[string]*registry.Node) *registry.Node {
+	i := sort.Search(len(that.hashes), func(i int) bool {
+		return that.hashes[i] >= hash
+	})
+	for j := 0; j < len(that.nodes); j++ {
+		if node, ok := available[nodeKey(that.nodes[(i+j)%len(that.nodes)])]; ok {
+			return node
+		}
+	}
+	return nil
+}
+
+// maglev 查找表
+type maglev struct {
+	table []*registry.Node
+}
+
+func newMaglev(nodes []*registry.Node) hashBalancer {
+	nodes = sortedNodes(nodes)
+	n := len(nodes)
+	offsets := make([]uint64, n)
+	skips := make([]uint64, n)
+	for i, node := range nodes {
+		key := nodeKey(node)
+		offsets[i] = hashKey(key+"#offset") % maglevTableSize
+		skips[i] = hashKey(key+"#skip")%(maglevTableSize-1) + 1
+	}
+	var (
+		table  = make([]*registry.Node, maglevTableSize)
+		next   = make([]uint64, n)
+		filled int
+	)
+	for {
+		for i := 0; i < n; i++ {
+			// 按节点的排列顺序找到第一个空位
+			c := (offsets[i] + next[i]*skips[i]) % maglevTableSize
+			for table[c] != nil {
+				next[i]++
+				c = (offsets[i] + next[i]*skips[i]) % maglevTableSize
+			}
+			table[c] = nodes[i]
+			next[i]++
+			filled++
+			if filled == maglevTableSize {
+				return &maglev{table: table}
+			}
+		}
+	}
+}
+
+// 从key对应的位置开始找到第一个可用节点
+func (that *maglev) pick(hash uint64, available map[string]*registry.Node) *registry.Node {
+	for i := uint64(0); i < maglevTableSize; i++ {
+		if node, ok := available[nodeKey(that.table[(hash+i)%maglevTableSize])]; ok {
+			return node
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package selector
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/registry"
+	"github.com/osgochina/dmicro/registry/memory"
+	"testing"
+)
+
+func hashServices(name string, n int) []*registry.Service {
+	nodes := make([]*registry.Node, 0, n)
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, &registry.Node{Id: fmt.Sprintf("node-%d", i), Address: fmt.Sprintf("127.0.0.1:%d", 8000+i)})
+	}
+	return []*registry.Service{{Name: name, Nodes: nodes}}
+}
+
+func testHashStrategy(t *gtest.T, name string, strategy func(key string) StrategyFactory) {
+	state := NewState()
+	before := hashServices(name, 5)
+	after := hashServices(name, 6)
+	var (
+		moved  int
+		counts = make(map[string]int)
+		total  = 3000
+		picked = make([]string, total)
+	)
+	for i := 0; i < total; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		n1, err := strategy(key)(state)(before)()
+		t.AssertNil(err)
+		// 相同的key总是选择相同的节点
+		n2, _ := strategy(key)(state)(before)()
+		t.Assert(n1.Id, n2.Id)
+		counts[n1.Id]++
+		picked[i] = n1.Id
+	}
+	for i := 0; i < total; i++ {
+		n3, _ := strategy(fmt.Sprintf("user-%d", i))(state)(after)()
+		if n3.Id != picked[i] {
+			moved++
+			// 新增节点时，key只会迁移到新节点上
+			t.Assert(n3.Id, "node-5")
+		}
+	}
+	// 每个节点都分到了请求
+	t.Assert(len(counts), 5)
+	// 新增一个节点，大约1/6的key被重新映射
+	t.AssertLT(moved, total/3)
+	t.AssertGT(moved, 0)
+}
+
+func TestRingHash(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		testHashStrategy(t, "ring-hash", RingHash)
+	})
+}
+
+func TestMaglev(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		testHashStrategy(t, "maglev-hash", Maglev)
+	})
+}
+
+func TestSelectWithHashKey(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		r := memory.NewRegistry()
+		for _, service := range hashServices("hash-select", 4) {
+			t.AssertNil(r.Register(service))
+		}
+		s := NewSelector(OptRegistry(r), OptStrategyFactory(RoundRobin))
+		defer s.Close()
+
+		var first string
+		for i := 0; i < 10; i++ {
+			next, err := s.Select("hash-select", OptWithHashKey("user-1"))
+			t.AssertNil(err)
+			node, err := next()
+			t.AssertNil(err)
+			if i == 0 {
+				first = node.Id
+			}
+			t.Assert(node.Id, first)
+		}
+	})
+}
+
+func TestHashSkipEjectedNode(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		r := memory.NewRegistry()
+		for _, service := range hashServices("hash-eject", 4) {
+			t.AssertNil(r.Register(service))
+		}
+		s := NewSelector(OptRegistry(r), OptMaxFailures(1), OptHashStrategy(Maglev))
+		defer s.Close()
+		rs := s.(*registrySelector)
+
+		pick := func(key string) *registry.Node {
+			next, err := s.Select("hash-eject", OptWithHashKey(key))
+			t.AssertNil(err)
+			node, err := next()
+			t.AssertNil(err)
+			return node
+		}
+		picked := make(map[string]string)
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("user-%d", i)
+			picked[key] = pick(key).Id
+		}
+		balancer := rs.state.balancers["hash-eject"]["maglev"]
+
+		// 剔除一个节点，只有落在该节点上的key被重新映射，查找表不会重建
+		ejected := pick("user-0")
+		s.Mark("hash-eject", ejected, errors.New("fail"))
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("user-%d", i)
+			node := pick(key)
+			if picked[key] == ejected.Id {
+				t.AssertNE(node.Id, ejected.Id)
+				continue
+			}
+			t.Assert(node.Id, picked[key])
+		}
+		t.Assert(rs.state.balancers["hash-eject"]["maglev"] == balancer, true)
+
+		// 节点恢复后key回到原来的节点
+		s.Reset("hash-eject")
+		t.Assert(pick("user-0").Id, ejected.Id)
+	})
+}
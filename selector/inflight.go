@@ -0,0 +1,67 @@
+package selector
+
+import (
+	"github.com/osgochina/dmicro/registry"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 节点的负载信息
+type nodeLoad struct {
+	inflight int64 // 正在处理的请求数
+}
+
+// 获取节点的负载信息，不存在时创建
+func (that *State) loadOf(service string, node *registry.Node) *nodeLoad {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	nodes, ok := that.loads[service]
+	if !ok {
+		nodes = make(map[string]*nodeLoad)
+		that.loads[service] = nodes
+	}
+	key := nodeKey(node)
+	load, ok := nodes[key]
+	if !ok {
+		load = &nodeLoad{}
+		nodes[key] = load
+	}
+	return load
+}
+
+// 获取节点的负载信息，不存在时返回nil
+func (that *State) load(service string, node *registry.Node) *nodeLoad {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	return that.loads[service][nodeKey(node)]
+}
+
+// Begin 记录节点开始处理一个请求，返回的方法在请求结束时调用。请求的耗时通过 Mark 方法报告
+func (that *State) Begin(service string, node *registry.Node) (end func()) {
+	if node == nil {
+		return func() {}
+	}
+	load := that.loadOf(service, node)
+	atomic.AddInt64(&load.inflight, 1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			atomic.AddInt64(&load.inflight, -1)
+		})
+	}
+}
+
+// Inflight 获取节点正在处理的请求数
+func (that *State) Inflight(service string, node *registry.Node) int64 {
+	load := that.load(service, node)
+	if load == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&load.inflight)
+}
+
+// Latency 获取节点的平均延迟，数据来自 Mark 方法报告的调用耗时
+func (that *State) Latency(service string, node *registry.Node) time.Duration {
+	return that.od.latency(service, node)
+}
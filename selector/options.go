@@ -3,6 +3,7 @@ package selector
 import (
 	"context"
 	"github.com/osgochina/dmicro/registry"
+	"time"
 )
 
 // Options selector的配置参数
@@ -11,6 +12,14 @@ type Options struct {
 	Registry registry.Registry
 	// 节点选择策略引擎
 	Strategy Strategy
+	// 有状态的节点选择策略，设置后优先于 Strategy
+	StrategyFactory StrategyFactory
+	// 节点连续失败多少次后被剔除
+	MaxFailures int
+	// 节点被剔除的时长，参数是节点连续被剔除的次数
+	EjectBackoff func(ejections int) time.Duration
+	// 一致性哈希策略，调用select方法时传入了哈希key才会使用
+	HashStrategy func(key string) StrategyFactory
 	// 扩展配置，可以添加自定义选项
 	Context context.Context
 }
@@ -21,6 +30,10 @@ type SelectOptions struct {
 	Filters []Filter
 	// 节点选择策略引擎
 	Strategy Strategy
+	// 有状态的节点选择策略，设置后优先于 Strategy
+	StrategyFactory StrategyFactory
+	// 一致性哈希的key，不为空时使用一致性哈希策略选择节点
+	HashKey string
 	// 扩展配置，可以添加自定义选项
 	Context context.Context
 }
@@ -42,6 +55,14 @@ func OptRegistry(r registry.Registry) Option {
 func OptStrategy(fn Strategy) Option {
 	return func(o *Options) {
 		o.Strategy = fn
+		o.StrategyFactory = nil
+	}
+}
+
+// OptStrategyFactory 设置有状态的节点策略引擎，例如 RoundRobin、LeastConn、P2C
+func OptStrategyFactory(fn StrategyFactory) Option {
+	return func(o *Options) {
+		o.StrategyFactory = fn
 	}
 }
 
@@ -56,5 +77,41 @@ func OptWithFilter(fn ...Filter) SelectOption {
 func OptWithStrategy(fn Strategy) SelectOption {
 	return func(o *SelectOptions) {
 		o.Strategy = fn
+		o.StrategyFactory = nil
+	}
+}
+
+// OptWithStrategyFactory 在调用select方法时候传入有状态的节点策略引擎，使用选择器的状态创建
+func OptWithStrategyFactory(fn StrategyFactory) SelectOption {
+	return func(o *SelectOptions) {
+		o.StrategyFactory = fn
+	}
+}
+
+// OptHashStrategy 设置一致性哈希策略，可选 RingHash 或 Maglev
+func OptHashStrategy(fn func(key string) StrategyFactory) Option {
+	return func(o *Options) {
+		o.HashStrategy = fn
+	}
+}
+
+// OptWithHashKey 在调用select方法时候传入一致性哈希的key，相同key的请求会落在同一个节点上
+func OptWithHashKey(key string) SelectOption {
+	return func(o *SelectOptions) {
+		o.HashKey = key
+	}
+}
+
+// OptMaxFailures 设置节点连续失败多少次后被剔除
+func OptMaxFailures(n int) Option {
+	return func(o *Options) {
+		o.MaxFailures = n
+	}
+}
+
+// OptEjectBackoff 设置节点被剔除的时长
+func OptEjectBackoff(fn func(ejections int) time.Duration) Option {
+	return func(o *Options) {
+		o.EjectBackoff = fn
 	}
 }
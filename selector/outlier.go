@@ -0,0 +1,253 @@
+package selector
+
+import (
+	"github.com/osgochina/dmicro/registry"
+	"github.com/osgochina/dmicro/utils/backoff"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// 默认连续失败多少次后剔除节点
+	defaultMaxFailures = 5
+	// 计算平均延迟时新样本所占的权重
+	latencyDecay = 0.3
+	// 试探期内节点被选中的最小概率
+	minProbeRatio = 0.1
+	// 节点长时间没有请求时，平均延迟按该时间窗口衰减，让变慢的节点有机会被重新探测
+	latencyDecayWindow = 10 * time.Second
+)
+
+// 默认的剔除时长，第一次剔除约2秒，之后逐步增加，最长2分钟
+func defaultEjectBackoff(ejections int) time.Duration {
+	return backoff.DoMul(ejections + 2)
+}
+
+// 节点的统计信息
+type nodeStats struct {
+	successes    uint64        // 成功次数
+	failures     uint64        // 失败次数
+	consecutive  int           // 连续失败次数
+	ejections    int           // 连续被剔除的次数
+	ejectedAt    time.Time     // 最近一次被剔除的时间
+	ejectedUntil time.Time     // 剔除的截止时间
+	latency      time.Duration // 平均延迟
+	latencyAt    time.Time     // 最后一次更新平均延迟的时间
+}
+
+// 被剔除的节点在剔除期满后进入试探期，试探期的长度与剔除时长相同，
+// 试探期内节点被选中的概率随时间线性增加
+func (that *nodeStats) passRatio(now time.Time) float64 {
+	if that.ejections == 0 {
+		return 1
+	}
+	if now.Before(that.ejectedUntil) {
+		return 0
+	}
+	window := that.ejectedUntil.Sub(that.ejectedAt)
+	elapsed := now.Sub(that.ejectedUntil)
+	if window <= 0 || elapsed >= window {
+		return 1
+	}
+	ratio := float64(elapsed) / float64(window)
+	if ratio < minProbeRatio {
+		ratio = minProbeRatio
+	}
+	return ratio
+}
+
+// 节点异常检测，记录节点的调用结果，剔除连续失败的节点
+type outlierDetector struct {
+	mu sync.RWMutex
+	// 最大连续失败次数
+	maxFailures int
+	// 剔除时长
+	ejectBackoff func(ejections int) time.Duration
+	// map[service]map[nodeKey]*nodeStats
+	services map[string]map[string]*nodeStats
+}
+
+func newOutlierDetector(maxFailures int, ejectBackoff func(int) time.Duration) *outlierDetector {
+	if maxFailures <= 0 {
+		maxFailures = defaultMaxFailures
+	}
+	if ejectBackoff == nil {
+		ejectBackoff = defaultEjectBackoff
+	}
+	return &outlierDetector{
+		maxFailures:  maxFailures,
+		ejectBackoff: ejectBackoff,
+		services:     make(map[string]map[string]*nodeStats),
+	}
+}
+
+// 节点的唯一标识
+func nodeKey(node *registry.Node) string {
+	if len(node.Id) > 0 {
+		return node.Id
+	}
+	return node.Address
+}
+
+// 记录节点的调用结果
+func (that *outlierDetector) mark(service string, node *registry.Node, err error, latency time.Duration) {
+	if node == nil {
+		return
+	}
+	now := time.Now()
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	nodes, ok := that.services[service]
+	if !ok {
+		nodes = make(map[string]*nodeStats)
+		that.services[service] = nodes
+	}
+	key := nodeKey(node)
+	stats, ok := nodes[key]
+	if !ok {
+		stats = &nodeStats{}
+		nodes[key] = stats
+	}
+	if err == nil {
+		// 失败的请求可能很快返回，不计入平均延迟，由连续失败次数体现
+		if latency > 0 {
+			if stats.latency == 0 {
+				stats.latency = latency
+			} else {
+				stats.latency = time.Duration(latencyDecay*float64(latency) + (1-latencyDecay)*float64(stats.latency))
+			}
+			stats.latencyAt = now
+		}
+		stats.successes++
+		stats.consecutive = 0
+		// 试探期结束后仍然成功，节点完全恢复
+		if stats.ejections > 0 && stats.passRatio(now) >= 1 {
+			stats.ejections = 0
+		}
+		return
+	}
+	stats.failures++
+	stats.consecutive++
+	if stats.ejections > 0 {
+		// 剔除期内的失败来自剔除前发出的请求，不再重复剔除
+		if now.Before(stats.ejectedUntil) {
+			return
+		}
+		// 试探期已经结束，节点视为完全恢复
+		if stats.passRatio(now) >= 1 {
+			stats.ejections = 0
+		}
+	}
+	// 试探期内失败立即重新剔除，否则需要连续失败达到阈值
+	if stats.ejections == 0 && stats.consecutive < that.maxFailures {
+		return
+	}
+	stats.ejections++
+	stats.consecutive = 0
+	stats.ejectedAt = now
+	stats.ejectedUntil = now.Add(that.ejectBackoff(stats.ejections))
+}
+
+// 获取节点的平均延迟，长时间没有更新则逐渐衰减
+func (that *outlierDetector) latency(service string, node *registry.Node) time.Duration {
+	that.mu.RLock()
+	defer that.mu.RUnlock()
+	stats, ok := that.services[service][nodeKey(node)]
+	if !ok || stats.latency == 0 {
+		return 0
+	}
+	idle := time.Since(stats.latencyAt)
+	return time.Duration(float64(stats.latency) * math.Exp(-float64(idle)/float64(latencyDecayWindow)))
+}
+
+// 节点是否处于剔除期内
+func (that *outlierDetector) ejected(service string, node *registry.Node) bool {
+	that.mu.RLock()
+	defer that.mu.RUnlock()
+	stats, ok := that.services[service][nodeKey(node)]
+	if !ok {
+		return false
+	}
+	return stats.passRatio(time.Now()) == 0
+}
+
+// 获取节点的连续失败次数
+func (that *outlierDetector) consecutive(service string, node *registry.Node) int {
+	that.mu.RLock()
+	defer that.mu.RUnlock()
+	stats, ok := that.services[service][nodeKey(node)]
+	if !ok {
+		return 0
+	}
+	return stats.consecutive
+}
+
+// 清除服务的统计信息
+func (that *outlierDetector) reset(service string) {
+	that.mu.Lock()
+	delete(that.services, service)
+	that.mu.Unlock()
+}
+
+// 移除已经从注册表中删除的节点的统计信息
+func (that *outlierDetector) remove(service string, nodes []*registry.Node) {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	stats, ok := that.services[service]
+	if !ok {
+		return
+	}
+	for _, node := range nodes {
+		delete(stats, nodeKey(node))
+	}
+	if len(stats) == 0 {
+		delete(that.services, service)
+	}
+}
+
+// 过滤掉被剔除的节点，如果所有节点都被剔除，则返回原始列表，避免服务完全不可用
+func (that *outlierDetector) filter(service string, services []*registry.Service) []*registry.Service {
+	that.mu.RLock()
+	defer that.mu.RUnlock()
+	nodes, ok := that.services[service]
+	if !ok || len(nodes) == 0 {
+		return services
+	}
+	now := time.Now()
+	var (
+		result  = make([]*registry.Service, 0, len(services))
+		changed bool
+		total   int
+	)
+	for _, s := range services {
+		available := make([]*registry.Node, 0, len(s.Nodes))
+		for _, node := range s.Nodes {
+			stats, ok := nodes[nodeKey(node)]
+			if !ok {
+				available = append(available, node)
+				continue
+			}
+			ratio := stats.passRatio(now)
+			if ratio >= 1 || (ratio > 0 && rand.Float64() < ratio) {
+				available = append(available, node)
+				continue
+			}
+			changed = true
+		}
+		total += len(available)
+		if len(available) == len(s.Nodes) {
+			result = append(result, s)
+			continue
+		}
+		// 不能修改缓存中的服务信息，所以复制一份
+		cp := *s
+		cp.Nodes = available
+		result = append(result, &cp)
+	}
+	if !changed || total == 0 {
+		return services
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package selector
+
+import (
+	"errors"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/registry"
+	"testing"
+	"time"
+)
+
+func TestOutlierDetector(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		od := newOutlierDetector(3, func(int) time.Duration {
+			return 200 * time.Millisecond
+		})
+		bad := &registry.Node{Id: "bad", Address: "127.0.0.1:1"}
+		good := &registry.Node{Id: "good", Address: "127.0.0.1:2"}
+		services := []*registry.Service{{Name: "foo", Nodes: []*registry.Node{bad, good}}}
+		errFail := errors.New("fail")
+
+		// 未达到连续失败次数，不剔除
+		od.mark("foo", bad, errFail, time.Millisecond)
+		od.mark("foo", bad, errFail, time.Millisecond)
+		t.Assert(len(od.filter("foo", services)[0].Nodes), 2)
+		// 成功会清空连续失败次数
+		od.mark("foo", bad, nil, time.Millisecond)
+		od.mark("foo", bad, errFail, time.Millisecond)
+		od.mark("foo", bad, errFail, time.Millisecond)
+		t.Assert(len(od.filter("foo", services)[0].Nodes), 2)
+		od.mark("foo", bad, errFail, time.Millisecond)
+		filtered := od.filter("foo", services)
+		t.Assert(len(filtered[0].Nodes), 1)
+		t.Assert(filtered[0].Nodes[0].Id, "good")
+		// 不能修改原始的服务信息
+		t.Assert(len(services[0].Nodes), 2)
+
+		// 所有节点都被剔除时，返回原始列表
+		for i := 0; i < 3; i++ {
+			od.mark("foo", good, errFail, time.Millisecond)
+		}
+		t.Assert(len(od.filter("foo", services)[0].Nodes), 2)
+		od.reset("foo")
+
+		// 剔除期满后进入试探期，试探失败立即重新剔除
+		for i := 0; i < 3; i++ {
+			od.mark("foo", bad, errFail, time.Millisecond)
+		}
+		t.Assert(len(od.filter("foo", services)[0].Nodes), 1)
+		time.Sleep(250 * time.Millisecond)
+		od.mark("foo", bad, errFail, time.Millisecond)
+		t.Assert(len(od.filter("foo", services)[0].Nodes), 1)
+		// 试探期结束后节点完全恢复
+		time.Sleep(650 * time.Millisecond)
+		t.Assert(len(od.filter("foo", services)[0].Nodes), 2)
+		od.mark("foo", bad, nil, time.Millisecond)
+		t.Assert(od.services["foo"]["bad"].ejections, 0)
+	})
+}
+
+func TestSelectorMark(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		s := NewSelector(OptMaxFailures(1))
+		node := &registry.Node{Id: "n1", Address: "127.0.0.1:1"}
+		os, ok := s.(OutlierSelector)
+		t.Assert(ok, true)
+		os.MarkLatency("foo", node, errors.New("fail"), time.Millisecond)
+		t.Assert(os.Ejected("foo", node), true)
+		rs := s.(*registrySelector)
+		t.Assert(rs.od.services["foo"]["n1"].ejections, 1)
+		// 失败的请求不计入平均延迟
+		t.Assert(rs.od.services["foo"]["n1"].latency == 0, true)
+		os.MarkLatency("foo", node, nil, time.Millisecond)
+		t.Assert(rs.od.services["foo"]["n1"].latency == time.Millisecond, true)
+		// 不带耗时的 Mark 只记录调用结果
+		s.Mark("foo", node, nil)
+		t.Assert(rs.od.services["foo"]["n1"].latency == time.Millisecond, true)
+		s.Reset("foo")
+		t.Assert(len(rs.od.services), 0)
+		_ = s.Close()
+	})
+}
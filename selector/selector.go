@@ -3,6 +3,7 @@ package selector
 import (
 	"errors"
 	"github.com/osgochina/dmicro/registry"
+	"time"
 )
 
 var (
@@ -21,6 +22,19 @@ type Selector interface {
 	String() string
 }
 
+// OutlierSelector 支持异常节点剔除和负载统计的选择器，客户端通过类型断言检测
+type OutlierSelector interface {
+	Selector
+	// GetService 获取服务的全部节点，不经过过滤，被剔除的节点也会返回
+	GetService(service string) ([]*registry.Service, error)
+	// Ejected 节点当前是否处于剔除期内
+	Ejected(service string, node *registry.Node) bool
+	// MarkLatency 设置针对节点的成功或错误，同时记录本次调用的耗时
+	MarkLatency(service string, node *registry.Node, err error, latency time.Duration)
+	// Begin 记录节点开始处理一个请求，返回的方法在请求结束时调用
+	Begin(service string, node *registry.Node) (end func())
+}
+
 // Next 获取可用的节点
 type Next func() (*registry.Node, error)
 
@@ -29,3 +43,6 @@ type Filter func([]*registry.Service) []*registry.Service
 
 // Strategy 根据策略选择节点
 type Strategy func([]*registry.Service) Next
+
+// StrategyFactory 创建有状态的策略，选择器传入自己的 State，轮询位置、节点负载等状态不会在选择器之间共享
+type StrategyFactory func(*State) Strategy
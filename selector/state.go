@@ -0,0 +1,125 @@
+package selector
+
+import (
+	"github.com/osgochina/dmicro/registry"
+	"sync"
+)
+
+// State 选择器实例中保存的节点状态，包括节点的负载以及各个策略的状态。
+// 每个选择器拥有独立的状态，节点从注册表中删除时清理对应的记录
+type State struct {
+	mu sync.Mutex
+	// 节点的调用结果和平均延迟
+	od *outlierDetector
+	// 轮询策略的位置 map[service]*uint64
+	roundRobin map[string]*uint64
+	// 平滑加权轮询策略的当前权重 map[service]map[nodeKey]currentWeight
+	weighted map[string]map[string]int
+	// 节点的负载信息 map[service]map[nodeKey]*nodeLoad
+	loads map[string]map[string]*nodeLoad
+	// 一致性哈希负载均衡器 map[service]map[kind]*cachedBalancer
+	balancers map[string]map[string]*cachedBalancer
+	// 注册表中的全部节点，未经过滤 map[service][]*registry.Node
+	registered map[string][]*registry.Node
+}
+
+// NewState 创建节点状态，直接调用策略时使用，选择器会自动创建自己的状态
+func NewState() *State {
+	return newState(newOutlierDetector(0, nil))
+}
+
+func newState(od *outlierDetector) *State {
+	return &State{
+		od:         od,
+		roundRobin: make(map[string]*uint64),
+		weighted:   make(map[string]map[string]int),
+		loads:      make(map[string]map[string]*nodeLoad),
+		balancers:  make(map[string]map[string]*cachedBalancer),
+		registered: make(map[string][]*registry.Node),
+	}
+}
+
+// 获取服务的轮询位置
+func (that *State) roundRobinCounter(service string) *uint64 {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	counter, ok := that.roundRobin[service]
+	if !ok {
+		counter = new(uint64)
+		that.roundRobin[service] = counter
+	}
+	return counter
+}
+
+// 记录注册表中服务的全部节点，返回上次记录之后已经从注册表中删除的节点
+func (that *State) setRegistered(service string, services []*registry.Service) []*registry.Node {
+	nodes := allNodes(services)
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	old := that.registered[service]
+	that.registered[service] = nodes
+	if sameNodes(old, nodes) {
+		return nil
+	}
+	current := make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		current[nodeKey(node)] = struct{}{}
+	}
+	var removed []*registry.Node
+	for _, node := range old {
+		if _, ok := current[nodeKey(node)]; !ok {
+			removed = append(removed, node)
+		}
+	}
+	return removed
+}
+
+// 节点列表是否相同，缓存返回的节点顺序通常不变，只比较相同位置的节点
+func sameNodes(a, b []*registry.Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if nodeKey(a[i]) != nodeKey(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// 获取注册表中服务的全部节点，选择器没有记录时返回nil
+func (that *State) registeredNodes(service string) []*registry.Node {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	return that.registered[service]
+}
+
+// 移除已经从注册表中删除的节点的状态
+func (that *State) remove(service string, nodes []*registry.Node) {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	for _, node := range nodes {
+		key := nodeKey(node)
+		delete(that.weighted[service], key)
+		delete(that.loads[service], key)
+	}
+	if len(that.weighted[service]) == 0 {
+		delete(that.weighted, service)
+	}
+	if len(that.loads[service]) == 0 {
+		delete(that.loads, service)
+	}
+	// 节点列表已经变化，负载均衡器下次使用时重新创建
+	delete(that.balancers, service)
+}
+
+// 清除服务的全部状态
+func (that *State) reset(service string) {
+	that.mu.Lock()
+	defer that.mu.Unlock()
+	delete(that.roundRobin, service)
+	delete(that.weighted, service)
+	delete(that.loads, service)
+	delete(that.balancers, service)
+	delete(that.registered, service)
+}
@@ -3,6 +3,8 @@ package selector
 import (
 	"github.com/osgochina/dmicro/registry"
 	"math/rand"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,11 +14,7 @@ func init() {
 
 // Random 随机选择节点
 func Random(services []*registry.Service) Next {
-	nodes := make([]*registry.Node, 0, len(services))
-
-	for _, service := range services {
-		nodes = append(nodes, service.Nodes...)
-	}
+	nodes := allNodes(services)
 
 	return func() (*registry.Node, error) {
 		if len(nodes) == 0 {
@@ -26,3 +24,167 @@ func Random(services []*registry.Service) Next {
 		return nodes[i], nil
 	}
 }
+
+const (
+	// 节点权重的元数据key
+	weightMetadataKey = "weight"
+	// 计算节点负载时，每次连续失败相当于增加的延迟
+	failurePenalty = time.Second
+)
+
+// 合并所有版本的服务节点
+func allNodes(services []*registry.Service) []*registry.Node {
+	nodes := make([]*registry.Node, 0, len(services))
+	for _, service := range services {
+		nodes = append(nodes, service.Nodes...)
+	}
+	return nodes
+}
+
+// 服务名称，作为策略状态的key
+func serviceName(services []*registry.Service) string {
+	if len(services) == 0 {
+		return ""
+	}
+	return services[0].Name
+}
+
+// RoundRobin 轮询选择节点，同一服务的多次选择共享轮询位置
+func RoundRobin(state *State) Strategy {
+	return func(services []*registry.Service) Next {
+		nodes := allNodes(services)
+		counter := state.roundRobinCounter(serviceName(services))
+
+		return func() (*registry.Node, error) {
+			if len(nodes) == 0 {
+				return nil, ErrNoneAvailable
+			}
+			i := atomic.AddUint64(counter, 1) - 1
+			return nodes[i%uint64(len(nodes))], nil
+		}
+	}
+}
+
+// 获取节点的权重，未设置或者设置错误时权重为1
+func nodeWeight(node *registry.Node) int {
+	if node.Metadata == nil {
+		return 1
+	}
+	weight, err := strconv.Atoi(node.Metadata[weightMetadataKey])
+	if err != nil || weight <= 0 {
+		return 1
+	}
+	return weight
+}
+
+// WeightedRoundRobin 平滑加权轮询选择节点，节点权重读取 Node.Metadata 中的 weight
+func WeightedRoundRobin(state *State) Strategy {
+	return func(services []*registry.Service) Next {
+		nodes := allNodes(services)
+		name := serviceName(services)
+
+		return func() (*registry.Node, error) {
+			if len(nodes) == 0 {
+				return nil, ErrNoneAvailable
+			}
+			state.mu.Lock()
+			defer state.mu.Unlock()
+			current, ok := state.weighted[name]
+			if !ok {
+				current = make(map[string]int)
+				state.weighted[name] = current
+			}
+			var (
+				best      *registry.Node
+				bestKey   string
+				total     int
+				available = make(map[string]struct{}, len(nodes))
+			)
+			for _, node := range nodes {
+				key := nodeKey(node)
+				weight := nodeWeight(node)
+				available[key] = struct{}{}
+				total += weight
+				current[key] += weight
+				if best == nil || current[key] > current[bestKey] {
+					best = node
+					bestKey = key
+				}
+			}
+			current[bestKey] -= total
+			// 清理已经下线的节点
+			for key := range current {
+				if _, ok = available[key]; !ok {
+					delete(current, key)
+				}
+			}
+			return best, nil
+		}
+	}
+}
+
+// LeastConn 选择正在处理的请求数最少的节点，请求数相同则随机选择
+func LeastConn(state *State) Strategy {
+	return func(services []*registry.Service) Next {
+		nodes := allNodes(services)
+		name := serviceName(services)
+
+		return func() (*registry.Node, error) {
+			if len(nodes) == 0 {
+				return nil, ErrNoneAvailable
+			}
+			var (
+				best  *registry.Node
+				least int64
+				ties  int
+			)
+			for _, node := range nodes {
+				n := state.Inflight(name, node)
+				switch {
+				case best == nil || n < least:
+					best, least, ties = node, n, 1
+				case n == least:
+					// 蓄水池抽样，在请求数相同的节点中随机选择
+					ties++
+					if rand.Intn(ties) == 0 {
+						best = node
+					}
+				}
+			}
+			return best, nil
+		}
+	}
+}
+
+// P2C 随机选择两个节点，选择平均延迟与正在处理的请求数综合负载较低的节点。
+// 节点较慢但仍然健康时，可以避免请求持续落在慢节点上
+func P2C(state *State) Strategy {
+	return func(services []*registry.Service) Next {
+		nodes := allNodes(services)
+		name := serviceName(services)
+
+		return func() (*registry.Node, error) {
+			switch len(nodes) {
+			case 0:
+				return nil, ErrNoneAvailable
+			case 1:
+				return nodes[0], nil
+			}
+			i := rand.Intn(len(nodes))
+			j := rand.Intn(len(nodes) - 1)
+			if j >= i {
+				j++
+			}
+			if nodeCost(state, name, nodes[j]) < nodeCost(state, name, nodes[i]) {
+				return nodes[j], nil
+			}
+			return nodes[i], nil
+		}
+	}
+}
+
+// 节点的负载，没有延迟数据的节点负载较低，会被优先探测，连续失败的节点按失败次数增加负载
+func nodeCost(state *State, service string, node *registry.Node) float64 {
+	latency := state.Latency(service, node) + time.Duration(state.od.consecutive(service, node))*failurePenalty
+	return float64(latency+1) * float64(state.Inflight(service, node)+1)
+}
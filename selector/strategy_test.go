@@ -0,0 +1,215 @@
+package selector
+
+import (
+	"context"
+	"errors"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/osgochina/dmicro/registry"
+	"github.com/osgochina/dmicro/registry/memory"
+	"testing"
+	"time"
+)
+
+func TestRoundRobin(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		state := NewState()
+		services := []*registry.Service{{Name: "rr", Nodes: []*registry.Node{
+			{Id: "a", Address: "127.0.0.1:1"},
+			{Id: "b", Address: "127.0.0.1:2"},
+			{Id: "c", Address: "127.0.0.1:3"},
+		}}}
+		counts := make(map[string]int)
+		// 每次请求都重新调用策略，轮询位置需要在多次选择之间保持
+		for i := 0; i < 9; i++ {
+			node, err := RoundRobin(state)(services)()
+			t.AssertNil(err)
+			counts[node.Id]++
+		}
+		t.Assert(counts["a"], 3)
+		t.Assert(counts["b"], 3)
+		t.Assert(counts["c"], 3)
+
+		_, err := RoundRobin(state)([]*registry.Service{{Name: "empty"}})()
+		t.Assert(err, ErrNoneAvailable)
+	})
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		state := NewState()
+		services := []*registry.Service{{Name: "wrr", Nodes: []*registry.Node{
+			{Id: "a", Address: "127.0.0.1:1", Metadata: map[string]string{"weight": "5"}},
+			{Id: "b", Address: "127.0.0.1:2", Metadata: map[string]string{"weight": "1"}},
+			{Id: "c", Address: "127.0.0.1:3", Metadata: map[string]string{"weight": "1"}},
+		}}}
+		var seq []string
+		for i := 0; i < 7; i++ {
+			node, err := WeightedRoundRobin(state)(services)()
+			t.AssertNil(err)
+			seq = append(seq, node.Id)
+		}
+		// 平滑加权轮询的选择顺序
+		t.Assert(seq, []string{"a", "a", "b", "a", "c", "a", "a"})
+
+		// 未设置权重的节点权重为1
+		t.Assert(nodeWeight(&registry.Node{}), 1)
+		t.Assert(nodeWeight(&registry.Node{Metadata: map[string]string{"weight": "x"}}), 1)
+	})
+}
+
+func TestLeastConn(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		state := NewState()
+		a := &registry.Node{Id: "lc-a", Address: "127.0.0.1:1"}
+		b := &registry.Node{Id: "lc-b", Address: "127.0.0.1:2"}
+		services := []*registry.Service{{Name: "lc", Nodes: []*registry.Node{a, b}}}
+
+		endA1 := state.Begin("lc", a)
+		endA2 := state.Begin("lc", a)
+		endB := state.Begin("lc", b)
+		t.Assert(state.Inflight("lc", a), 2)
+		node, err := LeastConn(state)(services)()
+		t.AssertNil(err)
+		t.Assert(node.Id, "lc-b")
+
+		endA1()
+		endA2()
+		// 重复调用不会重复减少
+		endA2()
+		t.Assert(state.Inflight("lc", a), 0)
+		node, err = LeastConn(state)(services)()
+		t.AssertNil(err)
+		t.Assert(node.Id, "lc-a")
+		endB()
+		t.Assert(state.Inflight("lc", b), 0)
+	})
+}
+
+func TestP2C(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		state := NewState()
+		slow := &registry.Node{Id: "p2c-slow", Address: "127.0.0.1:1"}
+		fast := &registry.Node{Id: "p2c-fast", Address: "127.0.0.1:2"}
+		services := []*registry.Service{{Name: "p2c", Nodes: []*registry.Node{slow, fast}}}
+
+		state.od.mark("p2c", slow, nil, 100*time.Millisecond)
+		state.od.mark("p2c", fast, nil, time.Millisecond)
+		t.Assert(state.Latency("p2c", slow) > state.Latency("p2c", fast), true)
+		// 只有两个节点时每次都比较这两个节点，总是选择较快的节点
+		for i := 0; i < 20; i++ {
+			node, err := P2C(state)(services)()
+			t.AssertNil(err)
+			t.Assert(node.Id, "p2c-fast")
+		}
+
+		// 较快的节点积压了大量请求后，选择较慢的节点
+		ends := make([]func(), 0, 200)
+		for i := 0; i < 200; i++ {
+			ends = append(ends, state.Begin("p2c", fast))
+		}
+		node, err := P2C(state)(services)()
+		t.AssertNil(err)
+		t.Assert(node.Id, "p2c-slow")
+		for _, end := range ends {
+			end()
+		}
+		t.Assert(state.Inflight("p2c", fast), 0)
+
+		// 快速返回的失败不计入平均延迟，但是会增加节点的负载
+		latency := state.Latency("p2c", fast)
+		state.od.mark("p2c", fast, errors.New("fail"), time.Microsecond)
+		t.Assert(state.Latency("p2c", fast) <= latency, true)
+		node, err = P2C(state)(services)()
+		t.AssertNil(err)
+		t.Assert(node.Id, "p2c-slow")
+		// 成功以后恢复
+		state.od.mark("p2c", fast, nil, time.Millisecond)
+		node, err = P2C(state)(services)()
+		t.AssertNil(err)
+		t.Assert(node.Id, "p2c-fast")
+	})
+}
+
+func TestStateRemoveDeletedNode(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		r := memory.NewRegistry()
+		a := &registry.Node{Id: "rm-a", Address: "127.0.0.1:1"}
+		b := &registry.Node{Id: "rm-b", Address: "127.0.0.1:2"}
+		t.AssertNil(r.Register(&registry.Service{Name: "rm", Nodes: []*registry.Node{a, b}}))
+		// 缓存很快过期，删除节点后的下一次选择就能拿到注册表中最新的节点
+		ttl := func(o *Options) {
+			o.Context = context.WithValue(context.Background(), "selector_ttl", time.Millisecond)
+		}
+		s := NewSelector(OptRegistry(r), OptStrategyFactory(WeightedRoundRobin), ttl)
+		defer s.Close()
+		rs := s.(*registrySelector)
+
+		for i := 0; i < 2; i++ {
+			next, err := s.Select("rm")
+			t.AssertNil(err)
+			node, err := next()
+			t.AssertNil(err)
+			rs.Begin("rm", node)()
+			s.Mark("rm", node, nil)
+		}
+		t.Assert(len(rs.state.loads["rm"]), 2)
+		t.Assert(len(rs.state.weighted["rm"]), 2)
+		t.Assert(len(rs.od.services["rm"]), 2)
+
+		// 节点从注册表中删除后，下一次选择时清理节点的状态
+		t.AssertNil(r.Deregister(&registry.Service{Name: "rm", Nodes: []*registry.Node{a}}))
+		time.Sleep(2 * time.Millisecond)
+		next, err := s.Select("rm")
+		t.AssertNil(err)
+		node, err := next()
+		t.AssertNil(err)
+		t.Assert(node.Id, "rm-b")
+		rs.state.mu.Lock()
+		_, found := rs.state.loads["rm"]["rm-a"]
+		t.Assert(found, false)
+		_, found = rs.state.weighted["rm"]["rm-a"]
+		t.Assert(found, false)
+		t.Assert(len(rs.state.loads["rm"]), 1)
+		rs.state.mu.Unlock()
+		rs.od.mu.RLock()
+		_, found = rs.od.services["rm"]["rm-a"]
+		rs.od.mu.RUnlock()
+		t.Assert(found, false)
+	})
+}
+
+func TestCustomStrategy(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		r := memory.NewRegistry()
+		t.AssertNil(r.Register(&registry.Service{Name: "custom", Nodes: []*registry.Node{
+			{Id: "a", Address: "127.0.0.1:1"},
+			{Id: "b", Address: "127.0.0.1:2"},
+		}}))
+		// 无状态的自定义策略
+		pickB := func(services []*registry.Service) Next {
+			return func() (*registry.Node, error) {
+				for _, node := range allNodes(services) {
+					if node.Id == "b" {
+						return node, nil
+					}
+				}
+				return nil, ErrNoneAvailable
+			}
+		}
+		s := NewSelector(OptRegistry(r), OptStrategy(pickB))
+		defer s.Close()
+		next, err := s.Select("custom")
+		t.AssertNil(err)
+		node, err := next()
+		t.AssertNil(err)
+		t.Assert(node.Id, "b")
+
+		// 调用时传入的策略优先
+		next, err = s.Select("custom", OptWithStrategyFactory(RoundRobin))
+		t.AssertNil(err)
+		first, _ := next()
+		next, _ = s.Select("custom", OptWithStrategyFactory(RoundRobin))
+		second, _ := next()
+		t.AssertNE(first.Id, second.Id)
+	})
+}
@@ -3,6 +3,7 @@ package backoff
 
 import (
 	"math"
+	"math/rand"
 	"time"
 )
 
@@ -21,3 +22,23 @@ func Do(attempts int) time.Duration {
 	}
 	return time.Duration(math.Pow(10, float64(attempts))) * time.Millisecond
 }
+
+// Exponential 指数退避，第attempts次重试等待 base*2^(attempts-1)，最长不超过max
+func Exponential(attempts int, base time.Duration, max time.Duration) time.Duration {
+	if attempts <= 0 {
+		return time.Duration(0)
+	}
+	d := float64(base) * math.Pow(2, float64(attempts-1))
+	if max > 0 && d > float64(max) {
+		return max
+	}
+	return time.Duration(d)
+}
+
+// Jitter 给等待时间增加随机抖动，结果在 [d*(1-factor), d*(1+factor)) 之间，避免大量客户端同时重试
+func Jitter(d time.Duration, factor float64) time.Duration {
+	if d <= 0 || factor <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 - factor + 2*factor*rand.Float64()))
+}